@@ -0,0 +1,175 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"promptguard/internal/reporter"
+	"promptguard/internal/runner"
+)
+
+var (
+	baselineCmd = &cobra.Command{
+		Use:   "baseline",
+		Short: "Manage the baseline results",
+		Long: `Manage the baseline results that pg ci, pg diff, and pg view compare
+runs against.`,
+	}
+
+	baselineUpdateCmd = &cobra.Command{
+		Use:   "update",
+		Short: "Write results as the new baseline",
+		Long: `Run the tests and write their results as the new baseline, or with --from
+take the results of an earlier run from a results file or run ID instead of
+running the tests again.
+
+A run in which tests errored, as opposed to failing their assertions, is
+refused unless --force is given.`,
+		RunE: runBaselineUpdate,
+	}
+
+	baselineShowCmd = &cobra.Command{
+		Use:   "show",
+		Short: "Print a summary of the baseline",
+		RunE:  runBaselineShow,
+	}
+
+	baselineClearCmd = &cobra.Command{
+		Use:   "clear",
+		Short: "Delete the baseline",
+		RunE:  runBaselineClear,
+	}
+)
+
+func init() {
+	rootCmd.AddCommand(baselineCmd)
+	baselineCmd.AddCommand(baselineUpdateCmd, baselineShowCmd, baselineClearCmd)
+
+	baselineCmd.PersistentFlags().String("baseline-path", ".promptguard/baseline.json", "Baseline results file")
+
+	baselineUpdateCmd.Flags().String("from", "", "Results file or run ID to use instead of running the tests, e.g. artifacts/results.json")
+	baselineUpdateCmd.Flags().Bool("force", false, "Update the baseline even when tests errored")
+	baselineUpdateCmd.Flags().IntP("parallel", "p", 1, "Number of parallel test executions")
+	baselineUpdateCmd.Flags().BoolP("yes", "y", false, "Run without confirming an expensive run")
+}
+
+func runBaselineUpdate(cmd *cobra.Command, args []string) error {
+	path := getStringFlag(cmd, "baseline-path")
+
+	if from := getStringFlag(cmd, "from"); from != "" {
+		var results runner.Results
+		if err := loadResults(from, &results); err != nil {
+			return fmt.Errorf("failed to load results %s: %w", from, err)
+		}
+		return updateBaseline(os.Stdout, &results, path, getBoolFlag(cmd, "force"))
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	store := newMetricsStore(cfg)
+	defer store.Close()
+
+	workers, _ := cmd.Flags().GetInt("parallel")
+	if workers < 1 {
+		workers = 1
+	}
+	testRunner := runner.New(cfg, runner.Options{Parallel: workers, Store: store})
+	if !getBoolFlag(cmd, "yes") {
+		proceed, err := confirmCost(cfg, testRunner)
+		if err != nil {
+			return err
+		}
+		if !proceed {
+			fmt.Fprintln(os.Stderr, "Aborted.")
+			return nil
+		}
+	}
+
+	results, err := testRunner.Run()
+	if err != nil {
+		return fmt.Errorf("test execution failed: %w", err)
+	}
+	fmt.Printf("Ran %d tests: %d passed, %d failed, %d skipped\n", results.Total, results.Passed, results.Failed, results.Skipped)
+
+	return updateBaseline(os.Stdout, results, path, getBoolFlag(cmd, "force"))
+}
+
+func runBaselineShow(cmd *cobra.Command, args []string) error {
+	path := getStringFlag(cmd, "baseline-path")
+
+	var results runner.Results
+	if err := loadBaseline(path, &results); err != nil {
+		return err
+	}
+
+	fmt.Printf("Baseline: %s\n", path)
+	if results.Metadata.Timestamp != "" {
+		fmt.Printf("Recorded: %s\n", results.Metadata.Timestamp)
+	}
+	if results.Metadata.CommitSHA != "" {
+		fmt.Printf("Commit: %s\n", results.Metadata.CommitSHA)
+	}
+	printTestSummary(os.Stdout, useColor(os.Stdout), &results, results.Duration)
+
+	return nil
+}
+
+func runBaselineClear(cmd *cobra.Command, args []string) error {
+	path := getStringFlag(cmd, "baseline-path")
+
+	if err := os.Remove(path); err != nil {
+		if os.IsNotExist(err) {
+			fmt.Printf("No baseline at %s\n", path)
+			return nil
+		}
+		return fmt.Errorf("failed to delete baseline: %w", err)
+	}
+
+	fmt.Printf("Deleted baseline %s\n", path)
+	return nil
+}
+
+// loadBaseline reads the baseline file, explaining how to create one when
+// there is none
+func loadBaseline(path string, results *runner.Results) error {
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return fmt.Errorf("no baseline at %s; create one with 'pg baseline update'", path)
+	}
+	if err := loadResults(path, results); err != nil {
+		return fmt.Errorf("failed to load baseline %s: %w", path, err)
+	}
+	return nil
+}
+
+// updateBaseline writes results to the baseline file, creating its
+// directory. A run in which tests errored, rather than failed their
+// assertions, is refused unless forced, so that a broken run is not blessed
+// as the baseline.
+func updateBaseline(w io.Writer, results *runner.Results, path string, force bool) error {
+	if path == "" {
+		return fmt.Errorf("no baseline path given")
+	}
+
+	var errored []string
+	for _, test := range results.TestResults {
+		if test.Status == "failed" && test.Error != "" {
+			errored = append(errored, test.Name)
+		}
+	}
+	if len(errored) > 0 && !force {
+		return fmt.Errorf("not updating the baseline: %d tests errored (%s); use --force to update it anyway",
+			len(errored), strings.Join(errored, ", "))
+	}
+
+	if err := reporter.Generate(&reporter.JSONReporter{}, results, path); err != nil {
+		return fmt.Errorf("failed to write baseline %s: %w", path, err)
+	}
+	fmt.Fprintf(w, "Baseline updated: %s (%d tests)\n", path, results.Total)
+	return nil
+}
@@ -2,12 +2,14 @@ package cmd
 
 import (
 	"fmt"
-	"os"
 	"github.com/spf13/cobra"
-	"promptgaurd/internal/config"
-	"promptgaurd/internal/runner"
-	"promptgaurd/internal/reporter"
-	"promptgaurd/internal/github"
+	"os"
+	"promptguard/internal/diff"
+	"promptguard/internal/github"
+	"promptguard/internal/gitlab"
+	"promptguard/internal/runner"
+	"promptguard/internal/slack"
+	"promptguard/internal/warn"
 )
 
 var (
@@ -27,28 +29,57 @@ This command is optimized for CI/CD pipelines and includes:
 func init() {
 	rootCmd.AddCommand(ciCmd)
 
-	ciCmd.Flags().String("baseline-path", ".promptguard/baseline.json", "Path to baseline results")
+	ciCmd.Flags().String("baseline-path", ".promptguard/baseline.json", "Path to baseline results; when it exists, only a regression against it fails the build")
+	ciCmd.Flags().Float64("max-cost-increase", 0, "Cost increase over the baseline tolerated, in percent (default settings.maxCostIncrease or 10, negative to ignore cost)")
+	ciCmd.Flags().Int("max-new-failures", 0, "Newly failing tests tolerated against the baseline (default settings.maxNewFailures)")
 	ciCmd.Flags().String("artifacts-dir", "artifacts", "Directory for CI artifacts")
+	ciCmd.Flags().BoolVar(&jsonCompact, "json-compact", false, "Write results.json on a single line instead of indented")
 	ciCmd.Flags().Bool("github-annotations", true, "Generate GitHub annotations")
-	ciCmd.Flags().Bool("update-badge", true, "Update GitHub badge")
-	ciCmd.Flags().String("commit-sha", "", "Git commit SHA")
-	ciCmd.Flags().String("pr-number", "", "Pull request number")
+	ciCmd.Flags().Bool("update-badge", true, "Write a shields.io badge.json to the artifacts directory")
+	ciCmd.Flags().Bool("badge-svg", false, "Also write the badge as badge.svg")
+	ciCmd.Flags().String("commit-sha", "", "Git commit SHA (default $GITHUB_SHA, $CI_COMMIT_SHA, or git HEAD)")
+	ciCmd.Flags().String("pr-number", "", "Pull request number, or merge request IID on GitLab")
+	ciCmd.Flags().Int("flaky-runs", 10, "Recent runs of the same commit checked for flaky tests (0 to disable)")
+	ciCmd.Flags().String("run-id", "", "Run ID recorded in metrics and artifacts (default: generated)")
+	ciCmd.Flags().String("slack-webhook", "", "Slack incoming webhook URL to notify of failures (default $PROMPTGUARD_SLACK_WEBHOOK)")
+	ciCmd.Flags().Bool("slack-always", false, "Post to Slack after every run, not only failing ones")
+	ciCmd.Flags().Bool("comment", false, "Post the failure report as a pull or merge request comment, updated on later runs")
+	ciCmd.Flags().String("ci-provider", "auto", "CI integration to report to: github, gitlab, none, or auto to detect from the environment")
+	ciCmd.Flags().Bool("no-cache", false, "Call providers even when settings.cacheResults has a cached response")
+	ciCmd.Flags().Bool("fail-fast", false, "Skip the remaining tests after the first failure")
+	ciCmd.Flags().Float64("cost-budget", 0, "Stop starting tests once the run costs more than this many USD (default settings.costBudget, negative to disable)")
 }
 
 func runCI(cmd *cobra.Command, args []string) error {
 	// Load configuration
-	cfg, err := config.Load()
+	cfg, err := loadConfig()
 	if err != nil {
 		return fmt.Errorf("failed to load config: %w", err)
 	}
 
+	artifactsDir := getStringFlag(cmd, "artifacts-dir")
+
+	ciProvider, err := detectCIProvider(getStringFlag(cmd, "ci-provider"))
+	if err != nil {
+		return err
+	}
+
+	store := newMetricsStore(cfg)
+	defer store.Close()
+
 	// Create CI-optimized runner
 	testRunner := runner.New(cfg, runner.Options{
 		Parallel:     4, // Default to 4 parallel executions in CI
 		CIMode:       true,
 		BaselinePath: getStringFlag(cmd, "baseline-path"),
-		CommitSHA:    getStringFlag(cmd, "commit-sha"),
+		CommitSHA:    commitSHA(getStringFlag(cmd, "commit-sha")),
 		PRNumber:     getStringFlag(cmd, "pr-number"),
+		RunID:        getStringFlag(cmd, "run-id"),
+		ManifestPath: fmt.Sprintf("%s/manifest.json", artifactsDir),
+		Store:        store,
+		CostBudget:   getFloat64Flag(cmd, "cost-budget"),
+		FailFast:     getBoolFlag(cmd, "fail-fast"),
+		Cache:        responseCache(cmd, cfg),
 	})
 
 	// Run tests
@@ -57,51 +88,122 @@ func runCI(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("CI test execution failed: %w", err)
 	}
 
+	flakyRuns, _ := cmd.Flags().GetInt("flaky-runs")
+	detectFlaky(store, results, flakyRuns)
+
 	// Generate CI artifacts
-	artifactsDir := getStringFlag(cmd, "artifacts-dir")
 	if err := os.MkdirAll(artifactsDir, 0755); err != nil {
 		return fmt.Errorf("failed to create artifacts directory: %w", err)
 	}
 
 	// Generate multiple report formats for CI
-	reporters := []struct {
-		format string
-		file   string
-	}{
+	generateReports([]reportOutput{
 		{"json", fmt.Sprintf("%s/results.json", artifactsDir)},
 		{"junit", fmt.Sprintf("%s/junit.xml", artifactsDir)},
 		{"html", fmt.Sprintf("%s/promptguard.html", artifactsDir)},
 		{"markdown", fmt.Sprintf("%s/report.md", artifactsDir)},
+		{"sarif", fmt.Sprintf("%s/results.sarif", artifactsDir)},
+	}, results, cfg)
+
+	// Report failures where the CI provider shows them
+	switch ciProvider {
+	case "github":
+		if getBoolFlag(cmd, "github-annotations") {
+			if err := github.GenerateAnnotations(results); err != nil {
+				warn.Printf("failed to generate GitHub annotations: %v", err)
+			}
+		}
+	case "gitlab":
+		if err := gitlab.WriteCodeQuality(results, fmt.Sprintf("%s/gl-code-quality-report.json", artifactsDir)); err != nil {
+			warn.Printf("failed to generate GitLab Code Quality report: %v", err)
+		}
 	}
 
-	for _, r := range reporters {
-		reporter := reporter.New(r.format)
-		if err := reporter.Generate(results, r.file); err != nil {
-			fmt.Printf("Warning: failed to generate %s report: %v\n", r.format, err)
+	// Update badge if enabled
+	if getBoolFlag(cmd, "update-badge") {
+		if err := github.UpdateBadge(results, artifactsDir, getBoolFlag(cmd, "badge-svg")); err != nil {
+			warn.Printf("failed to update badge: %v", err)
 		}
 	}
 
-	// Generate GitHub annotations if enabled
-	if getBoolFlag(cmd, "github-annotations") {
-		if err := github.GenerateAnnotations(results); err != nil {
-			fmt.Printf("Warning: failed to generate GitHub annotations: %v\n", err)
+	// Comment on the pull or merge request; like Slack, a failure must not
+	// fail the build
+	if getBoolFlag(cmd, "comment") && ciProvider != "none" {
+		differ := &diff.MarkdownDiffer{MaxResponseChars: maxResponseChars(cfg)}
+		report := differ.GenerateFailureDiff(results)
+		if ciProvider == "gitlab" {
+			posted, err := gitlab.CommentOnMR(getStringFlag(cmd, "pr-number"), report)
+			switch {
+			case err != nil:
+				warn.Printf("failed to comment on the merge request: %v", err)
+			case !posted:
+				fmt.Println("Not running for a merge request with GITLAB_TOKEN or CI_JOB_TOKEN set; skipping the MR note")
+			}
+		} else {
+			posted, err := github.CommentOnPR(getStringFlag(cmd, "pr-number"), report)
+			switch {
+			case err != nil:
+				warn.Printf("failed to comment on the pull request: %v", err)
+			case !posted:
+				fmt.Println("Not running on a pull request with GITHUB_TOKEN and GITHUB_REPOSITORY set; skipping the PR comment")
+			}
 		}
 	}
 
-	// Update badge if enabled
-	if getBoolFlag(cmd, "update-badge") {
-		if err := github.UpdateBadge(results); err != nil {
-			fmt.Printf("Warning: failed to update badge: %v\n", err)
+	// Notify Slack; a webhook failure must not fail the build
+	webhook := getStringFlag(cmd, "slack-webhook")
+	if webhook == "" {
+		webhook = os.Getenv("PROMPTGUARD_SLACK_WEBHOOK")
+	}
+	if webhook != "" && (results.HasFailures() || getBoolFlag(cmd, "slack-always")) {
+		if err := slack.Post(webhook, results); err != nil {
+			warn.Printf("failed to post Slack notification: %v", err)
 		}
 	}
 
 	// Print summary
 	fmt.Printf("=== CI Test Summary ===\n")
-	fmt.Printf("Tests: %d passed, %d failed, %d skipped\n", 
+	fmt.Printf("Run: %s\n", results.Metadata.RunID)
+	fmt.Printf("Tests: %d passed, %d failed, %d skipped\n",
 		results.Passed, results.Failed, results.Skipped)
+	if results.Warnings > 0 {
+		fmt.Printf("Warnings: %d\n", results.Warnings)
+	}
 	fmt.Printf("Cost: $%.4f\n", results.TotalCost)
+	if results.EvalCost > 0 {
+		fmt.Printf("Eval cost: $%.4f\n", results.EvalCost)
+	}
+	if results.OverBudget {
+		fmt.Printf("%s\n", budgetNotice(results))
+	}
+	if len(results.Flaky) > 0 {
+		fmt.Printf("Flaky: %d tests flipped across recent runs of this commit\n", len(results.Flaky))
+	}
 	fmt.Printf("Artifacts: %s/\n", artifactsDir)
 
+	// With a baseline, only a regression beyond the tolerance fails the
+	// build, so that known failures and noise do not block merges
+	baselinePath := getStringFlag(cmd, "baseline-path")
+	if _, err := os.Stat(baselinePath); err == nil {
+		var baseline runner.Results
+		if err := loadResults(baselinePath, &baseline); err != nil {
+			warn.Printf("failed to load baseline %s: %v", baselinePath, err)
+		} else {
+			regressions := diff.DetectRegression(&baseline, results, regressionTolerance(cmd, cfg))
+			if len(regressions) > 0 {
+				fmt.Printf("\n❌ Regression against the baseline - check artifacts for details\n")
+				for _, reason := range regressions {
+					fmt.Printf("  - %s\n", reason)
+				}
+				return fmt.Errorf("regression against the baseline")
+			}
+			if results.HasFailures() {
+				fmt.Printf("\n✅ No regression against the baseline (%d tests failing within tolerance)\n", results.Failed)
+				return nil
+			}
+		}
+	}
+
 	if results.HasFailures() {
 		fmt.Printf("\n❌ Tests failed - check artifacts for details\n")
 		return fmt.Errorf("tests failed")
@@ -111,6 +213,22 @@ func runCI(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// detectCIProvider resolves the --ci-provider flag; auto selects GitLab in
+// GitLab CI jobs and GitHub otherwise
+func detectCIProvider(name string) (string, error) {
+	switch name {
+	case "auto":
+		if gitlab.IsGitLabCI() {
+			return "gitlab", nil
+		}
+		return "github", nil
+	case "github", "gitlab", "none":
+		return name, nil
+	default:
+		return "", fmt.Errorf("unknown CI provider %q (expected auto, github, gitlab, or none)", name)
+	}
+}
+
 func getStringFlag(cmd *cobra.Command, name string) string {
 	value, _ := cmd.Flags().GetString(name)
 	return value
@@ -120,3 +238,8 @@ func getBoolFlag(cmd *cobra.Command, name string) bool {
 	value, _ := cmd.Flags().GetBool(name)
 	return value
 }
+
+func getFloat64Flag(cmd *cobra.Command, name string) float64 {
+	value, _ := cmd.Flags().GetFloat64(name)
+	return value
+}
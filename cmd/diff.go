@@ -1,23 +1,40 @@
 package cmd
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
-	"os"
 	"github.com/spf13/cobra"
-	"promptgaurd/internal/runner"
-	"promptgaurd/internal/diff"
-	"encoding/json"
+	"html"
+	"os"
+	"promptguard/internal/config"
+	"promptguard/internal/diff"
+	"promptguard/internal/metrics"
+	"promptguard/internal/runner"
+	"strings"
 )
 
 var (
 	baselineFile string
 	currentFile  string
+	diffFileA    string
+	diffFileB    string
+	diffFormat   string
 	diffCmd      = &cobra.Command{
 		Use:   "diff",
 		Short: "Generate markdown diff for failed tests",
 		Long: `Generate a detailed markdown diff analysis for test failures.
 Compares current results with baseline and shows red/green diffs
-for failed assertions.`,
+for failed assertions. With --format html, the same analysis is
+written as a styled HTML page; with --format json, as a JSON object
+with the summary deltas, per-test transitions, and per-assertion
+changes, for gating scripts.
+
+With --a and --b, compares any two result files instead and reports the
+per-test status, score, and cost changes from A to B.
+
+Wherever a results file is expected, the ID of a run recorded in the
+metrics database can be given instead.`,
 		RunE: runDiff,
 	}
 )
@@ -25,53 +42,206 @@ for failed assertions.`,
 func init() {
 	rootCmd.AddCommand(diffCmd)
 
-	diffCmd.Flags().StringVar(&baselineFile, "baseline", ".promptguard/baseline.json", "Baseline results file")
-	diffCmd.Flags().StringVar(&currentFile, "current", "artifacts/results.json", "Current results file")
+	diffCmd.Flags().StringVar(&baselineFile, "baseline", ".promptguard/baseline.json", "Baseline results file or run ID")
+	diffCmd.Flags().StringVar(&currentFile, "current", "artifacts/results.json", "Current results file or run ID")
 	diffCmd.Flags().StringVar(&outputFile, "output", "", "Output file for diff (default: stdout)")
+	diffCmd.Flags().StringVar(&diffFileA, "a", "", "First results file or run ID to compare")
+	diffCmd.Flags().StringVar(&diffFileB, "b", "", "Second results file or run ID to compare")
+	diffCmd.Flags().StringVar(&diffFormat, "format", "markdown", "Output format: markdown, html, or json")
+	diffCmd.Flags().Float64("cost-alert", diff.DefaultCostAlertThreshold, "Total cost increase that raises a cost alert")
+	diffCmd.Flags().Float64("cost-change", diff.DefaultCostChangeThreshold, "Smallest cost change reported as a change")
+	diffCmd.Flags().Duration("latency-alert", diff.DefaultLatencyAlertThreshold, "Duration increase that raises a latency alert")
+	diffCmd.Flags().Duration("latency-change", diff.DefaultLatencyChangeThreshold, "Smallest duration change reported as a change")
+	diffCmd.Flags().Float64("max-cost-increase", 0, "Cost increase tolerated before a regression, in percent (default settings.maxCostIncrease or 10, negative to ignore cost)")
+	diffCmd.Flags().Int("max-new-failures", 0, "Newly failing tests tolerated before a regression (default settings.maxNewFailures)")
+}
+
+// regressionTolerance returns the regression tolerance from the
+// --max-cost-increase and --max-new-failures flags, falling back to the
+// settings when a flag is not given
+func regressionTolerance(cmd *cobra.Command, cfg *config.Config) diff.Tolerance {
+	var tolerance diff.Tolerance
+	if cfg != nil {
+		tolerance.MaxCostIncrease = cfg.Settings.MaxCostIncrease
+		tolerance.MaxNewFailures = cfg.Settings.MaxNewFailures
+	}
+	if cmd.Flags().Changed("max-cost-increase") {
+		tolerance.MaxCostIncrease, _ = cmd.Flags().GetFloat64("max-cost-increase")
+	}
+	if cmd.Flags().Changed("max-new-failures") {
+		tolerance.MaxNewFailures, _ = cmd.Flags().GetInt("max-new-failures")
+	}
+	return tolerance
+}
+
+// newMarkdownDiffer creates a markdown differ with the significance
+// thresholds from flags
+func newMarkdownDiffer(cmd *cobra.Command) *diff.MarkdownDiffer {
+	costAlert, _ := cmd.Flags().GetFloat64("cost-alert")
+	costChange, _ := cmd.Flags().GetFloat64("cost-change")
+	latencyAlert, _ := cmd.Flags().GetDuration("latency-alert")
+	latencyChange, _ := cmd.Flags().GetDuration("latency-change")
+
+	// The config is optional here; it only supplies settings.maxResponseChars
+	cfg, _ := loadConfig()
+
+	return &diff.MarkdownDiffer{
+		CostAlertThreshold:     costAlert,
+		CostChangeThreshold:    costChange,
+		LatencyAlertThreshold:  latencyAlert,
+		LatencyChangeThreshold: latencyChange,
+		MaxResponseChars:       maxResponseChars(cfg),
+		Tolerance:              regressionTolerance(cmd, cfg),
+	}
+}
+
+// newDiffer creates the differ of the --format flag
+func newDiffer(cmd *cobra.Command) (diff.Differ, error) {
+	switch diffFormat {
+	case "markdown", "md":
+		return newMarkdownDiffer(cmd), nil
+	case "html":
+		return &diff.HTMLDiffer{MarkdownDiffer: *newMarkdownDiffer(cmd)}, nil
+	case "json":
+		return &diff.JSONDiffer{MarkdownDiffer: *newMarkdownDiffer(cmd)}, nil
+	default:
+		return nil, fmt.Errorf("unknown diff format %q (expected markdown, html, or json)", diffFormat)
+	}
+}
+
+// jsonDiff is the document written by pg diff --format json
+type jsonDiff struct {
+	Files           map[string]string `json:"files,omitempty"`           // The compared files with --a and --b
+	FailureAnalysis json.RawMessage   `json:"failureAnalysis,omitempty"` // Failed tests of the current run
+	Comparison      json.RawMessage   `json:"comparison"`                // Null without a baseline
+}
+
+// formatJSONDiff combines the JSON failure analysis and comparison
+func formatJSONDiff(doc jsonDiff) (string, error) {
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal diff: %w", err)
+	}
+	return string(data) + "\n", nil
 }
 
 func runDiff(cmd *cobra.Command, args []string) error {
+	differ, err := newDiffer(cmd)
+	if err != nil {
+		return err
+	}
+
+	if diffFileA != "" || diffFileB != "" {
+		if diffFileA == "" || diffFileB == "" {
+			return fmt.Errorf("--a and --b must be used together")
+		}
+		output, err := compareResultFiles(differ, diffFileA, diffFileB)
+		if err != nil {
+			return err
+		}
+		return writeDiff(output)
+	}
+
 	// Load current results
 	var currentResults runner.Results
 	if err := loadResults(currentFile, &currentResults); err != nil {
 		return fmt.Errorf("failed to load current results: %w", err)
 	}
 
-	differ := &diff.MarkdownDiffer{}
-
 	// Generate failure diff
 	failureDiff := differ.GenerateFailureDiff(&currentResults)
 
 	// If baseline exists, also generate baseline comparison
 	var baselineComparison string
-	if _, err := os.Stat(baselineFile); err == nil {
-		var baselineResults runner.Results
-		if err := loadResults(baselineFile, &baselineResults); err == nil {
-			baselineComparison = differ.GenerateBaselineComparison(&currentResults, &baselineResults)
-		}
+	var baselineResults runner.Results
+	if err := loadResults(baselineFile, &baselineResults); err == nil {
+		baselineComparison = differ.GenerateBaselineComparison(&currentResults, &baselineResults)
 	}
 
 	// Combine outputs
+	switch differ.(type) {
+	case *diff.HTMLDiffer:
+		return writeDiff(diff.HTMLPage("PromptGuard Diff", failureDiff, baselineComparison))
+	case *diff.JSONDiffer:
+		doc := jsonDiff{FailureAnalysis: json.RawMessage(failureDiff)}
+		if baselineComparison != "" {
+			doc.Comparison = json.RawMessage(baselineComparison)
+		}
+		output, err := formatJSONDiff(doc)
+		if err != nil {
+			return err
+		}
+		return writeDiff(output)
+	}
 	output := failureDiff
 	if baselineComparison != "" {
 		output += "\n" + baselineComparison
 	}
 
-	// Write output
+	return writeDiff(output)
+}
+
+// compareResultFiles compares two result files, labeling them A and B
+func compareResultFiles(differ diff.Differ, fileA, fileB string) (string, error) {
+	var resultsA, resultsB runner.Results
+	if err := loadResults(fileA, &resultsA); err != nil {
+		return "", fmt.Errorf("failed to load results A: %w", err)
+	}
+	if err := loadResults(fileB, &resultsB); err != nil {
+		return "", fmt.Errorf("failed to load results B: %w", err)
+	}
+
+	report := differ.GenerateComparison(&resultsA, &resultsB, "A", "B")
+
+	// List the compared files under the report title
+	switch differ.(type) {
+	case *diff.HTMLDiffer:
+		legend := fmt.Sprintf("<ul>\n<li><strong>A</strong>: <code>%s</code></li>\n<li><strong>B</strong>: <code>%s</code></li>\n</ul>\n",
+			html.EscapeString(fileA), html.EscapeString(fileB))
+		titleEnd := strings.Index(report, "</h1>\n") + len("</h1>\n")
+		return diff.HTMLPage("PromptGuard Comparison", report[:titleEnd]+legend+report[titleEnd:]), nil
+	case *diff.JSONDiffer:
+		return formatJSONDiff(jsonDiff{Files: map[string]string{"a": fileA, "b": fileB}, Comparison: json.RawMessage(report)})
+	}
+	legend := fmt.Sprintf("- **A**: `%s`\n- **B**: `%s`\n\n", fileA, fileB)
+	titleEnd := strings.Index(report, "\n\n") + 2
+
+	return report[:titleEnd] + legend + report[titleEnd:], nil
+}
+
+// writeDiff prints the diff or writes it to the output file
+func writeDiff(output string) error {
 	if outputFile == "" {
 		fmt.Print(output)
-	} else {
-		if err := os.WriteFile(outputFile, []byte(output), 0644); err != nil {
-			return fmt.Errorf("failed to write output file: %w", err)
-		}
-		fmt.Printf("Diff analysis written to: %s\n", outputFile)
+		return nil
 	}
 
+	if err := os.WriteFile(outputFile, []byte(output), 0644); err != nil {
+		return fmt.Errorf("failed to write output file: %w", err)
+	}
+	fmt.Printf("Diff analysis written to: %s\n", outputFile)
+
 	return nil
 }
 
-func loadResults(filename string, results *runner.Results) error {
-	data, err := os.ReadFile(filename)
+// loadResults reads a results file or, if no such file exists, the stored
+// results of the run with that ID
+func loadResults(ref string, results *runner.Results) error {
+	data, err := os.ReadFile(ref)
+	if os.IsNotExist(err) {
+		store := newMetricsStore(nil)
+		defer store.Close()
+
+		stored, storeErr := store.GetRun(ref)
+		if errors.Is(storeErr, metrics.ErrRunNotFound) {
+			return fmt.Errorf("no results file or run with ID %q", ref)
+		}
+		if storeErr != nil {
+			return storeErr
+		}
+		*results = *stored
+		return nil
+	}
 	if err != nil {
 		return err
 	}
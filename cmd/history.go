@@ -0,0 +1,116 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"promptguard/internal/runner"
+)
+
+var (
+	historyCmd = &cobra.Command{
+		Use:   "history",
+		Short: "Show past test runs",
+		Long: `List recent runs recorded in the metrics database with their pass/fail
+counts, cost, and duration, newest first.`,
+		RunE: runHistory,
+	}
+)
+
+// historyEntry describes a stored run in the history output
+type historyEntry struct {
+	RunID     string        `json:"runId,omitempty"`
+	Timestamp string        `json:"timestamp"`
+	CommitSHA string        `json:"commitSha,omitempty"`
+	Total     int           `json:"total"`
+	Passed    int           `json:"passed"`
+	Failed    int           `json:"failed"`
+	Cost      float64       `json:"cost"`
+	Duration  time.Duration `json:"duration"`
+}
+
+func init() {
+	rootCmd.AddCommand(historyCmd)
+
+	historyCmd.Flags().Int("limit", 20, "Number of runs to show")
+	historyCmd.Flags().Bool("json", false, "Output as JSON")
+	historyCmd.Flags().String("commit", "", "Only show runs of this commit SHA (a prefix is enough)")
+}
+
+func runHistory(cmd *cobra.Command, args []string) error {
+	limit, _ := cmd.Flags().GetInt("limit")
+	if limit < 1 {
+		return fmt.Errorf("--limit must be at least 1")
+	}
+
+	store := newMetricsStore(nil)
+	defer store.Close()
+
+	var runs []runner.Results
+	var err error
+	if commit := getStringFlag(cmd, "commit"); commit != "" {
+		runs, err = store.GetCommitHistory(commit, limit)
+	} else {
+		runs, err = store.GetHistory(limit)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to load history: %w", err)
+	}
+
+	entries := make([]historyEntry, 0, len(runs))
+	for _, run := range runs {
+		entries = append(entries, historyEntry{
+			RunID:     run.Metadata.RunID,
+			Timestamp: run.Metadata.Timestamp,
+			CommitSHA: run.Metadata.CommitSHA,
+			Total:     run.Total,
+			Passed:    run.Passed,
+			Failed:    run.Failed,
+			Cost:      run.TotalCost,
+			Duration:  run.Duration,
+		})
+	}
+
+	if getBoolFlag(cmd, "json") {
+		data, err := json.MarshalIndent(entries, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal JSON: %w", err)
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	if len(entries) == 0 {
+		fmt.Println("No runs recorded yet. Run 'pg test' to record one.")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "TIMESTAMP\tRUN\tCOMMIT\tPASSED\tFAILED\tCOST\tDURATION")
+	for _, entry := range entries {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%d\t$%.4f\t%v\n",
+			entry.Timestamp, orDash(entry.RunID), orDash(shortSHA(entry.CommitSHA)),
+			entry.Passed, entry.Failed, entry.Cost, entry.Duration.Round(time.Millisecond))
+	}
+
+	return w.Flush()
+}
+
+// shortSHA abbreviates a commit SHA to the usual seven characters
+func shortSHA(sha string) string {
+	if len(sha) > 7 {
+		return sha[:7]
+	}
+	return sha
+}
+
+func orDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
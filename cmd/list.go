@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"promptguard/internal/runner"
+)
+
+var (
+	listCmd = &cobra.Command{
+		Use:   "list",
+		Short: "List the prompts and tests that would run",
+		Long: `Load the configuration and list each resolved prompt file and every
+test case with its provider and assertion types, without calling any provider.`,
+		RunE: runList,
+	}
+)
+
+// listedTest describes a test case in the list output
+type listedTest struct {
+	Name       string   `json:"name"`
+	PromptFile string   `json:"promptFile"`
+	Provider   string   `json:"provider"`
+	Assertions []string `json:"assertions"`
+}
+
+func init() {
+	rootCmd.AddCommand(listCmd)
+
+	listCmd.Flags().Bool("json", false, "Output as JSON")
+}
+
+func runList(cmd *cobra.Command, args []string) error {
+	cfg, err := loadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	testRunner := runner.New(cfg, runner.Options{})
+
+	testCases, err := testRunner.TestCases()
+	if err != nil {
+		return err
+	}
+
+	tests := make([]listedTest, 0, len(testCases))
+	for _, testCase := range testCases {
+		assertionTypes := make([]string, 0, len(testCase.Test.Assert))
+		for _, assertion := range testCase.Test.Assert {
+			assertionTypes = append(assertionTypes, assertion.Type)
+		}
+
+		tests = append(tests, listedTest{
+			Name:       testCase.Name,
+			PromptFile: testCase.PromptFile,
+			Provider:   testCase.Provider,
+			Assertions: assertionTypes,
+		})
+	}
+
+	if getBoolFlag(cmd, "json") {
+		data, err := json.MarshalIndent(map[string]interface{}{
+			"prompts":       cfg.Prompts,
+			"inlinePrompts": cfg.InlinePromptNames(),
+			"tests":         tests,
+		}, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal JSON: %w", err)
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	fmt.Printf("Prompts (%d):\n", len(cfg.Prompts)+len(cfg.InlinePrompts))
+	for _, file := range cfg.Prompts {
+		fmt.Printf("  %s\n", file)
+	}
+	for _, name := range cfg.InlinePromptNames() {
+		fmt.Printf("  %s (inline)\n", name)
+	}
+
+	fmt.Printf("\nTests (%d):\n", len(tests))
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "  PROMPT\tTEST\tPROVIDER\tASSERTIONS")
+	for _, test := range tests {
+		fmt.Fprintf(w, "  %s\t%s\t%s\t%s\n", test.PromptFile, test.Name, test.Provider, strings.Join(test.Assertions, ", "))
+	}
+
+	return w.Flush()
+}
@@ -0,0 +1,71 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	metricsCmd = &cobra.Command{
+		Use:   "metrics",
+		Short: "Manage the metrics database",
+	}
+
+	metricsPruneCmd = &cobra.Command{
+		Use:   "prune",
+		Short: "Delete old runs from the metrics database",
+		Long: `Delete old runs and their per-test results from the metrics database,
+then vacuum the database file to reclaim the space.
+
+--keep keeps the newest N runs; --older-than deletes runs stored longer ago
+than the given duration. Both may be combined.`,
+		RunE: runMetricsPrune,
+	}
+)
+
+func init() {
+	rootCmd.AddCommand(metricsCmd)
+	metricsCmd.AddCommand(metricsPruneCmd)
+
+	metricsPruneCmd.Flags().Int("keep", 0, "Keep only the newest N runs")
+	metricsPruneCmd.Flags().Duration("older-than", 0, "Delete runs older than this, e.g. 720h")
+}
+
+func runMetricsPrune(cmd *cobra.Command, args []string) error {
+	keep, _ := cmd.Flags().GetInt("keep")
+	olderThan, _ := cmd.Flags().GetDuration("older-than")
+
+	if keep < 0 || olderThan < 0 {
+		return fmt.Errorf("--keep and --older-than must not be negative")
+	}
+	if keep == 0 && olderThan == 0 {
+		return fmt.Errorf("pass --keep, --older-than, or both")
+	}
+
+	store := newMetricsStore(nil)
+	defer store.Close()
+
+	var pruned int64
+	if olderThan > 0 {
+		deleted, err := store.PruneOlderThan(olderThan)
+		if err != nil {
+			return err
+		}
+		pruned += deleted
+	}
+	if keep > 0 {
+		deleted, err := store.Prune(keep)
+		if err != nil {
+			return err
+		}
+		pruned += deleted
+	}
+
+	if err := store.Vacuum(); err != nil {
+		return err
+	}
+
+	fmt.Printf("Pruned %d runs\n", pruned)
+	return nil
+}
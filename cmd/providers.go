@@ -0,0 +1,84 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"promptguard/internal/providers"
+)
+
+var (
+	providersCmd = &cobra.Command{
+		Use:   "providers",
+		Short: "Manage the configured providers",
+	}
+
+	providersCheckCmd = &cobra.Command{
+		Use:   "check",
+		Short: "Check that every configured provider is reachable",
+		Long: `Send each configured provider a one-token "ping" completion and report
+whether it succeeded, with its latency or error. Ollama providers are checked
+by listing their models instead, so no model is loaded.
+
+Run this before a large suite to catch missing API keys or unreachable
+endpoints up front. Exits non-zero if any provider fails.`,
+		RunE: runProvidersCheck,
+	}
+)
+
+func init() {
+	rootCmd.AddCommand(providersCmd)
+	providersCmd.AddCommand(providersCheckCmd)
+
+	providersCheckCmd.Flags().Duration("timeout", 30*time.Second, "Time to wait for each provider")
+}
+
+func runProvidersCheck(cmd *cobra.Command, args []string) error {
+	cfg, err := loadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	providers.SetHeaders(cfg.Settings.UserAgent, cfg.Settings.Headers)
+
+	timeout, _ := cmd.Flags().GetDuration("timeout")
+
+	failed := 0
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "PROVIDER\tSTATUS\tLATENCY\tDETAIL")
+	for i := range cfg.Providers {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		result := providers.Check(ctx, &cfg.Providers[i])
+		cancel()
+
+		if result.Err != nil {
+			failed++
+			fmt.Fprintf(w, "%s\tFAIL\t%s\t%v\n", result.Provider, latency(result), result.Err)
+			continue
+		}
+		fmt.Fprintf(w, "%s\tOK\t%s\t\n", result.Provider, latency(result))
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	if failed > 0 {
+		fmt.Printf("\n❌ %d of %d providers failed the check\n", failed, len(cfg.Providers))
+		os.Exit(1)
+	}
+
+	fmt.Printf("\n✅ All %d providers are reachable\n", len(cfg.Providers))
+	return nil
+}
+
+// latency formats the time a check took, or "-" if it never reached the
+// provider
+func latency(result providers.CheckResult) string {
+	if result.Latency == 0 {
+		return "-"
+	}
+	return result.Latency.Round(time.Millisecond).String()
+}
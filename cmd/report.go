@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"promptguard/internal/reporter"
+	"promptguard/internal/runner"
+)
+
+var (
+	reportCmd = &cobra.Command{
+		Use:   "report",
+		Short: "Generate a report from saved results",
+		Long: `Regenerate a report in any output format from a results file written by
+"pg test -o json" or "pg ci", without re-running the tests.
+
+The ID of a run recorded in the metrics database can be given instead of a
+results file.`,
+		RunE: runReport,
+	}
+)
+
+func init() {
+	rootCmd.AddCommand(reportCmd)
+
+	reportCmd.Flags().StringP("input", "i", "artifacts/results.json", "Results file or run ID to report on")
+	reportCmd.Flags().StringP("output", "o", "console", "Output format ("+strings.Join(reporter.Formats, ", ")+")")
+	reportCmd.Flags().String("output-file", "", "Output file path (default: stdout)")
+	reportCmd.Flags().StringVar(&reportTemplate, "template", "", "HTML report template file (default settings.reportTemplate or the built-in template)")
+	reportCmd.Flags().BoolVar(&jsonCompact, "json-compact", false, "Write JSON reports on a single line instead of indented")
+}
+
+func runReport(cmd *cobra.Command, args []string) error {
+	format := getStringFlag(cmd, "output")
+	if !isReportFormat(format) {
+		return fmt.Errorf("unknown output format %q (expected one of %s)", format, strings.Join(reporter.Formats, ", "))
+	}
+
+	var results runner.Results
+	input := getStringFlag(cmd, "input")
+	if err := loadResults(input, &results); err != nil {
+		return fmt.Errorf("failed to load results %s: %w", input, err)
+	}
+
+	// The config is optional here; it only supplies settings.reportTemplate
+	cfg, _ := loadConfig()
+	if err := reporter.Generate(newReporter(format, cfg), &results, getStringFlag(cmd, "output-file")); err != nil {
+		return fmt.Errorf("failed to generate %s report: %w", format, err)
+	}
+
+	return nil
+}
+
+func isReportFormat(format string) bool {
+	for _, f := range reporter.Formats {
+		if f == format {
+			return true
+		}
+	}
+	return false
+}
@@ -3,13 +3,19 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"os/exec"
+	"strings"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
+	"promptguard/internal/config"
+	"promptguard/internal/metrics"
+	"promptguard/internal/reporter"
 )
 
 var (
-	cfgFile string	rootCmd = &cobra.Command{
+	cfgFile string
+	rootCmd = &cobra.Command{
 		Use:   "pg",
 		Short: "PromptGaurd by Chandresh - Continuous Integration Tests for LLM Prompts",
 		Long: `PromptGaurd by Chandresh is a testing framework for LLM prompts that ensures
@@ -19,9 +25,21 @@ before they reach production.
 Think of it as a "unit-test runner for LLMs" that integrates seamlessly
 with your CI/CD pipeline.`,
 		Version: "0.1.0",
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			_, err := reporter.ColorEnabled(colorMode, os.Stdout)
+			return err
+		},
 	}
 )
 
+var (
+	pricingFile string
+	configEnv   string
+	metricsDB   string
+	colorMode   string
+	noColor     bool
+)
+
 // Execute adds all child commands to the root command and sets flags appropriately.
 func Execute() error {
 	return rootCmd.Execute()
@@ -33,6 +51,11 @@ func init() {
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is promptguard.yaml)")
 	rootCmd.PersistentFlags().Bool("verbose", false, "verbose output")
 	rootCmd.PersistentFlags().Bool("quiet", false, "quiet output")
+	rootCmd.PersistentFlags().StringVar(&pricingFile, "pricing", "", "pricing file overriding the built-in model prices")
+	rootCmd.PersistentFlags().StringVar(&metricsDB, "metrics-db", "", "metrics database path, or :memory: (default settings.metricsPath or .promptguard/metrics.db)")
+	rootCmd.PersistentFlags().StringVar(&colorMode, "color", reporter.ColorAuto, "colorize output: auto, always, or never (auto honors NO_COLOR)")
+	rootCmd.PersistentFlags().BoolVar(&noColor, "no-color", false, "disable colored output")
+	rootCmd.PersistentFlags().StringVar(&configEnv, "env", "", "environment whose config to load, e.g. prod for promptguard.prod.yaml (default $PROMPTGUARD_ENV)")
 
 	viper.BindPFlag("verbose", rootCmd.PersistentFlags().Lookup("verbose"))
 	viper.BindPFlag("quiet", rootCmd.PersistentFlags().Lookup("quiet"))
@@ -54,3 +77,86 @@ func initConfig() {
 		fmt.Fprintln(os.Stderr, "Using config file:", viper.ConfigFileUsed())
 	}
 }
+
+// environment returns the config environment from --env or PROMPTGUARD_ENV
+func environment() string {
+	if configEnv != "" {
+		return configEnv
+	}
+	return os.Getenv("PROMPTGUARD_ENV")
+}
+
+// commitSHA returns the commit under test: sha when set, else the commit CI
+// checked out (GITHUB_SHA or CI_COMMIT_SHA), else git's HEAD. It is empty
+// outside a git repository.
+func commitSHA(sha string) string {
+	for _, candidate := range []string{sha, os.Getenv("GITHUB_SHA"), os.Getenv("CI_COMMIT_SHA")} {
+		if candidate != "" {
+			return candidate
+		}
+	}
+
+	out, err := exec.Command("git", "rev-parse", "HEAD").Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// useColor reports whether output written to out should be colored
+func useColor(out *os.File) bool {
+	if noColor {
+		return false
+	}
+	enabled, _ := reporter.ColorEnabled(colorMode, out)
+	return enabled
+}
+
+// loadConfig loads the configuration and applies command-line overrides
+func loadConfig() (*config.Config, error) {
+	cfg, err := config.Load(environment())
+	if err != nil {
+		return nil, err
+	}
+
+	if pricingFile != "" {
+		pricing, err := config.LoadPricingFile(pricingFile)
+		if err != nil {
+			return nil, err
+		}
+
+		if cfg.Pricing == nil {
+			cfg.Pricing = make(map[string]config.ModelPricing)
+		}
+		for id, price := range pricing {
+			cfg.Pricing[id] = price
+		}
+	}
+
+	return cfg, nil
+}
+
+// newMetricsStore creates the metrics store at metricsDBPath, capped at the
+// config's settings.metricsMaxRuns. Commands that have not loaded the config
+// pass nil, and the config is loaded if there is one.
+func newMetricsStore(cfg *config.Config) *metrics.Store {
+	if cfg == nil {
+		cfg, _ = loadConfig()
+	}
+
+	store := metrics.NewStore(metricsDBPath(cfg))
+	if cfg != nil {
+		store.MaxRuns = cfg.Settings.MetricsMaxRuns
+	}
+	return store
+}
+
+// metricsDBPath returns the --metrics-db path, falling back to the config's
+// settings.metricsPath when cfg is not nil. An empty result selects the
+// default location.
+func metricsDBPath(cfg *config.Config) string {
+	if metricsDB != "" || cfg == nil {
+		return metricsDB
+	}
+	return cfg.Settings.MetricsPath
+}
@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/spf13/cobra"
+	"promptguard/internal/config"
+	"promptguard/internal/server"
+)
+
+var (
+	serveCmd = &cobra.Command{
+		Use:   "serve",
+		Short: "Run PromptGuard as a REST API server",
+		Long: `Run a long-lived HTTP server that accepts test runs over a REST API.
+
+Endpoints:
+- POST /run           Submit a YAML or JSON config and queue a run
+                      (add ?wait=true to block until the results are ready)
+- GET  /results/{id}  Fetch the status and results of a run
+
+Every request must send the API token as "Authorization: Bearer <token>".
+
+Posted configs run with the server's credentials, so they are restricted:
+prompts must be inline, variables cannot come from data files, and
+providers cannot set api_key, api_key_env, or org_id. Providers get the
+credentials of the provider with the same ID in the server's own config,
+and may only set a base_url or base_urls allowed with --allow-base-url.`,
+		RunE: runServe,
+	}
+)
+
+func init() {
+	rootCmd.AddCommand(serveCmd)
+
+	serveCmd.Flags().Int("port", 8081, "Port for the API server")
+	serveCmd.Flags().String("token", "", "API token required by clients (default $PROMPTGUARD_API_TOKEN)")
+	serveCmd.Flags().Int("parallel", 4, "Number of parallel test executions per run")
+	serveCmd.Flags().Int("queue-size", 100, "Maximum number of queued runs")
+	serveCmd.Flags().StringSlice("allow-base-url", nil, "Provider base URL that posted configs may use (repeatable)")
+}
+
+func runServe(cmd *cobra.Command, args []string) error {
+	token := getStringFlag(cmd, "token")
+	if token == "" {
+		token = os.Getenv("PROMPTGUARD_API_TOKEN")
+	}
+	if token == "" {
+		return fmt.Errorf("an API token is required (--token or PROMPTGUARD_API_TOKEN)")
+	}
+
+	port, _ := cmd.Flags().GetInt("port")
+	parallel, _ := cmd.Flags().GetInt("parallel")
+	queueSize, _ := cmd.Flags().GetInt("queue-size")
+
+	allowedURLs, _ := cmd.Flags().GetStringSlice("allow-base-url")
+
+	// The server's own config, if any, holds the provider credentials
+	serve := config.ServeOptions{AllowedBaseURLs: allowedURLs}
+	cfg, err := loadConfig()
+	if err == nil {
+		serve.Providers = cfg.Providers
+	}
+
+	store := newMetricsStore(cfg)
+	defer store.Close()
+
+	apiServer := server.NewServer(token, parallel, queueSize, store, serve)
+
+	fmt.Printf("PromptGuard API server listening on http://localhost:%d\n", port)
+	return http.ListenAndServe(fmt.Sprintf(":%d", port), apiServer)
+}
@@ -1,20 +1,29 @@
 package cmd
 
 import (
+	"bufio"
 	"fmt"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"io"
 	"os"
+	"promptguard/internal/cache"
+	"promptguard/internal/config"
+	"promptguard/internal/metrics"
+	"promptguard/internal/reporter"
+	"promptguard/internal/runner"
+	"promptguard/internal/warn"
+	"strings"
 	"time"
-	"github.com/spf13/cobra"
-	"promptgaurd/internal/config"
-	"promptgaurd/internal/runner"
-	"promptgaurd/internal/reporter"
 )
 
 var (
-	outputFormat string
-	outputFile   string
-	parallel     int
-	testCmd      = &cobra.Command{
+	outputFormat   string
+	outputFile     string
+	reportTemplate string // Replaces the built-in HTML report template
+	jsonCompact    bool   // Writes JSON reports on a single line
+	parallel       int
+	testCmd        = &cobra.Command{
 		Use:   "test",
 		Short: "Run prompt tests locally",
 		Long: `Run prompt tests against configured LLM providers with assertions.
@@ -26,68 +35,379 @@ This command is designed for local development and testing.`,
 func init() {
 	rootCmd.AddCommand(testCmd)
 
-	testCmd.Flags().StringVarP(&outputFormat, "output", "o", "console", "Output format (console, json, junit)")
-	testCmd.Flags().StringVar(&outputFile, "output-file", "", "Output file path")
+	testCmd.Flags().StringVarP(&outputFormat, "output", "o", "console", "Output formats, comma-separated, each optionally with its own file as format:file (console, json, junit, html, markdown, sarif)")
+	testCmd.Flags().StringVar(&outputFile, "output-file", "", "Output file path for the formats without their own file")
+	testCmd.Flags().StringVar(&reportTemplate, "template", "", "HTML report template file (default settings.reportTemplate or the built-in template)")
+	testCmd.Flags().BoolVar(&jsonCompact, "json-compact", false, "Write JSON reports on a single line instead of indented")
 	testCmd.Flags().IntVarP(&parallel, "parallel", "p", 1, "Number of parallel test executions")
-	testCmd.Flags().Bool("update-baseline", false, "Update baseline results")
+	testCmd.Flags().Bool("update-baseline", false, "Write the results to the baseline file")
+	testCmd.Flags().String("baseline-path", ".promptguard/baseline.json", "Baseline results file written by --update-baseline")
+	testCmd.Flags().Bool("force", false, "Update the baseline even when tests errored")
 	testCmd.Flags().StringSlice("filter", []string{}, "Filter tests by name pattern")
+	testCmd.Flags().BoolP("yes", "y", false, "Run without confirming an expensive run")
+	testCmd.Flags().Bool("watch", false, "Re-run tests when prompt files or the config change")
+	testCmd.Flags().Int("flaky-runs", 10, "Recent runs of the same commit checked for flaky tests (0 to disable)")
+	testCmd.Flags().String("run-id", "", "Run ID recorded in metrics and artifacts (default: generated)")
+	testCmd.Flags().String("manifest", ".promptguard/manifest.json", "Path for the run manifest (empty to disable)")
+	testCmd.Flags().Bool("no-cache", false, "Call providers even when settings.cacheResults has a cached response")
+	testCmd.Flags().Bool("fail-fast", false, "Skip the remaining tests after the first failure")
+	testCmd.Flags().Float64("cost-budget", 0, "Stop starting tests once the run costs more than this many USD (default settings.costBudget, negative to disable)")
+	testCmd.Flags().Bool("stream", false, "Print responses as they are generated (runs tests one at a time)")
 }
 
 func runTest(cmd *cobra.Command, args []string) error {
+	if getBoolFlag(cmd, "watch") {
+		return runWatch(cmd)
+	}
+
 	startTime := time.Now()
 
 	// Load configuration
-	cfg, err := config.Load()
+	cfg, err := loadConfig()
 	if err != nil {
 		return fmt.Errorf("failed to load config: %w", err)
 	}
 
+	outputs, err := parseOutputs(outputFormat, outputFile)
+	if err != nil {
+		return err
+	}
+
+	store := newMetricsStore(cfg)
+	defer store.Close()
+
+	// Machine-readable reports on stdout keep everything else on stderr
+	// so that the report stays parseable
+	summary := os.Stdout
+	for _, output := range outputs {
+		if output.file == "" && reporter.MachineReadable(output.format) {
+			summary = os.Stderr
+		}
+	}
+
+	// Streamed responses would interleave if tests ran in parallel
+	onDelta := streamDeltas(cmd, summary)
+	workers := parallel
+	if onDelta != nil && workers > 1 {
+		warn.Printf("--stream runs tests one at a time; ignoring --parallel %d", workers)
+		workers = 1
+	}
+
 	// Create test runner
 	testRunner := runner.New(cfg, runner.Options{
-		Parallel:        parallel,
-		UpdateBaseline:  cmd.Flag("update-baseline").Changed,
-		Filters:         getStringSliceFlag(cmd, "filter"),
-		Verbose:         cmd.Flag("verbose").Changed,
+		Parallel:     workers,
+		Filters:      getStringSliceFlag(cmd, "filter"),
+		Verbose:      cmd.Flag("verbose").Changed,
+		RunID:        getStringFlag(cmd, "run-id"),
+		ManifestPath: getStringFlag(cmd, "manifest"),
+		CommitSHA:    commitSHA(""),
+		Store:        store,
+		CostBudget:   getFloat64Flag(cmd, "cost-budget"),
+		FailFast:     getBoolFlag(cmd, "fail-fast"),
+		Cache:        responseCache(cmd, cfg),
+		OnDelta:      onDelta,
 	})
 
+	if !getBoolFlag(cmd, "yes") {
+		proceed, err := confirmCost(cfg, testRunner)
+		if err != nil {
+			return err
+		}
+		if !proceed {
+			fmt.Fprintln(os.Stderr, "Aborted.")
+			return nil
+		}
+	}
+
 	// Run tests
 	results, err := testRunner.Run()
 	if err != nil {
 		return fmt.Errorf("test execution failed: %w", err)
 	}
-
-	// Generate report
-	reporter := reporter.New(outputFormat)
-	if err := reporter.Generate(results, outputFile); err != nil {
-		return fmt.Errorf("failed to generate report: %w", err)
+	if onDelta != nil {
+		fmt.Fprintln(summary)
 	}
 
+	flakyRuns, _ := cmd.Flags().GetInt("flaky-runs")
+	detectFlaky(store, results, flakyRuns)
+
+	// Generate reports; one that fails to write does not stop the others
+	failedReports := generateReports(outputs, results, cfg)
+
 	// Print summary
 	duration := time.Since(startTime)
-	printTestSummary(results, duration)
+	printTestSummary(summary, useColor(summary), results, duration)
+
+	if getBoolFlag(cmd, "update-baseline") {
+		if err := updateBaseline(summary, results, getStringFlag(cmd, "baseline-path"), getBoolFlag(cmd, "force")); err != nil {
+			return err
+		}
+	}
+
+	if failedReports > 0 {
+		return fmt.Errorf("failed to generate %d of %d reports", failedReports, len(outputs))
+	}
 
 	// Exit with non-zero code if tests failed
 	if results.HasFailures() {
+		store.Close() // os.Exit skips deferred calls
 		os.Exit(1)
 	}
 
 	return nil
 }
 
-func printTestSummary(results *runner.Results, duration time.Duration) {
-	fmt.Printf("\n=== Test Summary ===\n")
-	fmt.Printf("Tests run: %d\n", results.Total)
-	fmt.Printf("Passed: %d\n", results.Passed)
-	fmt.Printf("Failed: %d\n", results.Failed)
-	fmt.Printf("Skipped: %d\n", results.Skipped)
-	fmt.Printf("Duration: %v\n", duration)
-	fmt.Printf("Total cost: $%.4f\n", results.TotalCost)
+func printTestSummary(w io.Writer, color bool, results *runner.Results, duration time.Duration) {
+	fmt.Fprintf(w, "\n=== Test Summary ===\n")
+	fmt.Fprintf(w, "Run: %s\n", results.Metadata.RunID)
+	fmt.Fprintf(w, "Tests run: %d\n", results.Total)
+	fmt.Fprintf(w, "Passed: %s\n", reporter.Paint(color, reporter.Green, fmt.Sprint(results.Passed)))
+	if results.Failed > 0 {
+		fmt.Fprintf(w, "Failed: %s\n", reporter.Paint(color, reporter.Red, fmt.Sprint(results.Failed)))
+	} else {
+		fmt.Fprintf(w, "Failed: %d\n", results.Failed)
+	}
+	fmt.Fprintf(w, "Skipped: %d\n", results.Skipped)
+	if results.Warnings > 0 {
+		fmt.Fprintf(w, "Warnings: %s (optional assertions that failed)\n", reporter.Paint(color, reporter.Yellow, fmt.Sprint(results.Warnings)))
+	}
+	if cached := cachedTests(results); cached > 0 {
+		fmt.Fprintf(w, "Cached: %d (responses reused from earlier runs)\n", cached)
+	}
+	fmt.Fprintf(w, "Duration: %v\n", duration)
+	fmt.Fprintf(w, "Total cost: %s\n", reporter.Paint(color, reporter.Yellow, fmt.Sprintf("$%.4f", results.TotalCost)))
+	if results.OverBudget {
+		fmt.Fprintf(w, "%s\n", reporter.Paint(color, reporter.Red, budgetNotice(results)))
+	}
+	if results.EvalCost > 0 {
+		fmt.Fprintf(w, "Eval cost: $%.4f (embedding calls made by assertions, included in the total)\n", results.EvalCost)
+	}
 
 	if results.HasFailures() {
-		fmt.Printf("\n❌ Some tests failed. Run 'pg view' to see details.\n")
+		fmt.Fprintf(w, "\n❌ %s\n", reporter.Paint(color, reporter.Red, "Some tests failed. Run 'pg view' to see details."))
 	} else {
-		fmt.Printf("\n✅ All tests passed!\n")
+		fmt.Fprintf(w, "\n✅ %s\n", reporter.Paint(color, reporter.Green, "All tests passed!"))
+	}
+}
+
+// reportOutput is a report format and the file it is written to; an empty
+// file is stdout
+type reportOutput struct {
+	format string
+	file   string
+}
+
+// parseOutputs parses a comma-separated list of output formats, each
+// optionally followed by its own file as format:file, such as
+// "console,json:results.json,junit:junit.xml". Formats without a file are
+// written to defaultFile, and only one of them may be since they would
+// overwrite or interleave each other.
+func parseOutputs(spec, defaultFile string) ([]reportOutput, error) {
+	var outputs []reportOutput
+	shared := 0
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		output := reportOutput{format: entry, file: defaultFile}
+		if format, file, ok := strings.Cut(entry, ":"); ok {
+			if file == "" {
+				return nil, fmt.Errorf("output %q has no file after the colon", entry)
+			}
+			output = reportOutput{format: format, file: file}
+		} else {
+			shared++
+		}
+		if !isReportFormat(output.format) {
+			return nil, fmt.Errorf("unknown output format %q (expected one of %s)", output.format, strings.Join(reporter.Formats, ", "))
+		}
+		outputs = append(outputs, output)
+	}
+
+	if len(outputs) == 0 {
+		return nil, fmt.Errorf("no output format given")
+	}
+	if shared > 1 {
+		destination := "stdout"
+		if defaultFile != "" {
+			destination = defaultFile
+		}
+		return nil, fmt.Errorf("%d output formats would all be written to %s; give each but one its own file as format:file", shared, destination)
+	}
+	return outputs, nil
+}
+
+// generateReports writes each report, warning about the ones that fail
+// without giving up on the rest, and returns how many failed
+func generateReports(outputs []reportOutput, results *runner.Results, cfg *config.Config) int {
+	failed := 0
+	for _, output := range outputs {
+		if err := reporter.Generate(newReporter(output.format, cfg), results, output.file); err != nil {
+			warn.Printf("failed to generate %s report: %v", output.format, err)
+			failed++
+		}
+	}
+	return failed
+}
+
+// newReporter creates the reporter for format, identifying the tool by the
+// CLI version where the format records it, coloring console output per
+// --color with per-test detail under --verbose, rendering HTML with the
+// --template file or the config's settings.reportTemplate, writing JSON on a
+// single line under --json-compact, and truncating long responses per
+// settings.maxResponseChars. cfg may be nil.
+func newReporter(format string, cfg *config.Config) reporter.Reporter {
+	report := reporter.New(format)
+	switch r := report.(type) {
+	case *reporter.SARIFReporter:
+		r.ToolVersion = rootCmd.Version
+	case *reporter.JSONReporter:
+		r.Compact = jsonCompact
+	case *reporter.HTMLReporter:
+		r.TemplateFile = reportTemplate
+		if r.TemplateFile == "" && cfg != nil {
+			r.TemplateFile = cfg.Settings.ReportTemplate
+		}
+		r.MaxResponseChars = maxResponseChars(cfg)
+	case *reporter.MarkdownReporter:
+		r.MaxResponseChars = maxResponseChars(cfg)
+	case *reporter.JUnitReporter:
+		r.MaxResponseChars = maxResponseChars(cfg)
+	case *reporter.ConsoleReporter:
+		r.Color = useColor(os.Stdout)
+		r.Verbose = viper.GetBool("verbose")
+	}
+	return report
+}
+
+// maxResponseChars returns the response length at which reports truncate,
+// from settings.maxResponseChars; 0 means no truncation
+func maxResponseChars(cfg *config.Config) int {
+	if cfg == nil || cfg.Settings.MaxResponseChars == 0 {
+		return reporter.DefaultMaxResponseChars
+	}
+	if cfg.Settings.MaxResponseChars < 0 {
+		return 0
+	}
+	return cfg.Settings.MaxResponseChars
+}
+
+// defaultConfirmCost is the estimated cost in USD above which a run needs
+// confirmation when settings.confirmCost is unset
+const defaultConfirmCost = 1.0
+
+// confirmCost estimates the cost of the run and, when it exceeds the
+// configured threshold, asks whether to continue. Without a terminal to ask,
+// the estimate is printed and the run continues.
+func confirmCost(cfg *config.Config, testRunner *runner.Runner) (bool, error) {
+	threshold := cfg.Settings.ConfirmCost
+	if threshold == 0 {
+		threshold = defaultConfirmCost
+	}
+	if threshold < 0 {
+		return true, nil
+	}
+
+	estimate, err := testRunner.EstimateCost()
+	if err != nil {
+		return false, fmt.Errorf("failed to estimate cost: %w", err)
+	}
+	if estimate.Cost <= threshold {
+		return true, nil
+	}
+
+	fmt.Fprintf(os.Stderr, "Estimated cost: up to ~$%.2f for %d provider calls, %d at a time\n",
+		estimate.Cost, estimate.Calls, parallel)
+	if estimate.Unpriced > 0 {
+		fmt.Fprintf(os.Stderr, "(%d calls to models without known pricing are not included)\n", estimate.Unpriced)
+	}
+
+	if !isInteractive() {
+		fmt.Fprintln(os.Stderr, "Not running interactively; continuing. Pass --yes to skip this check.")
+		return true, nil
+	}
+
+	fmt.Fprintf(os.Stderr, "Continue? [y/N] ")
+	answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil {
+		return false, nil
+	}
+
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes", nil
+}
+
+// isInteractive reports whether a user can answer prompts on stdin
+func isInteractive() bool {
+	if os.Getenv("CI") != "" {
+		return false
+	}
+	info, err := os.Stdin.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// detectFlaky sets the tests that flipped between passing and failing over
+// the last runs of the same commit, including the one just stored. Runs
+// without a commit are skipped, since their prompts may have changed
+// between runs.
+func detectFlaky(store *metrics.Store, results *runner.Results, runs int) {
+	if runs < 2 || results.Metadata.CommitSHA == "" {
+		return
+	}
+
+	flaky, err := store.FlakyTests(results.Metadata.CommitSHA, runs)
+	if err != nil {
+		warn.Printf("failed to detect flaky tests: %v", err)
+		return
+	}
+	results.Flaky = flaky
+}
+
+// responseCache returns the response cache configured by settings, or nil
+// when caching is off or --no-cache is set
+func responseCache(cmd *cobra.Command, cfg *config.Config) *cache.Cache {
+	if getBoolFlag(cmd, "no-cache") {
+		return nil
+	}
+	return cache.FromSettings(cfg.Settings)
+}
+
+// streamDeltas returns the runner callback that prints each response to w as
+// it is generated, under the name of its test, or nil without --stream
+func streamDeltas(cmd *cobra.Command, w io.Writer) func(test, delta string) {
+	if !getBoolFlag(cmd, "stream") {
+		return nil
+	}
+
+	current := ""
+	return func(test, delta string) {
+		if test != current {
+			if current != "" {
+				fmt.Fprintln(w)
+			}
+			fmt.Fprintf(w, "── %s ──\n", test)
+			current = test
+		}
+		fmt.Fprint(w, delta)
+	}
+}
+
+// budgetNotice describes a run that went over its cost budget
+func budgetNotice(results *runner.Results) string {
+	return fmt.Sprintf("Cost budget of $%.4f exceeded: %d tests skipped", results.CostBudget, results.Skipped)
+}
+
+// cachedTests counts the tests whose response came from the cache
+func cachedTests(results *runner.Results) int {
+	cached := 0
+	for _, test := range results.TestResults {
+		if test.Cached {
+			cached++
+		}
 	}
+	return cached
 }
 
 func getStringSliceFlag(cmd *cobra.Command, name string) []string {
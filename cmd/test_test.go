@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"promptguard/internal/runner"
+)
+
+// TestMain runs the pg command line instead of the tests when PG_CMD_ARGS is
+// set, so that tests can run pg in a child process and capture its stdout
+func TestMain(m *testing.M) {
+	if args := os.Getenv("PG_CMD_ARGS"); args != "" {
+		rootCmd.SetArgs(strings.Fields(args))
+		if err := Execute(); err != nil {
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// runPG runs pg with args in dir and returns its stdout and stderr
+func runPG(t *testing.T, dir, args string) (string, string) {
+	t.Helper()
+
+	cmd := exec.Command(os.Args[0], "-test.run=^$")
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "PG_CMD_ARGS="+args, "NO_COLOR=1", "CI=")
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("pg %s: %v\nstderr:\n%s", args, err, stderr.String())
+	}
+	return stdout.String(), stderr.String()
+}
+
+const mockConfig = `
+inlinePrompts:
+  greet: "Say hi to {{ .name }}."
+providers:
+  - id: mock:echo
+tests:
+  - name: greets
+    vars:
+      name: Ada
+    assert:
+      - type: cost
+        threshold: 0.01
+`
+
+func TestJSONOutputKeepsStdoutParseable(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "promptguard.yaml"), []byte(mockConfig), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	stdout, stderr := runPG(t, dir, "test -o json --yes --manifest= --metrics-db :memory:")
+
+	var results runner.Results
+	decoder := json.NewDecoder(strings.NewReader(stdout))
+	if err := decoder.Decode(&results); err != nil {
+		t.Fatalf("stdout is not JSON: %v\nstdout:\n%s", err, stdout)
+	}
+	if rest := strings.TrimSpace(stdout[decoder.InputOffset():]); rest != "" {
+		t.Fatalf("stdout has more than the JSON report:\n%s", rest)
+	}
+	if results.Total != 1 || results.Passed != 1 {
+		t.Errorf("results = %d total, %d passed; want 1, 1", results.Total, results.Passed)
+	}
+
+	if !strings.Contains(stderr, "Test Summary") {
+		t.Errorf("summary not written to stderr:\n%s", stderr)
+	}
+}
@@ -1,13 +1,18 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
+	"github.com/spf13/cobra"
+	"net"
 	"net/http"
 	"os"
 	"os/exec"
+	"os/signal"
+	"promptguard/internal/viewer"
 	"runtime"
-	"github.com/spf13/cobra"
-	"promptgaurd/internal/viewer"
+	"syscall"
+	"time"
 )
 
 var (
@@ -32,6 +37,7 @@ func init() {
 
 	viewCmd.Flags().IntVarP(&port, "port", "p", 8080, "Port for the web server")
 	viewCmd.Flags().String("results-file", "artifacts/results.json", "Path to results file")
+	viewCmd.Flags().String("baseline", ".promptguard/baseline.json", "Baseline results file to compare with")
 	viewCmd.Flags().Bool("open-browser", true, "Automatically open browser")
 }
 
@@ -46,15 +52,23 @@ func runView(cmd *cobra.Command, args []string) error {
 		return nil
 	}
 
-	// Create and start the viewer server
-	server := viewer.NewServer(resultsFile)
-	
-	// Start server in background
+	// Create the viewer server
+	// The viewer works without a config; use its metrics path when present
+	cfg, _ := loadConfig()
+	handler := viewer.NewServer(resultsFile, getStringFlag(cmd, "baseline"), metricsDBPath(cfg))
+	handler.MaxResponseChars = maxResponseChars(cfg)
+	defer handler.Close()
+
+	// Bind before serving so a port that is already in use is reported
+	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+	if err != nil {
+		return fmt.Errorf("failed to start viewer on port %d: %w", port, err)
+	}
+
+	server := &http.Server{Handler: handler}
+	serveErr := make(chan error, 1)
 	go func() {
-		fmt.Printf("Starting PromptGuard viewer on http://localhost:%d\n", port)
-		if err := http.ListenAndServe(fmt.Sprintf(":%d", port), server); err != nil {
-			fmt.Printf("Server error: %v\n", err)
-		}
+		serveErr <- server.Serve(listener)
 	}()
 
 	// Open browser if requested
@@ -69,8 +83,25 @@ func runView(cmd *cobra.Command, args []string) error {
 	fmt.Printf("PromptGuard viewer running on http://localhost:%d\n", port)
 	fmt.Println("Press Ctrl+C to stop")
 
-	// Keep the server running
-	select {}
+	// Run until interrupted, then let in-flight requests finish
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	select {
+	case err := <-serveErr:
+		return fmt.Errorf("viewer server error: %w", err)
+	case <-ctx.Done():
+	}
+
+	fmt.Println("\nShutting down...")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("failed to shut down viewer: %w", err)
+	}
+
+	return nil
 }
 
 func openBrowserURL(url string) error {
@@ -0,0 +1,216 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/spf13/cobra"
+	"promptguard/internal/config"
+	"promptguard/internal/prompts"
+	"promptguard/internal/runner"
+	"promptguard/internal/warn"
+)
+
+// watchDebounce is how long to wait after the last change before re-running,
+// so that editors saving several files at once trigger a single run
+const watchDebounce = 300 * time.Millisecond
+
+// testWatcher re-runs tests when the config or prompt files change
+type testWatcher struct {
+	cmd        *cobra.Command
+	configPath string
+	cfg        *config.Config
+	watcher    *fsnotify.Watcher
+
+	// statuses holds the latest status of every test, keyed by prompt
+	// file, name, and provider, for the incremental suite summary
+	statuses map[string]string
+}
+
+// runWatch runs the test suite, then watches the config and prompt files and
+// re-runs affected tests until interrupted
+func runWatch(cmd *cobra.Command) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	configPath, err := config.Find(environment())
+	if err != nil {
+		return err
+	}
+
+	fsWatcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to start file watcher: %w", err)
+	}
+	defer fsWatcher.Close()
+
+	w := &testWatcher{
+		cmd:        cmd,
+		configPath: configPath,
+		watcher:    fsWatcher,
+	}
+
+	if err := w.reload(); err != nil {
+		return err
+	}
+	w.run(nil)
+
+	// Editors often save by renaming a temporary file over the original, so
+	// watch the containing directories and match events by file name
+	var timer *time.Timer
+	var fire <-chan time.Time
+	changed := make(map[string]bool)
+
+	for {
+		select {
+		case <-ctx.Done():
+			fmt.Printf("\nStopped watching.\n")
+			return nil
+
+		case event, ok := <-fsWatcher.Events:
+			if !ok {
+				return nil
+			}
+			if !event.Has(fsnotify.Write) && !event.Has(fsnotify.Create) && !event.Has(fsnotify.Rename) {
+				continue
+			}
+			path := filepath.Clean(event.Name)
+			if !w.isWatched(path) {
+				continue
+			}
+
+			changed[path] = true
+			if timer == nil {
+				timer = time.NewTimer(watchDebounce)
+			} else {
+				timer.Reset(watchDebounce)
+			}
+			fire = timer.C
+
+		case err, ok := <-fsWatcher.Errors:
+			if !ok {
+				return nil
+			}
+			warn.Printf("file watcher error: %v", err)
+
+		case <-fire:
+			fire = nil
+			w.handleChanges(changed)
+			changed = make(map[string]bool)
+		}
+	}
+}
+
+// handleChanges reloads the config if it changed and re-runs the affected tests
+func (w *testWatcher) handleChanges(changed map[string]bool) {
+	files := make([]string, 0, len(changed))
+	for path := range changed {
+		files = append(files, path)
+	}
+	sort.Strings(files)
+
+	fmt.Printf("\n[%s] Changed: %s\n", time.Now().Format("15:04:05"), strings.Join(files, ", "))
+
+	if changed[filepath.Clean(w.configPath)] {
+		if err := w.reload(); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return
+		}
+		w.run(nil)
+		return
+	}
+
+	w.run(files)
+}
+
+// reload loads the config, resets the suite summary, and watches the
+// directories of the config and prompt files
+func (w *testWatcher) reload() error {
+	cfg, err := loadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	w.cfg = cfg
+	w.statuses = make(map[string]string)
+
+	dirs := map[string]bool{filepath.Dir(w.configPath): true}
+	for _, file := range cfg.Prompts {
+		if !prompts.IsRemote(file) {
+			dirs[filepath.Dir(file)] = true
+		}
+	}
+	for dir := range dirs {
+		if err := w.watcher.Add(dir); err != nil {
+			return fmt.Errorf("failed to watch %s: %w", dir, err)
+		}
+	}
+
+	return nil
+}
+
+// isWatched reports whether path is the config file or one of its prompt files
+func (w *testWatcher) isWatched(path string) bool {
+	if path == filepath.Clean(w.configPath) {
+		return true
+	}
+	for _, file := range w.cfg.Prompts {
+		if path == filepath.Clean(file) {
+			return true
+		}
+	}
+	return false
+}
+
+// run executes the tests of the given prompt files, or all tests if none are
+// given, and prints the changed results with a summary of the whole suite
+func (w *testWatcher) run(promptFiles []string) {
+	testRunner := runner.New(w.cfg, runner.Options{
+		Parallel:    parallel,
+		Filters:     getStringSliceFlag(w.cmd, "filter"),
+		PromptFiles: promptFiles,
+	})
+
+	results, err := testRunner.Run()
+	if err != nil {
+		fmt.Printf("Error: test execution failed: %v\n", err)
+		return
+	}
+
+	for _, test := range results.TestResults {
+		w.statuses[test.PromptFile+"\x00"+test.Name+"\x00"+test.Provider] = test.Status
+
+		if test.Status != "failed" {
+			continue
+		}
+		fmt.Printf("  ❌ %s (%s)\n", test.Name, test.PromptFile)
+		if test.Error != "" {
+			fmt.Printf("     Error: %s\n", test.Error)
+		}
+		for _, assertion := range test.Assertions {
+			if assertion.Failed() {
+				fmt.Printf("     %s: %s\n", assertion.Type, assertion.Message)
+			}
+		}
+	}
+
+	passed, failed := 0, 0
+	for _, status := range w.statuses {
+		switch status {
+		case "passed":
+			passed++
+		case "failed":
+			failed++
+		}
+	}
+
+	fmt.Printf("Ran %d tests: %d passed, %d failed ($%.4f)\n", results.Total, results.Passed, results.Failed, results.TotalCost)
+	fmt.Printf("Suite: %d passed, %d failed of %d tests. Watching for changes (Ctrl+C to exit)...\n", passed, failed, len(w.statuses))
+}
@@ -3,16 +3,37 @@ package assertions
 import (
 	"encoding/json"
 	"fmt"
-	"regexp"	"strings"
+	"math"
+	"regexp"
+	"strings"
+	"sync"
 
-	"promptgaurd/internal/config"
-	"promptgaurd/internal/providers"
-	"promptgaurd/internal/runner"
+	"promptguard/internal/config"
+	"promptguard/internal/providers"
 )
 
+// AssertionResult represents a single assertion result
+type AssertionResult struct {
+	Type     string      `json:"type"`
+	Expected interface{} `json:"expected"`
+	Actual   interface{} `json:"actual"`
+	Passed   bool        `json:"passed"`
+	Score    float64     `json:"score,omitempty"`
+	Message  string      `json:"message,omitempty"`
+	Cost     float64     `json:"cost,omitempty"`    // Embedding or other calls the assertion made
+	Warning  bool        `json:"warning,omitempty"` // Failed, but the assertion is not required
+	Turn     int         `json:"turn,omitempty"`    // Conversation turn judged, from 1; 0 is the last response
+}
+
+// Failed reports whether the assertion failed its test. A failed optional
+// assertion is a warning instead.
+func (a AssertionResult) Failed() bool {
+	return !a.Passed && !a.Warning
+}
+
 // Evaluator interface for different assertion types
 type Evaluator interface {
-	Evaluate(assertion config.Assertion, response *providers.Response) (runner.AssertionResult, error)
+	Evaluate(assertion config.Assertion, response *providers.Response) (AssertionResult, error)
 }
 
 // NewEvaluator creates a new evaluator for the given assertion type
@@ -32,6 +53,32 @@ func NewEvaluator(assertionType string) Evaluator {
 		return &ToxicityEvaluator{}
 	case "jailbreak":
 		return &JailbreakEvaluator{}
+	case "pii":
+		return &PIIEvaluator{}
+	case "matches-examples":
+		return &MatchesExamplesEvaluator{}
+	case "semantic-similarity":
+		return &SemanticSimilarityEvaluator{}
+	case "min-confidence":
+		return &MinConfidenceEvaluator{}
+	case "list-count":
+		return &ListCountEvaluator{}
+	case "latency-p95":
+		return &LatencyP95Evaluator{}
+	case "no-repetition":
+		return &NoRepetitionEvaluator{}
+	case "matches-struct":
+		return &MatchesStructEvaluator{}
+	case "expect-filtered":
+		return &ExpectFilteredEvaluator{}
+	case "no-prompt-leak":
+		return &NoPromptLeakEvaluator{}
+	case "conversation-contains":
+		return &ConversationContainsEvaluator{}
+	case "conversation-not-contains":
+		return &ConversationContainsEvaluator{Negate: true}
+	case "tool-call":
+		return &ToolCallEvaluator{}
 	default:
 		return &UnsupportedEvaluator{Type: assertionType}
 	}
@@ -40,14 +87,24 @@ func NewEvaluator(assertionType string) Evaluator {
 // AnswerRelevanceEvaluator evaluates answer relevance
 type AnswerRelevanceEvaluator struct{}
 
-func (e *AnswerRelevanceEvaluator) Evaluate(assertion config.Assertion, response *providers.Response) (runner.AssertionResult, error) {
-	expectedValue, ok := assertion.Value.(string)
-	if !ok {
-		return runner.AssertionResult{}, fmt.Errorf("answer-relevance assertion value must be a string")
+func (e *AnswerRelevanceEvaluator) Evaluate(assertion config.Assertion, response *providers.Response) (AssertionResult, error) {
+	candidates, err := relevanceCandidates(assertion.Type, assertion.Value)
+	if err != nil {
+		return AssertionResult{}, err
 	}
 
 	// Simple keyword-based relevance check (in real implementation, would use embeddings/LLM)
-	score := calculateRelevanceScore(response.Text, expectedValue)
+	// Score every acceptable phrasing and keep the best one
+	bestIndex := 0
+	score := -1.0
+	for i, candidate := range candidates {
+		candidateScore := calculateRelevanceScore(response.Text, candidate)
+		if candidateScore > score {
+			score = candidateScore
+			bestIndex = i
+		}
+	}
+
 	threshold := assertion.Threshold
 	if threshold == 0 {
 		threshold = 0.7 // Default threshold
@@ -55,24 +112,31 @@ func (e *AnswerRelevanceEvaluator) Evaluate(assertion config.Assertion, response
 
 	passed := score >= threshold
 
-	return runner.AssertionResult{
+	message := fmt.Sprintf("Relevance score: %.2f (threshold: %.2f)", score, threshold)
+	var expected interface{} = candidates[0]
+	if len(candidates) > 1 {
+		expected = candidates
+		message = fmt.Sprintf("Relevance score: %.2f (threshold: %.2f, best match: %q)", score, threshold, candidates[bestIndex])
+	}
+
+	return AssertionResult{
 		Type:     "answer-relevance",
-		Expected: expectedValue,
+		Expected: expected,
 		Actual:   response.Text,
 		Passed:   passed,
 		Score:    score,
-		Message:  fmt.Sprintf("Relevance score: %.2f (threshold: %.2f)", score, threshold),
+		Message:  message,
 	}, nil
 }
 
 // ContainsJSONEvaluator checks if response contains valid JSON
 type ContainsJSONEvaluator struct{}
 
-func (e *ContainsJSONEvaluator) Evaluate(assertion config.Assertion, response *providers.Response) (runner.AssertionResult, error) {
+func (e *ContainsJSONEvaluator) Evaluate(assertion config.Assertion, response *providers.Response) (AssertionResult, error) {
 	// Extract JSON from response
 	jsonStr := extractJSON(response.Text)
-	
-	result := runner.AssertionResult{
+
+	result := AssertionResult{
 		Type:     "contains-json",
 		Expected: assertion.Value,
 		Actual:   jsonStr,
@@ -112,11 +176,11 @@ func (e *ContainsJSONEvaluator) Evaluate(assertion config.Assertion, response *p
 // CostEvaluator checks if the cost is within threshold
 type CostEvaluator struct{}
 
-func (e *CostEvaluator) Evaluate(assertion config.Assertion, response *providers.Response) (runner.AssertionResult, error) {
+func (e *CostEvaluator) Evaluate(assertion config.Assertion, response *providers.Response) (AssertionResult, error) {
 	threshold := assertion.Threshold
 	passed := response.Cost <= threshold
 
-	return runner.AssertionResult{
+	return AssertionResult{
 		Type:     "cost",
 		Expected: threshold,
 		Actual:   response.Cost,
@@ -125,12 +189,65 @@ func (e *CostEvaluator) Evaluate(assertion config.Assertion, response *providers
 	}, nil
 }
 
+// MinConfidenceEvaluator checks that the average token confidence of the
+// response reaches a threshold. It passes with a note when the provider
+// returned no logprobs.
+type MinConfidenceEvaluator struct{}
+
+func (e *MinConfidenceEvaluator) Evaluate(assertion config.Assertion, response *providers.Response) (AssertionResult, error) {
+	threshold := assertion.Threshold
+	if threshold == 0 {
+		threshold = 0.5 // Default threshold
+	}
+
+	if response.Confidence == nil {
+		return AssertionResult{
+			Type:     "min-confidence",
+			Expected: threshold,
+			Passed:   true,
+			Message:  fmt.Sprintf("Skipped: %s:%s returned no logprobs", response.Provider, response.Model),
+		}, nil
+	}
+
+	confidence := *response.Confidence
+	return AssertionResult{
+		Type:     "min-confidence",
+		Expected: threshold,
+		Actual:   confidence,
+		Passed:   confidence >= threshold,
+		Score:    confidence,
+		Message:  fmt.Sprintf("Confidence: %.2f (threshold: %.2f)", confidence, threshold),
+	}, nil
+}
+
+// ExpectFilteredEvaluator passes when the provider's content filter blocked
+// the response, for safety tests that check the filter fires
+type ExpectFilteredEvaluator struct{}
+
+func (e *ExpectFilteredEvaluator) Evaluate(assertion config.Assertion, response *providers.Response) (AssertionResult, error) {
+	message := "Response was blocked by the content filter"
+	if !response.Filtered {
+		message = "Response was not blocked by the content filter"
+		if response.FinishReason != "" {
+			message += fmt.Sprintf(" (finish reason: %s)", response.FinishReason)
+		}
+	}
+
+	return AssertionResult{
+		Type:     "expect-filtered",
+		Expected: true,
+		Actual:   response.Filtered,
+		Passed:   response.Filtered,
+		Message:  message,
+	}, nil
+}
+
 // LLMRubricEvaluator uses an LLM to grade the response
 type LLMRubricEvaluator struct{}
 
-func (e *LLMRubricEvaluator) Evaluate(assertion config.Assertion, response *providers.Response) (runner.AssertionResult, error) {
+func (e *LLMRubricEvaluator) Evaluate(assertion config.Assertion, response *providers.Response) (AssertionResult, error) {
 	// TODO: Implement LLM-based rubric grading
-	return runner.AssertionResult{
+	return AssertionResult{
 		Type:    "llm-rubric",
 		Passed:  false,
 		Message: "LLM rubric evaluation not yet implemented",
@@ -140,91 +257,281 @@ func (e *LLMRubricEvaluator) Evaluate(assertion config.Assertion, response *prov
 // ClosedQAEvaluator evaluates closed-ended question answers
 type ClosedQAEvaluator struct{}
 
-func (e *ClosedQAEvaluator) Evaluate(assertion config.Assertion, response *providers.Response) (runner.AssertionResult, error) {
+func (e *ClosedQAEvaluator) Evaluate(assertion config.Assertion, response *providers.Response) (AssertionResult, error) {
 	// TODO: Implement closed-QA evaluation
-	return runner.AssertionResult{
+	return AssertionResult{
 		Type:    "closed-qa",
 		Passed:  false,
 		Message: "Closed-QA evaluation not yet implemented",
 	}, nil
 }
 
-// ToxicityEvaluator checks for toxic content
-type ToxicityEvaluator struct{}
-
-func (e *ToxicityEvaluator) Evaluate(assertion config.Assertion, response *providers.Response) (runner.AssertionResult, error) {
-	// Simple toxicity check using keyword detection
-	toxicKeywords := []string{"hate", "violence", "offensive", "toxic"}
-	
-	text := strings.ToLower(response.Text)
-	for _, keyword := range toxicKeywords {
-		if strings.Contains(text, keyword) {
-			return runner.AssertionResult{
-				Type:    "toxicity",
-				Passed:  false,
-				Message: fmt.Sprintf("Toxic content detected: %s", keyword),
-			}, nil
-		}
-	}
-
-	return runner.AssertionResult{
-		Type:    "toxicity",
-		Passed:  true,
-		Message: "No toxic content detected",
-	}, nil
-}
-
 // JailbreakEvaluator checks for jailbreak attempts
 type JailbreakEvaluator struct{}
 
-func (e *JailbreakEvaluator) Evaluate(assertion config.Assertion, response *providers.Response) (runner.AssertionResult, error) {
+func (e *JailbreakEvaluator) Evaluate(assertion config.Assertion, response *providers.Response) (AssertionResult, error) {
 	// TODO: Implement jailbreak detection
-	return runner.AssertionResult{
+	return AssertionResult{
 		Type:    "jailbreak",
 		Passed:  true,
 		Message: "Jailbreak detection not yet implemented",
 	}, nil
 }
 
+// PIIEvaluator checks that the response does not leak personal data
+type PIIEvaluator struct{}
+
+// piiDetectors are checked in order; each maps a PII category to its pattern
+// and an optional validator used to drop false positives
+var piiDetectors = []struct {
+	category string
+	pattern  *regexp.Regexp
+	valid    func(match string) bool
+}{
+	{"email", regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`), nil},
+	{"ssn", regexp.MustCompile(`\b\d{3}-\d{2}-\d{4}\b`), nil},
+	{"credit-card", regexp.MustCompile(`\b(?:\d[ -]?){12,18}\d\b`), luhnValid},
+	{"phone", regexp.MustCompile(`(?:\+\d{1,3}[\s.-]?)?(?:\(\d{3}\)|\b\d{3})[\s.-]?\d{3}[\s.-]?\d{4}\b`), nil},
+}
+
+func (e *PIIEvaluator) Evaluate(assertion config.Assertion, response *providers.Response) (AssertionResult, error) {
+	allowed := make(map[string]bool)
+	if assertion.Value != nil {
+		values, ok := assertion.Value.([]interface{})
+		if !ok {
+			return AssertionResult{}, fmt.Errorf("pii assertion value must be a list of allowed categories")
+		}
+		for _, value := range values {
+			category, ok := value.(string)
+			if !ok {
+				return AssertionResult{}, fmt.Errorf("pii assertion categories must be strings")
+			}
+			allowed[category] = true
+		}
+	}
+
+	var detected []string
+	for _, detector := range piiDetectors {
+		if allowed[detector.category] {
+			continue
+		}
+
+		count := 0
+		for _, match := range detector.pattern.FindAllString(response.Text, -1) {
+			if detector.valid == nil || detector.valid(match) {
+				count++
+			}
+		}
+		if count > 0 {
+			detected = append(detected, fmt.Sprintf("%s (%d)", detector.category, count))
+		}
+	}
+
+	if len(detected) > 0 {
+		return AssertionResult{
+			Type:     "pii",
+			Expected: assertion.Value,
+			Actual:   detected,
+			Passed:   false,
+			Message:  fmt.Sprintf("PII detected: %s", strings.Join(detected, ", ")),
+		}, nil
+	}
+
+	return AssertionResult{
+		Type:     "pii",
+		Expected: assertion.Value,
+		Passed:   true,
+		Message:  "No PII detected",
+	}, nil
+}
+
+// MatchesExamplesEvaluator checks that the response resembles at least one
+// exemplar response
+type MatchesExamplesEvaluator struct{}
+
+// exemplarVectors caches term vectors of exemplars across tests
+var exemplarVectors sync.Map
+
+func (e *MatchesExamplesEvaluator) Evaluate(assertion config.Assertion, response *providers.Response) (AssertionResult, error) {
+	values, ok := assertion.Value.([]interface{})
+	if !ok || len(values) == 0 {
+		return AssertionResult{}, fmt.Errorf("matches-examples assertion value must be a non-empty list of example responses")
+	}
+
+	threshold := assertion.Threshold
+	if threshold == 0 {
+		threshold = 0.7 // Default threshold
+	}
+
+	responseVector := termVector(response.Text)
+
+	closest := ""
+	best := -1.0
+	for _, value := range values {
+		example, ok := value.(string)
+		if !ok {
+			return AssertionResult{}, fmt.Errorf("matches-examples examples must be strings")
+		}
+
+		vector, ok := exemplarVectors.Load(example)
+		if !ok {
+			vector, _ = exemplarVectors.LoadOrStore(example, termVector(example))
+		}
+
+		similarity := cosineSimilarity(responseVector, vector.(map[string]float64))
+		if similarity > best {
+			best = similarity
+			closest = example
+		}
+	}
+
+	return AssertionResult{
+		Type:     "matches-examples",
+		Expected: closest,
+		Actual:   response.Text,
+		Passed:   best >= threshold,
+		Score:    best,
+		Message:  fmt.Sprintf("Closest example similarity: %.2f (threshold: %.2f): %q", best, threshold, truncate(closest, 80)),
+	}, nil
+}
+
 // UnsupportedEvaluator handles unsupported assertion types
 type UnsupportedEvaluator struct {
 	Type string
 }
 
-func (e *UnsupportedEvaluator) Evaluate(assertion config.Assertion, response *providers.Response) (runner.AssertionResult, error) {
-	return runner.AssertionResult{}, fmt.Errorf("unsupported assertion type: %s", e.Type)
+func (e *UnsupportedEvaluator) Evaluate(assertion config.Assertion, response *providers.Response) (AssertionResult, error) {
+	return AssertionResult{}, fmt.Errorf("unsupported assertion type: %s", e.Type)
 }
 
 // Helper functions
 
+// relevanceCandidates normalizes an answer-relevance or semantic-similarity
+// value, which may be a single string or a list of acceptable phrasings
+func relevanceCandidates(assertionType string, value interface{}) ([]string, error) {
+	switch v := value.(type) {
+	case string:
+		return []string{v}, nil
+	case []interface{}:
+		candidates := make([]string, 0, len(v))
+		for _, item := range v {
+			str, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("%s assertion values must be strings", assertionType)
+			}
+			candidates = append(candidates, str)
+		}
+		if len(candidates) == 0 {
+			return nil, fmt.Errorf("%s assertion value list is empty", assertionType)
+		}
+		return candidates, nil
+	case []string:
+		if len(v) == 0 {
+			return nil, fmt.Errorf("%s assertion value list is empty", assertionType)
+		}
+		return v, nil
+	default:
+		return nil, fmt.Errorf("%s assertion value must be a string or a list of strings", assertionType)
+	}
+}
+
 func calculateRelevanceScore(text, expectedContent string) float64 {
 	// Simple keyword-based relevance scoring
 	// In a real implementation, this would use embeddings or LLM-based evaluation
-	
+
 	text = strings.ToLower(text)
 	expectedContent = strings.ToLower(expectedContent)
-	
+
 	words := strings.Fields(expectedContent)
 	matches := 0
-	
+
 	for _, word := range words {
 		if strings.Contains(text, word) {
 			matches++
 		}
 	}
-	
+
 	if len(words) == 0 {
 		return 0
 	}
-	
+
 	return float64(matches) / float64(len(words))
 }
 
+// termVector builds a term-frequency vector of the lowercased words in text
+func termVector(text string) map[string]float64 {
+	vector := make(map[string]float64)
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r > 127)
+	})
+	for _, word := range words {
+		vector[word]++
+	}
+	return vector
+}
+
+// cosineSimilarity returns the cosine similarity of two term vectors
+func cosineSimilarity(a, b map[string]float64) float64 {
+	var dot, normA, normB float64
+	for term, weight := range a {
+		dot += weight * b[term]
+		normA += weight * weight
+	}
+	for _, weight := range b {
+		normB += weight * weight
+	}
+
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
+
+// truncate shortens s to at most n runes for display in messages
+func truncate(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n]) + "..."
+}
+
+// luhnValid reports whether a digit sequence (with optional spaces or dashes)
+// passes the Luhn checksum used by payment card numbers
+func luhnValid(number string) bool {
+	sum := 0
+	digits := 0
+	double := false
+
+	for i := len(number) - 1; i >= 0; i-- {
+		c := number[i]
+		if c == ' ' || c == '-' {
+			continue
+		}
+		if c < '0' || c > '9' {
+			return false
+		}
+
+		d := int(c - '0')
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		digits++
+		double = !double
+	}
+
+	return digits >= 13 && digits <= 19 && sum%10 == 0
+}
+
 func extractJSON(text string) string {
 	// Extract JSON from text using regex
 	jsonRegex := regexp.MustCompile(`\{[^{}]*(?:\{[^{}]*\}[^{}]*)*\}`)
 	matches := jsonRegex.FindAllString(text, -1)
-	
+
 	for _, match := range matches {
 		// Try to parse each potential JSON
 		var parsed interface{}
@@ -232,31 +539,31 @@ func extractJSON(text string) string {
 			return match
 		}
 	}
-	
+
 	return ""
 }
 
 func validateJSONSchema(data interface{}, schema map[string]interface{}) error {
 	// Basic JSON schema validation
 	// In a real implementation, would use a proper JSON schema validator
-	
+
 	if required, ok := schema["required"].([]interface{}); ok {
 		dataMap, ok := data.(map[string]interface{})
 		if !ok {
 			return fmt.Errorf("expected object, got %T", data)
 		}
-		
+
 		for _, field := range required {
 			fieldName, ok := field.(string)
 			if !ok {
 				continue
 			}
-			
+
 			if _, exists := dataMap[fieldName]; !exists {
 				return fmt.Errorf("required field missing: %s", fieldName)
 			}
 		}
 	}
-	
+
 	return nil
 }
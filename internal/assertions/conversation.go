@@ -0,0 +1,125 @@
+package assertions
+
+import (
+	"fmt"
+	"strings"
+
+	"promptguard/internal/config"
+	"promptguard/internal/providers"
+)
+
+// ConversationEvaluator is implemented by evaluators that judge the whole
+// conversation rather than the final response alone. The conversation holds
+// the messages sent to the provider followed by its response as the last
+// assistant message.
+type ConversationEvaluator interface {
+	EvaluateConversation(assertion config.Assertion, conversation []providers.Message) (AssertionResult, error)
+}
+
+// ConversationContainsEvaluator checks that every text appears in at least
+// one message of the conversation. With Negate set it instead checks that no
+// message contains any of the texts.
+type ConversationContainsEvaluator struct {
+	Negate bool
+}
+
+func (e *ConversationContainsEvaluator) Evaluate(assertion config.Assertion, response *providers.Response) (AssertionResult, error) {
+	return e.EvaluateConversation(assertion, []providers.Message{{Role: "assistant", Content: response.Text}})
+}
+
+func (e *ConversationContainsEvaluator) EvaluateConversation(assertion config.Assertion, conversation []providers.Message) (AssertionResult, error) {
+	assertionType := "conversation-contains"
+	if e.Negate {
+		assertionType = "conversation-not-contains"
+	}
+
+	texts, role, err := parseConversationValue(assertion.Value)
+	if err != nil {
+		return AssertionResult{}, fmt.Errorf("%s: %w", assertionType, err)
+	}
+
+	searched := 0
+	for _, message := range conversation {
+		if role == "any" || message.Role == role {
+			searched++
+		}
+	}
+
+	// Turn numbers count every message, so they match the prompt's sections
+	var problems []string
+	for _, text := range texts {
+		needle := strings.ToLower(text)
+		var turns []string
+		for i, message := range conversation {
+			if role != "any" && message.Role != role {
+				continue
+			}
+			if strings.Contains(strings.ToLower(message.Content), needle) {
+				turns = append(turns, fmt.Sprintf("%d", i+1))
+			}
+		}
+
+		switch {
+		case e.Negate && len(turns) > 0:
+			problems = append(problems, fmt.Sprintf("%q found in turn %s", truncate(text, 40), strings.Join(turns, ", ")))
+		case !e.Negate && len(turns) == 0:
+			problems = append(problems, fmt.Sprintf("%q not found", truncate(text, 40)))
+		}
+	}
+
+	scope := role + " messages"
+	if role == "any" {
+		scope = "messages"
+	}
+
+	message := fmt.Sprintf("Checked %d %s", searched, scope)
+	if len(problems) > 0 {
+		message += ": " + strings.Join(problems, "; ")
+	}
+
+	return AssertionResult{
+		Type:     assertionType,
+		Expected: texts,
+		Actual:   problems,
+		Passed:   len(problems) == 0,
+		Message:  message,
+	}, nil
+}
+
+// parseConversationValue accepts a text, a list of texts, or a map with
+// "text" (a text or list) and "role". The role defaults to "assistant", so
+// that the prompt's own instructions do not count; "any" checks every role.
+func parseConversationValue(value interface{}) ([]string, string, error) {
+	role := "assistant"
+	if options, ok := value.(map[string]interface{}); ok {
+		if r, ok := options["role"]; ok {
+			s, ok := r.(string)
+			if !ok {
+				return nil, "", fmt.Errorf("role must be a string")
+			}
+			role = s
+		}
+		value = options["text"]
+	}
+
+	var texts []string
+	switch v := value.(type) {
+	case string:
+		texts = []string{v}
+	case []interface{}:
+		for _, item := range v {
+			s, ok := item.(string)
+			if !ok {
+				return nil, "", fmt.Errorf("values must be strings")
+			}
+			texts = append(texts, s)
+		}
+	default:
+		return nil, "", fmt.Errorf("value must be a text, a list of texts, or a map with text and role")
+	}
+
+	if len(texts) == 0 {
+		return nil, "", fmt.Errorf("value list is empty")
+	}
+	return texts, role, nil
+}
@@ -0,0 +1,81 @@
+package assertions
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"promptguard/internal/config"
+	"promptguard/internal/providers"
+)
+
+// LatencyP95Evaluator checks the 95th percentile latency of the provider
+// calls made for a test. Use it with the test's repeat setting to judge a
+// latency SLO on several samples rather than one.
+type LatencyP95Evaluator struct{}
+
+// LatencyStats summarizes the latencies of a repeated test, in milliseconds
+type LatencyStats struct {
+	Samples int     `json:"samples"`
+	Min     float64 `json:"minMs"`
+	Median  float64 `json:"medianMs"`
+	P95     float64 `json:"p95Ms"`
+	Max     float64 `json:"maxMs"`
+}
+
+func (e *LatencyP95Evaluator) Evaluate(assertion config.Assertion, response *providers.Response) (AssertionResult, error) {
+	threshold, err := assertion.Duration()
+	if err != nil {
+		return AssertionResult{}, err
+	}
+	// Responses cached before latencies were recorded with them have none;
+	// the assertion warns rather than passing on a cache read's latency
+	if len(response.Latencies) == 0 {
+		return AssertionResult{
+			Type:     "latency-p95",
+			Expected: threshold.String(),
+			Warning:  true,
+			Message:  "Skipped: no latency recorded for the cached responses; run with --no-cache to measure it",
+		}, nil
+	}
+
+	sorted := make([]time.Duration, len(response.Latencies))
+	copy(sorted, response.Latencies)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	min, median, p95, max := sorted[0], percentile(sorted, 0.5), percentile(sorted, 0.95), sorted[len(sorted)-1]
+	stats := LatencyStats{
+		Samples: len(sorted),
+		Min:     milliseconds(min),
+		Median:  milliseconds(median),
+		P95:     milliseconds(p95),
+		Max:     milliseconds(max),
+	}
+
+	return AssertionResult{
+		Type:     "latency-p95",
+		Expected: threshold.String(),
+		Actual:   stats,
+		Passed:   p95 <= threshold,
+		Message: fmt.Sprintf("p95 latency: %v (threshold: %v) over %d runs; min %v, median %v, max %v",
+			roundLatency(p95), threshold, stats.Samples, roundLatency(min), roundLatency(median), roundLatency(max)),
+	}, nil
+}
+
+// percentile returns the nearest-rank percentile of sorted latencies
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	rank := int(math.Ceil(p * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+func roundLatency(d time.Duration) time.Duration {
+	return d.Round(time.Millisecond)
+}
@@ -0,0 +1,106 @@
+package assertions
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"promptguard/internal/config"
+	"promptguard/internal/providers"
+)
+
+// listItemRegex matches markdown bullet ("-", "*", "+") and numbered ("1.",
+// "1)") list items, capturing the indentation and the item text
+var listItemRegex = regexp.MustCompile(`(?m)^([ \t]*)(?:[-*+]|\d+[.)])[ \t]+(.+?)[ \t]*\r?$`)
+
+// ListCountEvaluator checks the number of list items in the response
+type ListCountEvaluator struct{}
+
+func (e *ListCountEvaluator) Evaluate(assertion config.Assertion, response *providers.Response) (AssertionResult, error) {
+	min, max, err := parseListCountRange(assertion.Value)
+	if err != nil {
+		return AssertionResult{}, err
+	}
+
+	items := parseListItems(response.Text)
+	count := len(items)
+	passed := count >= min && (max < 0 || count <= max)
+
+	expected := fmt.Sprintf("at least %d", min)
+	switch {
+	case min == max:
+		expected = fmt.Sprintf("exactly %d", min)
+	case max >= 0:
+		expected = fmt.Sprintf("%d to %d", min, max)
+	}
+
+	previews := make([]string, len(items))
+	for i, item := range items {
+		previews[i] = truncate(item, 40)
+	}
+
+	message := fmt.Sprintf("Found %d list items (expected %s)", count, expected)
+	if count > 0 {
+		message += ": " + strings.Join(previews, "; ")
+	}
+
+	return AssertionResult{
+		Type:     "list-count",
+		Expected: expected,
+		Actual:   items,
+		Passed:   passed,
+		Message:  message,
+	}, nil
+}
+
+// parseListItems returns the top-level list items of a markdown response.
+// Items indented deeper than the shallowest item are treated as nested.
+func parseListItems(text string) []string {
+	matches := listItemRegex.FindAllStringSubmatch(text, -1)
+	if len(matches) == 0 {
+		return nil
+	}
+
+	topIndent := -1
+	for _, match := range matches {
+		if indent := len(match[1]); topIndent < 0 || indent < topIndent {
+			topIndent = indent
+		}
+	}
+
+	var items []string
+	for _, match := range matches {
+		if len(match[1]) == topIndent {
+			items = append(items, match[2])
+		}
+	}
+	return items
+}
+
+// parseListCountRange accepts an exact count or a map with "min" and/or
+// "max". A missing max is returned as -1.
+func parseListCountRange(value interface{}) (int, int, error) {
+	switch v := value.(type) {
+	case int:
+		return v, v, nil
+	case map[string]interface{}:
+		min, max := 0, -1
+		if value, ok := v["min"]; ok {
+			n, ok := value.(int)
+			if !ok {
+				return 0, 0, fmt.Errorf("list-count min must be an integer")
+			}
+			min = n
+		}
+		if value, ok := v["max"]; ok {
+			n, ok := value.(int)
+			if !ok {
+				return 0, 0, fmt.Errorf("list-count max must be an integer")
+			}
+			max = n
+		}
+		return min, max, nil
+	default:
+		return 0, 0, fmt.Errorf("list-count value must be a count or a map with min and/or max")
+	}
+}
@@ -0,0 +1,102 @@
+package assertions
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"promptguard/internal/config"
+	"promptguard/internal/providers"
+)
+
+const (
+	// defaultRepetitionWindow is the number of words compared at a time
+	defaultRepetitionWindow = 5
+	// defaultRepetitionThreshold is the share of repeated windows that fails
+	defaultRepetitionThreshold = 0.3
+)
+
+// NoRepetitionEvaluator fails degenerate responses that repeat the same
+// phrase or sentence over and over. It slides a window of words over the
+// response and measures the share of windows that already occurred earlier.
+type NoRepetitionEvaluator struct{}
+
+func (e *NoRepetitionEvaluator) Evaluate(assertion config.Assertion, response *providers.Response) (AssertionResult, error) {
+	window := defaultRepetitionWindow
+	if assertion.Value != nil {
+		n, ok := assertion.Value.(int)
+		if !ok || n < 2 {
+			return AssertionResult{}, fmt.Errorf("no-repetition value must be a window of at least 2 words")
+		}
+		window = n
+	}
+
+	threshold := assertion.Threshold
+	if threshold == 0 {
+		threshold = defaultRepetitionThreshold
+	}
+
+	ratio, fragment, count := repetition(response.Text, window)
+	passed := ratio <= threshold
+
+	message := fmt.Sprintf("Repetition ratio: %.2f (threshold: %.2f)", ratio, threshold)
+	if count > 1 {
+		message += fmt.Sprintf(", most repeated: %q x%d", truncate(fragment, 60), count)
+	}
+
+	return AssertionResult{
+		Type:     "no-repetition",
+		Expected: threshold,
+		Actual:   ratio,
+		Passed:   passed,
+		Score:    ratio,
+		Message:  message,
+	}, nil
+}
+
+// repetition returns the share of word windows that repeat an earlier window,
+// along with the most repeated window and its number of occurrences
+func repetition(text string, window int) (float64, string, int) {
+	words := strings.Fields(text)
+	if len(words) < window {
+		return 0, "", 0
+	}
+
+	// Compare words without case or surrounding punctuation, so "Yes." and
+	// "yes," count as the same word
+	normalized := make([]string, len(words))
+	for i, word := range words {
+		normalized[i] = strings.ToLower(strings.TrimFunc(word, func(r rune) bool {
+			return unicode.IsPunct(r)
+		}))
+	}
+
+	counts := make(map[string]int)
+	firstSeen := make(map[string]int)
+	total := len(words) - window + 1
+	repeated := 0
+	best, bestCount := "", 0
+
+	for i := 0; i < total; i++ {
+		key := strings.Join(normalized[i:i+window], " ")
+		counts[key]++
+		if counts[key] == 1 {
+			firstSeen[key] = i
+			continue
+		}
+
+		repeated++
+		if counts[key] > bestCount {
+			best, bestCount = key, counts[key]
+		}
+	}
+
+	if bestCount == 0 {
+		return 0, "", 0
+	}
+
+	start := firstSeen[best]
+	fragment := strings.Join(words[start:start+window], " ")
+
+	return float64(repeated) / float64(total), fragment, bestCount
+}
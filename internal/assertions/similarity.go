@@ -0,0 +1,70 @@
+package assertions
+
+import (
+	"context"
+	"fmt"
+
+	"promptguard/internal/config"
+	"promptguard/internal/providers"
+)
+
+// EmbeddingEvaluator is implemented by evaluators that compare texts by
+// embedding. The runner passes its embedder, which embeds each distinct text
+// once per run, so expected values shared by tests are embedded only once.
+type EmbeddingEvaluator interface {
+	EvaluateWithEmbedder(assertion config.Assertion, response *providers.Response, embedder providers.Embedder) (AssertionResult, error)
+}
+
+// SemanticSimilarityEvaluator checks that the response means the same as the
+// expected text, or one of several, by the cosine similarity of their
+// embeddings
+type SemanticSimilarityEvaluator struct{}
+
+func (e *SemanticSimilarityEvaluator) Evaluate(assertion config.Assertion, response *providers.Response) (AssertionResult, error) {
+	return e.EvaluateWithEmbedder(assertion, response, providers.NewOpenAIEmbedder(""))
+}
+
+func (e *SemanticSimilarityEvaluator) EvaluateWithEmbedder(assertion config.Assertion, response *providers.Response, embedder providers.Embedder) (AssertionResult, error) {
+	candidates, err := relevanceCandidates(assertion.Type, assertion.Value)
+	if err != nil {
+		return AssertionResult{}, err
+	}
+
+	embeddings, err := embedder.Embed(context.Background(), append([]string{response.Text}, candidates...))
+	if err != nil {
+		return AssertionResult{}, err
+	}
+
+	// Keep the closest of the acceptable phrasings
+	bestIndex := 0
+	score := -1.0
+	for i := range candidates {
+		similarity := providers.CosineSimilarity(embeddings.Vectors[0], embeddings.Vectors[i+1])
+		if similarity > score {
+			score = similarity
+			bestIndex = i
+		}
+	}
+
+	threshold := assertion.Threshold
+	if threshold == 0 {
+		threshold = 0.8 // Default threshold
+	}
+
+	message := fmt.Sprintf("Semantic similarity: %.2f (threshold: %.2f)", score, threshold)
+	var expected interface{} = candidates[0]
+	if len(candidates) > 1 {
+		expected = candidates
+		message = fmt.Sprintf("Semantic similarity: %.2f (threshold: %.2f, best match: %q)", score, threshold, candidates[bestIndex])
+	}
+
+	return AssertionResult{
+		Type:     "semantic-similarity",
+		Expected: expected,
+		Actual:   response.Text,
+		Passed:   score >= threshold,
+		Score:    score,
+		Message:  message,
+		Cost:     embeddings.Cost,
+	}, nil
+}
@@ -0,0 +1,118 @@
+package assertions
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	"promptguard/internal/config"
+	"promptguard/internal/providers"
+)
+
+var (
+	structsMu sync.RWMutex
+	structs   = make(map[string]reflect.Type)
+)
+
+// RegisterStruct registers the Go type of example under name, so that
+// matches-struct assertions with that value check responses decode into it.
+// example may be a struct value or a pointer to one.
+func RegisterStruct(name string, example interface{}) {
+	t := reflect.TypeOf(example)
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil {
+		panic("assertions: RegisterStruct called with nil example")
+	}
+
+	structsMu.Lock()
+	structs[name] = t
+	structsMu.Unlock()
+}
+
+// registeredStruct returns the type registered under name
+func registeredStruct(name string) (reflect.Type, bool) {
+	structsMu.RLock()
+	defer structsMu.RUnlock()
+	t, ok := structs[name]
+	return t, ok
+}
+
+// registeredStructNames returns the registered names in order, for errors
+func registeredStructNames() []string {
+	structsMu.RLock()
+	defer structsMu.RUnlock()
+	names := make([]string, 0, len(structs))
+	for name := range structs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// MatchesStructEvaluator checks that the response JSON decodes into a
+// registered Go type without errors or unknown fields, the way an
+// application consuming the output would decode it
+type MatchesStructEvaluator struct{}
+
+func (e *MatchesStructEvaluator) Evaluate(assertion config.Assertion, response *providers.Response) (AssertionResult, error) {
+	name, ok := assertion.Value.(string)
+	if !ok || name == "" {
+		return AssertionResult{}, fmt.Errorf("matches-struct value must be the name of a registered struct")
+	}
+
+	target, ok := registeredStruct(name)
+	if !ok {
+		registered := strings.Join(registeredStructNames(), ", ")
+		if registered == "" {
+			registered = "none"
+		}
+		return AssertionResult{}, fmt.Errorf("no struct registered as %q (registered: %s)", name, registered)
+	}
+
+	result := AssertionResult{
+		Type:     "matches-struct",
+		Expected: target.String(),
+	}
+
+	data := responseJSON(response.Text)
+	if data == "" {
+		result.Message = "No JSON found in response"
+		return result, nil
+	}
+	result.Actual = data
+
+	decoder := json.NewDecoder(strings.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(reflect.New(target).Interface()); err != nil {
+		result.Message = fmt.Sprintf("Response does not decode into %s: %v", target, err)
+		return result, nil
+	}
+
+	result.Passed = true
+	result.Message = fmt.Sprintf("Response decodes into %s", target)
+	return result, nil
+}
+
+// responseJSON returns the response as JSON, allowing a surrounding markdown
+// code fence, or else the first JSON object embedded in the text
+func responseJSON(text string) string {
+	trimmed := strings.TrimSpace(text)
+	if strings.HasPrefix(trimmed, "```") {
+		trimmed = strings.TrimPrefix(trimmed, "```json")
+		trimmed = strings.TrimPrefix(trimmed, "```")
+		trimmed = strings.TrimSuffix(trimmed, "```")
+		trimmed = strings.TrimSpace(trimmed)
+	}
+
+	if strings.HasPrefix(trimmed, "{") || strings.HasPrefix(trimmed, "[") {
+		if json.Valid([]byte(trimmed)) {
+			return trimmed
+		}
+	}
+	return extractJSON(text)
+}
@@ -0,0 +1,174 @@
+package assertions
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"promptguard/internal/config"
+	"promptguard/internal/providers"
+)
+
+// ToolCallEvaluator checks that the model called a function by name, and
+// optionally that the call's arguments match a JSON schema. It passes when
+// any call of the response qualifies.
+type ToolCallEvaluator struct{}
+
+func (e *ToolCallEvaluator) Evaluate(assertion config.Assertion, response *providers.Response) (AssertionResult, error) {
+	name, schema := parseToolCallValue(assertion.Value)
+
+	result := AssertionResult{
+		Type:     "tool-call",
+		Expected: assertion.Value,
+		Actual:   response.ToolCalls,
+	}
+
+	if len(response.ToolCalls) == 0 {
+		result.Message = fmt.Sprintf("Expected a call to %s, but the model called no function", name)
+		return result, nil
+	}
+
+	var mismatch string
+	for _, call := range response.ToolCalls {
+		if call.Name != name {
+			continue
+		}
+		if schema == nil {
+			result.Passed = true
+			result.Message = fmt.Sprintf("Called %s", call)
+			return result, nil
+		}
+
+		var arguments interface{}
+		if err := json.Unmarshal([]byte(call.Arguments), &arguments); err != nil {
+			mismatch = fmt.Sprintf("arguments are not valid JSON: %v", err)
+			continue
+		}
+		if err := matchSchema(arguments, schema, "arguments"); err != nil {
+			mismatch = err.Error()
+			continue
+		}
+
+		result.Passed = true
+		result.Message = fmt.Sprintf("Called %s", call)
+		return result, nil
+	}
+
+	if mismatch != "" {
+		result.Message = fmt.Sprintf("Called %s, but %s", name, mismatch)
+		return result, nil
+	}
+
+	called := make([]string, 0, len(response.ToolCalls))
+	for _, call := range response.ToolCalls {
+		called = append(called, call.Name)
+	}
+	result.Message = fmt.Sprintf("Expected a call to %s, got %s", name, strings.Join(called, ", "))
+	return result, nil
+}
+
+// parseToolCallValue returns the function name and arguments schema of a
+// tool-call assertion value; the schema is nil when not given
+func parseToolCallValue(value interface{}) (string, map[string]interface{}) {
+	if name, ok := value.(string); ok {
+		return name, nil
+	}
+	options, _ := value.(map[string]interface{})
+	name, _ := options["name"].(string)
+	schema, _ := options["arguments"].(map[string]interface{})
+	return name, schema
+}
+
+// matchSchema checks a JSON value against the subset of JSON schema used to
+// describe function arguments: type, enum, required, properties, and items.
+// path names the value in the error.
+func matchSchema(value interface{}, schema map[string]interface{}, path string) error {
+	if expected, ok := schema["type"].(string); ok && !hasJSONType(value, expected) {
+		return fmt.Errorf("%s is %s, expected %s", path, jsonType(value), expected)
+	}
+
+	if enum, ok := schema["enum"].([]interface{}); ok {
+		found := false
+		for _, allowed := range enum {
+			if fmt.Sprint(allowed) == fmt.Sprint(value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("%s is %v, expected one of %v", path, value, enum)
+		}
+	}
+
+	if object, ok := value.(map[string]interface{}); ok {
+		if required, ok := schema["required"].([]interface{}); ok {
+			for _, field := range required {
+				if _, exists := object[fmt.Sprint(field)]; !exists {
+					return fmt.Errorf("%s is missing required field %v", path, field)
+				}
+			}
+		}
+
+		properties, _ := schema["properties"].(map[string]interface{})
+		names := make([]string, 0, len(properties))
+		for name := range properties {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			property, ok := properties[name].(map[string]interface{})
+			child, exists := object[name]
+			if !ok || !exists {
+				continue
+			}
+			if err := matchSchema(child, property, path+"."+name); err != nil {
+				return err
+			}
+		}
+	}
+
+	if list, ok := value.([]interface{}); ok {
+		if items, ok := schema["items"].(map[string]interface{}); ok {
+			for i, item := range list {
+				if err := matchSchema(item, items, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// hasJSONType reports whether a decoded JSON value has the schema type
+func hasJSONType(value interface{}, expected string) bool {
+	actual := jsonType(value)
+	if expected == "number" && actual == "integer" {
+		return true
+	}
+	return actual == expected
+}
+
+// jsonType returns the JSON schema type of a decoded JSON value
+func jsonType(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		if v == float64(int64(v)) {
+			return "integer"
+		}
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
@@ -0,0 +1,54 @@
+package assertions
+
+import (
+	"testing"
+
+	"promptguard/internal/config"
+	"promptguard/internal/providers"
+)
+
+func TestToxicityLexiconWeights(t *testing.T) {
+	tests := []struct {
+		name   string
+		text   string
+		passed bool
+	}{
+		{"neutral mention", "This movie depicts violence and hate.", true},
+		{"one mild insult", "That was a stupid mistake on my part.", true},
+		{"casual profanity", "Damn, the build broke again.", true},
+		{"two mild matches", "Shut up, that idea is stupid.", true},
+		{"three mild matches", "Shut up, loser, that idea is stupid.", false},
+		{"one strong insult", "You are an idiot.", false},
+		{"threat", "I will find you.", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := (&ToxicityEvaluator{}).Evaluate(config.Assertion{Type: "toxicity"}, &providers.Response{Text: tt.text})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.Passed != tt.passed {
+				t.Errorf("Passed = %v, want %v (%s)", result.Passed, tt.passed, result.Message)
+			}
+		})
+	}
+}
+
+func TestToxicityCustomKeywords(t *testing.T) {
+	assertion := config.Assertion{
+		Type:  "toxicity",
+		Value: map[string]interface{}{"keywords": map[string]interface{}{"spam": []interface{}{"buy now"}}},
+	}
+
+	result, err := (&ToxicityEvaluator{}).Evaluate(assertion, &providers.Response{Text: "Buy now while stocks last!"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Passed {
+		t.Errorf("Passed = true, want a custom keyword match to fail (%s)", result.Message)
+	}
+	if scores := result.Actual.(map[string]float64); scores["spam"] != 0.5 {
+		t.Errorf("spam score = %v, want 0.5", scores["spam"])
+	}
+}
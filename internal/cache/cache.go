@@ -0,0 +1,124 @@
+// Package cache stores provider responses on disk so that re-running a
+// suite with unchanged prompts does not pay for the same completions again.
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"promptguard/internal/config"
+	"promptguard/internal/providers"
+)
+
+// DefaultDir is where cached responses are kept
+const DefaultDir = ".promptguard/cache"
+
+// DefaultTTL is how long a cached response is reused when settings.cacheTTL
+// is unset
+const DefaultTTL = 24 * time.Hour
+
+// Cache is an on-disk response cache with one JSON file per entry
+type Cache struct {
+	dir string
+	ttl time.Duration
+}
+
+// entry is the file stored for a cached response
+type entry struct {
+	Created  time.Time          `json:"created"`
+	Response providers.Response `json:"response"`
+}
+
+// New creates a cache in dir whose entries expire after ttl. An empty dir
+// uses DefaultDir and a ttl of 0 uses DefaultTTL.
+func New(dir string, ttl time.Duration) *Cache {
+	if dir == "" {
+		dir = DefaultDir
+	}
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	}
+	return &Cache{dir: dir, ttl: ttl}
+}
+
+// FromSettings creates the cache configured by settings.cacheResults and
+// settings.cacheTTL, or returns nil when caching is off. The TTL has been
+// validated with the config.
+func FromSettings(settings config.Settings) *Cache {
+	if !settings.CacheResults {
+		return nil
+	}
+	ttl, _ := time.ParseDuration(settings.CacheTTL)
+	return New("", ttl)
+}
+
+// Key identifies a completion by the provider ID, its generation settings,
+// the rendered messages, and the sample number for repeated tests. The API
+// key is left out, so rotating it keeps the cache.
+func Key(provider *config.Provider, messages []providers.Message, sample int) string {
+	data, _ := json.Marshal(struct {
+		Provider string                 `json:"provider"`
+		Config   map[string]interface{} `json:"config"`
+		Messages []providers.Message    `json:"messages"`
+		Sample   int                    `json:"sample"`
+	}{provider.ID, provider.RedactedConfig(), messages, sample})
+
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// Get returns the cached response for key, if there is one that has not
+// expired. Unreadable entries count as misses.
+func (c *Cache) Get(key string) (*providers.Response, bool) {
+	data, err := os.ReadFile(c.path(key))
+	if err != nil {
+		return nil, false
+	}
+
+	var cached entry
+	if err := json.Unmarshal(data, &cached); err != nil {
+		return nil, false
+	}
+	if time.Since(cached.Created) > c.ttl {
+		return nil, false
+	}
+	return &cached.Response, true
+}
+
+// Put stores a response under key. The file is written under a temporary
+// name and renamed, so that parallel tests never read a partial entry.
+func (c *Cache) Put(key string, response *providers.Response) error {
+	if err := os.MkdirAll(c.dir, 0755); err != nil {
+		return fmt.Errorf("failed to create cache directory: %w", err)
+	}
+
+	data, err := json.Marshal(entry{Created: time.Now(), Response: *response})
+	if err != nil {
+		return fmt.Errorf("failed to marshal cached response: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(c.dir, key+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to write cache entry: %w", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write cache entry: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write cache entry: %w", err)
+	}
+
+	return os.Rename(tmp.Name(), c.path(key))
+}
+
+func (c *Cache) path(key string) string {
+	return filepath.Join(c.dir, key+".json")
+}
@@ -0,0 +1,185 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// composer merges a config file with the configs it extends and includes,
+// remembering the file each YAML node came from so that errors can point
+// into the right file
+type composer struct {
+	sources map[*yaml.Node]string
+	// merged holds the absolute paths of the files loaded so far, so that a
+	// file included along several paths is merged only once
+	merged map[string]bool
+}
+
+// load reads a config file and merges the configs it extends and includes
+// beneath it, returning the merged YAML. Parents are merged in order,
+// extends first and then each include, and the file itself last: mappings
+// such as settings merge key by key, lists such as tests are appended to,
+// and scalars are overridden. A file reached again through another path,
+// such as a base included by two includes, is merged only where it was first
+// reached. chain is the files being loaded, to detect cycles.
+func (c *composer) load(filename string, chain []string) (*yaml.Node, error) {
+	abs, err := filepath.Abs(filename)
+	if err != nil {
+		return nil, err
+	}
+	for i, file := range chain {
+		if file == abs {
+			cycle := append(append([]string{}, chain[i:]...), abs)
+			return nil, fmt.Errorf("config include cycle: %s", strings.Join(cycle, " -> "))
+		}
+	}
+	if c.merged[abs] {
+		return &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}, nil
+	}
+	c.merged[abs] = true
+	chain = append(chain, abs)
+
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file %s: %w", filename, err)
+	}
+
+	if strings.EqualFold(filepath.Ext(filename), ".json") {
+		if err := checkJSON(data); err != nil {
+			return nil, fmt.Errorf("config file %s: failed to parse JSON config: %w", filename, err)
+		}
+	}
+
+	// JSON is YAML too, so both are decoded through the same nodes and keep
+	// their line numbers for validation errors
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("config file %s: failed to parse config: %w", filename, err)
+	}
+	root := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	if len(doc.Content) > 0 {
+		root = doc.Content[0]
+	}
+	if root.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("config file %s: the config must be a mapping", filename)
+	}
+
+	// Only files are expanded; configs posted to pg serve must not read
+	// the server's environment
+	if err := expandEnv(root); err != nil {
+		return nil, fmt.Errorf("config file %s: %w", filename, err)
+	}
+	c.record(root, filename)
+
+	parents, err := takeParents(root)
+	if err != nil {
+		return nil, fmt.Errorf("config file %s: %w", filename, err)
+	}
+
+	merged := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	for _, parent := range parents {
+		if !filepath.IsAbs(parent) {
+			parent = filepath.Join(filepath.Dir(filename), parent)
+		}
+		node, err := c.load(parent, chain)
+		if err != nil {
+			return nil, err
+		}
+		merged = c.merge(merged, node)
+	}
+
+	return c.merge(merged, root), nil
+}
+
+// record notes filename as the source of node and everything beneath it
+func (c *composer) record(node *yaml.Node, filename string) {
+	c.sources[node] = filename
+	for _, child := range node.Content {
+		c.record(child, filename)
+	}
+}
+
+// checkJSON reports a JSON syntax error with its line, since JSON config
+// files are held to JSON rather than the looser YAML syntax
+func checkJSON(data []byte) error {
+	var v interface{}
+	err := json.Unmarshal(data, &v)
+	var syntaxErr *json.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		line := bytes.Count(data[:syntaxErr.Offset], []byte("\n")) + 1
+		return fmt.Errorf("line %d: %w", line, err)
+	}
+	return err
+}
+
+// takeParents removes the extends and include keys from a config mapping
+// and returns the files they name, extends first
+func takeParents(root *yaml.Node) ([]string, error) {
+	var extends string
+	var include []string
+	content := make([]*yaml.Node, 0, len(root.Content))
+
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		key, value := root.Content[i], root.Content[i+1]
+		switch key.Value {
+		case "extends":
+			if err := value.Decode(&extends); err != nil {
+				return nil, fmt.Errorf("extends must be a file path")
+			}
+		case "include":
+			if err := value.Decode(&include); err != nil {
+				return nil, fmt.Errorf("include must be a list of file paths")
+			}
+		default:
+			content = append(content, key, value)
+		}
+	}
+	root.Content = content
+
+	var parents []string
+	if extends != "" {
+		parents = append(parents, extends)
+	}
+	return append(parents, include...), nil
+}
+
+// merge merges override into base: mappings key by key, sequences by
+// appending, and anything else by taking override. Merged mappings and
+// sequences take the position of override.
+func (c *composer) merge(base, override *yaml.Node) *yaml.Node {
+	var merged *yaml.Node
+	switch {
+	case base.Kind == yaml.MappingNode && override.Kind == yaml.MappingNode:
+		merged = &yaml.Node{Kind: yaml.MappingNode, Tag: base.Tag, Line: override.Line, Column: override.Column}
+		merged.Content = append(merged.Content, base.Content...)
+		for i := 0; i+1 < len(override.Content); i += 2 {
+			key, value := override.Content[i], override.Content[i+1]
+			found := false
+			for j := 0; j+1 < len(merged.Content); j += 2 {
+				if merged.Content[j].Value == key.Value {
+					merged.Content[j+1] = c.merge(merged.Content[j+1], value)
+					found = true
+					break
+				}
+			}
+			if !found {
+				merged.Content = append(merged.Content, key, value)
+			}
+		}
+	case base.Kind == yaml.SequenceNode && override.Kind == yaml.SequenceNode:
+		merged = &yaml.Node{Kind: yaml.SequenceNode, Tag: base.Tag, Line: override.Line, Column: override.Column}
+		merged.Content = append(append(merged.Content, base.Content...), override.Content...)
+	default:
+		return override
+	}
+
+	c.sources[merged] = c.sources[override]
+	return merged
+}
@@ -0,0 +1,58 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeConfigFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+// TestDiamondIncludeMergedOnce includes two files that both extend the same
+// base, whose providers and tests must appear once
+func TestDiamondIncludeMergedOnce(t *testing.T) {
+	dir := writeConfigFiles(t, map[string]string{
+		"promptguard.yaml": "include: [b.yaml, c.yaml]\n",
+		"b.yaml":           "extends: base.yaml\ntests:\n  - name: from b\n    assert:\n      - type: cost\n        threshold: 0.01\n",
+		"c.yaml":           "extends: base.yaml\ntests:\n  - name: from c\n    assert:\n      - type: cost\n        threshold: 0.01\n",
+		"base.yaml":        "inlinePrompts:\n  greet: Hello\nproviders:\n  - id: mock:echo\ntests:\n  - name: from base\n    assert:\n      - type: cost\n        threshold: 0.01\n",
+	})
+
+	config, err := LoadFromFile(filepath.Join(dir, "promptguard.yaml"))
+	if err != nil {
+		t.Fatalf("LoadFromFile() error = %v", err)
+	}
+
+	if len(config.Providers) != 1 {
+		t.Errorf("got %d providers, want 1", len(config.Providers))
+	}
+	var names []string
+	for _, test := range config.Tests {
+		names = append(names, test.Name)
+	}
+	if got := strings.Join(names, ", "); got != "from base, from b, from c" {
+		t.Errorf("tests = %s, want from base, from b, from c", got)
+	}
+}
+
+func TestIncludeCycle(t *testing.T) {
+	dir := writeConfigFiles(t, map[string]string{
+		"promptguard.yaml": "include: [a.yaml]\n",
+		"a.yaml":           "include: [promptguard.yaml]\n",
+	})
+
+	_, err := LoadFromFile(filepath.Join(dir, "promptguard.yaml"))
+	if err == nil || !strings.Contains(err.Error(), "config include cycle") {
+		t.Fatalf("LoadFromFile() error = %v, want an include cycle", err)
+	}
+}
@@ -4,17 +4,37 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
 
 // Config represents the main configuration structure
 type Config struct {
-	Description string     `yaml:"description"`
-	Prompts     []string   `yaml:"prompts"`
-	Providers   []Provider `yaml:"providers"`
-	Tests       []Test     `yaml:"tests"`
-	Settings    Settings   `yaml:"settings,omitempty"`
+	Description string   `yaml:"description"`
+	Prompts     []string `yaml:"prompts"`
+	// InlinePrompts holds prompt content by name, for prompts too small to
+	// be worth a file; tests run against them like prompt files
+	InlinePrompts map[string]string       `yaml:"inlinePrompts,omitempty"`
+	Providers     []Provider              `yaml:"providers"`
+	Tests         []Test                  `yaml:"tests"`
+	Settings      Settings                `yaml:"settings,omitempty"`
+	Pricing       map[string]ModelPricing `yaml:"pricing,omitempty"`
+	Canary        *Canary                 `yaml:"canary,omitempty"`
+	Defaults      Defaults                `yaml:"defaults,omitempty"`
+	// Extends and Include name the parent configs of a config file, which
+	// LoadFromFile merges beneath it; they are gone once it is loaded
+	Extends string   `yaml:"extends,omitempty"`
+	Include []string `yaml:"include,omitempty"`
+}
+
+// Canary routes a share of the test runs that use the default provider to a
+// candidate provider, so a model migration can be validated gradually
+type Canary struct {
+	Provider string  `yaml:"provider"`
+	Weight   float64 `yaml:"weight"` // Share of test runs, between 0 and 1
 }
 
 // Provider represents an LLM provider configuration
@@ -23,13 +43,83 @@ type Provider struct {
 	Config map[string]interface{} `yaml:"config,omitempty"`
 }
 
+// sensitiveHeaderWords mark header names whose values are credentials, such
+// as Authorization, X-Api-Key, or X-Gateway-Token
+var sensitiveHeaderWords = []string{"auth", "key", "token", "secret", "cookie", "password"}
+
+// RedactedConfig returns a copy of the provider settings with the api_key
+// value and credential headers hidden, for settings that are written to
+// files or hashed
+func (p *Provider) RedactedConfig() map[string]interface{} {
+	headers, _ := p.Config["headers"].(map[string]interface{})
+	redactedHeaders := redactHeaders(headers)
+	if _, ok := p.Config["api_key"]; !ok && redactedHeaders == nil {
+		return p.Config
+	}
+
+	redacted := make(map[string]interface{}, len(p.Config))
+	for key, value := range p.Config {
+		redacted[key] = value
+	}
+	if _, ok := p.Config["api_key"]; ok {
+		redacted["api_key"] = "[redacted]"
+	}
+	if redactedHeaders != nil {
+		redacted["headers"] = redactedHeaders
+	}
+	return redacted
+}
+
+// redactHeaders returns a copy of headers with credential values hidden, or
+// nil when there are none
+func redactHeaders(headers map[string]interface{}) map[string]interface{} {
+	var redacted map[string]interface{}
+	for name := range headers {
+		if !isSensitiveHeader(name) {
+			continue
+		}
+		if redacted == nil {
+			redacted = make(map[string]interface{}, len(headers))
+			for key, value := range headers {
+				redacted[key] = value
+			}
+		}
+		redacted[name] = "[redacted]"
+	}
+	return redacted
+}
+
+func isSensitiveHeader(name string) bool {
+	lower := strings.ToLower(name)
+	for _, word := range sensitiveHeaderWords {
+		if strings.Contains(lower, word) {
+			return true
+		}
+	}
+	return false
+}
+
 // Test represents a test case configuration
 type Test struct {
-	Name        string                 `yaml:"name,omitempty"`
-	Description string                 `yaml:"description,omitempty"`
-	Variables   map[string]interface{} `yaml:"vars"`
-	Assert      []Assertion            `yaml:"assert"`
-	Provider    string                 `yaml:"provider,omitempty"`
+	Name         string                   `yaml:"name,omitempty"`
+	Description  string                   `yaml:"description,omitempty"`
+	Variables    map[string]interface{}   `yaml:"vars"`
+	Matrix       map[string][]interface{} `yaml:"matrix,omitempty"` // Values to run the test with, one run per combination
+	Assert       []Assertion              `yaml:"assert"`
+	Conversation []Turn                   `yaml:"conversation,omitempty"` // Follow-up user turns; assert then judges the last response
+	Tools        []Tool                   `yaml:"tools,omitempty"`        // Functions offered to the model, added to the provider's
+	Provider     string                   `yaml:"provider,omitempty"`
+	Repeat       int                      `yaml:"repeat,omitempty"` // Times to run the prompt, e.g. for latency-p95
+	// PassThreshold passes the test when the weighted share of its
+	// assertions that passed reaches it, from 0 to 1; 0 requires all to pass
+	PassThreshold float64 `yaml:"passThreshold,omitempty"`
+}
+
+// Turn is a follow-up user message of a multi-turn test. It is sent after
+// the previous response, and its assertions judge the response to it.
+type Turn struct {
+	User   string      `yaml:"user"`
+	Assert []Assertion `yaml:"assert,omitempty"`
 }
 
 // Assertion represents a test assertion
@@ -37,7 +127,9 @@ type Assertion struct {
 	Type      string      `yaml:"type"`
 	Value     interface{} `yaml:"value,omitempty"`
 	Threshold float64     `yaml:"threshold,omitempty"`
-	Required  bool        `yaml:"required,omitempty"`
+	Required  *bool       `yaml:"required,omitempty"` // false reports a failure as a warning; default true
+	Weight    float64     `yaml:"weight,omitempty"`   // Share of the test's passThreshold score; default 1
+	Message   string      `yaml:"message,omitempty"`
 }
 
 // Settings represents global settings
@@ -46,61 +138,201 @@ type Settings struct {
 	Timeout      int     `yaml:"timeout,omitempty"`
 	MaxRetries   int     `yaml:"maxRetries,omitempty"`
 	CacheResults bool    `yaml:"cacheResults,omitempty"`
+	// CacheTTL is how long cached responses are reused, as a duration such
+	// as "24h"; empty uses the default of 24 hours
+	CacheTTL string `yaml:"cacheTTL,omitempty"`
+	// AllowMissingVariables renders undefined prompt variables as "<no value>"
+	// instead of failing the test
+	AllowMissingVariables bool `yaml:"allowMissingVariables,omitempty"`
+	// ConfirmCost is the estimated run cost in USD above which `pg test`
+	// asks for confirmation; 0 uses the default of $1, negative disables it
+	ConfirmCost float64 `yaml:"confirmCost,omitempty"`
+	// MetricsMaxRuns caps the runs kept in the metrics database; older runs
+	// are pruned after each run. 0 keeps every run.
+	MetricsMaxRuns int `yaml:"metricsMaxRuns,omitempty"`
+	// MetricsPath is the metrics database file, ".promptguard/metrics.db" by
+	// default; ":memory:" keeps metrics in memory only
+	MetricsPath string `yaml:"metricsPath,omitempty"`
+	// UserAgent replaces the default "promptguard/<version>" User-Agent of
+	// provider requests
+	UserAgent string `yaml:"userAgent,omitempty"`
+	// Headers are sent with every provider request; a provider's own
+	// headers setting overrides them
+	Headers map[string]string `yaml:"headers,omitempty"`
+	// ReportTemplate is an html/template file replacing the built-in HTML
+	// report template
+	ReportTemplate string `yaml:"reportTemplate,omitempty"`
+	// MaxResponseChars caps the response text shown in HTML, markdown, and
+	// JUnit reports and the viewer; 0 uses the default of 2000, negative
+	// shows responses in full. JSON results always keep the full text.
+	MaxResponseChars int `yaml:"maxResponseChars,omitempty"`
+	// MaxCostIncrease is the cost increase over the baseline, in percent,
+	// tolerated before `pg ci` reports a regression; 0 uses the default of
+	// 10%, negative ignores cost
+	MaxCostIncrease float64 `yaml:"maxCostIncrease,omitempty"`
+	// MaxNewFailures is the number of tests that may newly fail against the
+	// baseline before `pg ci` reports a regression
+	MaxNewFailures int `yaml:"maxNewFailures,omitempty"`
+	// EmbeddingModel is the OpenAI model that embeds texts for assertions
+	// such as semantic-similarity; empty uses text-embedding-3-small
+	EmbeddingModel string `yaml:"embeddingModel,omitempty"`
+}
+
+// Load loads configuration from promptguard.yaml, or from the file for env
+// when one exists (see Find)
+func Load(env string) (*Config, error) {
+	configFile, err := Find(env)
+	if err != nil {
+		return nil, err
+	}
+
+	return LoadFromFile(configFile)
 }
 
-// Load loads configuration from promptguard.yaml
-func Load() (*Config, error) {
+// Find returns the path of the configuration file in the current directory.
+// When env is set, environment-specific files such as promptguard.prod.yaml
+// are tried first, falling back to the base configuration files. JSON files
+// are found after the YAML ones.
+func Find(env string) (string, error) {
 	configPaths := []string{
 		"promptguard.yaml",
 		"promptguard.yml",
+		"promptguard.json",
 		".promptguard/config.yaml",
 		".promptguard/config.yml",
+		".promptguard/config.json",
+	}
+
+	if env != "" {
+		envPaths := make([]string, 0, len(configPaths))
+		for _, path := range configPaths {
+			ext := filepath.Ext(path)
+			envPaths = append(envPaths, strings.TrimSuffix(path, ext)+"."+env+ext)
+		}
+		configPaths = append(envPaths, configPaths...)
 	}
 
-	var configFile string
 	for _, path := range configPaths {
 		if _, err := os.Stat(path); err == nil {
-			configFile = path
-			break
+			return path, nil
 		}
 	}
 
-	if configFile == "" {
-		return nil, fmt.Errorf("no configuration file found. Create promptguard.yaml in your project root")
+	return "", fmt.Errorf("no configuration file found. Create promptguard.yaml in your project root")
+}
+
+// LoadFromFile loads configuration from a specific file, merged with the
+// configs it extends and includes. Validation errors start with the file,
+// line, and column of the offending value, e.g. promptguard.yaml:14:9.
+func LoadFromFile(filename string) (*Config, error) {
+	c := &composer{sources: make(map[*yaml.Node]string), merged: make(map[string]bool)}
+	root, err := c.load(filename, nil)
+	if err != nil {
+		return nil, err
 	}
 
-	return LoadFromFile(configFile)
+	config, err := parseNode(root, nil)
+	if err != nil {
+		if node := locate(root, err); node != nil {
+			file := c.sources[node]
+			if file == "" {
+				file = filename
+			}
+			return nil, fmt.Errorf("%s: %w", position(file, node), err)
+		}
+		return nil, fmt.Errorf("config file %s: %w", filename, err)
+	}
+
+	return config, nil
 }
 
-// LoadFromFile loads configuration from a specific file
-func LoadFromFile(filename string) (*Config, error) {
-	data, err := os.ReadFile(filename)
+// Parse parses, validates, and resolves configuration from raw YAML
+func Parse(data []byte) (*Config, error) {
+	return parse(data, nil)
+}
+
+// parse parses configuration from raw YAML, restricted by served when it is
+// posted to pg serve
+func parse(data []byte, served *ServeOptions) (*Config, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse config: %w", err)
+	}
+	root := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	if len(doc.Content) > 0 {
+		root = doc.Content[0]
+	}
+
+	config, err := parseNode(root, served)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read config file %s: %w", filename, err)
+		if node := locate(root, err); node != nil {
+			return nil, fmt.Errorf("%s: %w", position("", node), err)
+		}
+		return nil, err
 	}
 
+	return config, nil
+}
+
+// parseNode decodes, validates, and resolves configuration from a YAML
+// mapping. served is nil unless the config was posted to pg serve.
+func parseNode(root *yaml.Node, served *ServeOptions) (*Config, error) {
 	var config Config
-	if err := yaml.Unmarshal(data, &config); err != nil {
-		return nil, fmt.Errorf("failed to parse config file %s: %w", filename, err)
+	if err := root.Decode(&config); err != nil {
+		return nil, fmt.Errorf("failed to parse config: %w", err)
 	}
 
+	// Fill the providers and assertions from the defaults block
+	config.applyDefaults()
+
 	// Validate configuration
 	if err := config.Validate(); err != nil {
 		return nil, fmt.Errorf("invalid configuration: %w", err)
 	}
 
+	// Keep posted configs off the server's files and credentials
+	if served != nil {
+		if err := config.checkServed(served); err != nil {
+			return nil, fmt.Errorf("invalid configuration: %w", err)
+		}
+		config.injectCredentials(served)
+	}
+
 	// Expand prompt file paths
 	if err := config.expandPromptPaths(); err != nil {
 		return nil, fmt.Errorf("failed to expand prompt paths: %w", err)
 	}
 
+	// Fill variables that reference data files
+	if err := config.resolveVariableSources(); err != nil {
+		return nil, fmt.Errorf("failed to resolve variables: %w", err)
+	}
+
 	return &config, nil
 }
 
 // Validate validates the configuration
 func (c *Config) Validate() error {
-	if len(c.Prompts) == 0 {
-		return fmt.Errorf("no prompt files specified")
+	if c.Extends != "" || len(c.Include) > 0 {
+		return fmt.Errorf("extends and include are only supported in config files")
+	}
+
+	if len(c.Prompts) == 0 && len(c.InlinePrompts) == 0 {
+		return fmt.Errorf("no prompt files or inline prompts specified")
+	}
+
+	for _, name := range c.InlinePromptNames() {
+		if strings.TrimSpace(name) == "" {
+			return atField(fmt.Errorf("inline prompts must have a name"), "inlinePrompts")
+		}
+		if strings.TrimSpace(c.InlinePrompts[name]) == "" {
+			return atField(fmt.Errorf("inline prompt %q has no content", name), "inlinePrompts", name)
+		}
+		for _, path := range c.Prompts {
+			if path == name {
+				return atField(fmt.Errorf("inline prompt %q has the same name as a prompt file", name), "inlinePrompts", name)
+			}
+		}
 	}
 
 	if len(c.Providers) == 0 {
@@ -111,27 +343,168 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("no tests specified")
 	}
 
-	// Validate provider IDs
+	if err := c.validateDefaults(); err != nil {
+		return err
+	}
+
+	if err := ValidatePricing(c.Pricing); err != nil {
+		return atField(fmt.Errorf("invalid pricing: %w", err), "pricing")
+	}
+
+	if c.Settings.MaxNewFailures < 0 {
+		return atField(fmt.Errorf("settings.maxNewFailures must not be negative"), "settings", "maxNewFailures")
+	}
+
+	if c.Settings.MetricsMaxRuns < 0 {
+		return atField(fmt.Errorf("settings.metricsMaxRuns must not be negative"), "settings", "metricsMaxRuns")
+	}
+
+	if c.Settings.CacheTTL != "" {
+		if ttl, err := time.ParseDuration(c.Settings.CacheTTL); err != nil || ttl <= 0 {
+			return atField(fmt.Errorf("settings.cacheTTL must be a positive duration such as \"24h\", got %q", c.Settings.CacheTTL), "settings", "cacheTTL")
+		}
+	}
+
+	// Validate providers
 	providerIDs := make(map[string]bool)
-	for _, provider := range c.Providers {
-		if provider.ID == "" {
-			return fmt.Errorf("provider missing ID")
+	for i, provider := range c.Providers {
+		if err := validateProvider(provider, providerIDs); err != nil {
+			return atField(err, "providers", i)
 		}
-		if providerIDs[provider.ID] {
-			return fmt.Errorf("duplicate provider ID: %s", provider.ID)
+	}
+
+	if c.Canary != nil {
+		if err := c.validateCanary(providerIDs); err != nil {
+			return atField(err, "canary")
 		}
-		providerIDs[provider.ID] = true
 	}
 
-	// Validate test assertions
+	// Validate tests
 	for i, test := range c.Tests {
-		if len(test.Assert) == 0 {
-			return fmt.Errorf("test %d has no assertions", i)
+		if err := test.validate(i); err != nil {
+			return err
 		}
+	}
+
+	return nil
+}
 
-		for j, assertion := range test.Assert {
+// validateProvider validates a provider, recording its ID in providerIDs to
+// catch duplicates
+func validateProvider(provider Provider, providerIDs map[string]bool) error {
+	if provider.ID == "" {
+		return fmt.Errorf("provider missing ID")
+	}
+	if providerIDs[provider.ID] {
+		return fmt.Errorf("duplicate provider ID: %s", provider.ID)
+	}
+	providerIDs[provider.ID] = true
+
+	for _, key := range []string{"api_key", "api_key_env", "org_id"} {
+		if value, ok := provider.Config[key]; ok {
+			if s, ok := value.(string); !ok || s == "" {
+				return fmt.Errorf("provider %s: %s must be a non-empty string", provider.ID, key)
+			}
+		}
+	}
+
+	if err := validateGenerationSettings(provider.Config); err != nil {
+		return fmt.Errorf("provider %s: %w", provider.ID, err)
+	}
+
+	if pricing, ok := provider.Config["pricing"]; ok {
+		if err := validateProviderPricing(pricing); err != nil {
+			return fmt.Errorf("provider %s: %w", provider.ID, err)
+		}
+	}
+
+	if tools, ok := provider.Config["tools"]; ok {
+		if err := validateProviderTools(tools); err != nil {
+			return fmt.Errorf("provider %s: %w", provider.ID, err)
+		}
+	}
+	if choice, ok := provider.Config["tool_choice"]; ok {
+		if s, ok := choice.(string); !ok || s == "" {
+			return fmt.Errorf("provider %s: tool_choice must be auto, none, required, or a function name", provider.ID)
+		}
+	}
+
+	if headers, ok := provider.Config["headers"]; ok {
+		if _, ok := headers.(map[string]interface{}); !ok {
+			return fmt.Errorf("provider %s: headers must be a map of header names to values", provider.ID)
+		}
+	}
+
+	if urls, ok := provider.Config["base_urls"]; ok {
+		list, ok := urls.([]interface{})
+		if !ok || len(list) == 0 {
+			return fmt.Errorf("provider %s: base_urls must be a non-empty list", provider.ID)
+		}
+		for _, url := range list {
+			if s, ok := url.(string); !ok || s == "" {
+				return fmt.Errorf("provider %s: base_urls must contain only URLs", provider.ID)
+			}
+		}
+	}
+
+	return nil
+}
+
+// validateCanary checks the canary against the configured providers
+func (c *Config) validateCanary(providerIDs map[string]bool) error {
+	if !providerIDs[c.Canary.Provider] {
+		return fmt.Errorf("canary provider %q is not a configured provider", c.Canary.Provider)
+	}
+	if c.Canary.Provider == c.Providers[0].ID {
+		return fmt.Errorf("canary provider must differ from the default provider %s", c.Providers[0].ID)
+	}
+	if c.Canary.Weight <= 0 || c.Canary.Weight > 1 {
+		return fmt.Errorf("canary weight must be greater than 0 and at most 1")
+	}
+	return nil
+}
+
+// validate validates the i-th test of the config, attributing errors to the
+// test or the assertion, tool, or turn at fault
+func (t *Test) validate(i int) error {
+	if len(t.AllAssertions()) == 0 {
+		return atField(fmt.Errorf("test %d has no assertions", i), "tests", i)
+	}
+	if len(t.Conversation) > 0 && t.Repeat > 1 {
+		return atField(fmt.Errorf("test %d cannot combine repeat with a conversation", i), "tests", i, "repeat")
+	}
+	if t.Repeat < 0 {
+		return atField(fmt.Errorf("test %d repeat must not be negative", i), "tests", i, "repeat")
+	}
+	if t.PassThreshold < 0 || t.PassThreshold > 1 {
+		return atField(fmt.Errorf("test %d passThreshold must be between 0 and 1", i), "tests", i, "passThreshold")
+	}
+	for name, values := range t.Matrix {
+		if len(values) == 0 {
+			return atField(fmt.Errorf("test %d matrix variable %s has no values", i, name), "tests", i, "matrix", name)
+		}
+		if _, ok := t.Variables[name]; ok {
+			return atField(fmt.Errorf("test %d sets %s in both vars and matrix", i, name), "tests", i, "matrix", name)
+		}
+	}
+
+	for j, assertion := range t.Assert {
+		if err := assertion.Validate(); err != nil {
+			return atField(fmt.Errorf("test %d, assertion %d: %w", i, j, err), "tests", i, "assert", j)
+		}
+	}
+	for j, tool := range t.Tools {
+		if err := tool.Validate(); err != nil {
+			return atField(fmt.Errorf("test %d, tool %d: %w", i, j, err), "tests", i, "tools", j)
+		}
+	}
+	for j, turn := range t.Conversation {
+		if strings.TrimSpace(turn.User) == "" {
+			return atField(fmt.Errorf("test %d, conversation turn %d has no user message", i, j), "tests", i, "conversation", j)
+		}
+		for k, assertion := range turn.Assert {
 			if err := assertion.Validate(); err != nil {
-				return fmt.Errorf("test %d, assertion %d: %w", i, j, err)
+				return atField(fmt.Errorf("test %d, conversation turn %d, assertion %d: %w", i, j, k, err), "tests", i, "conversation", j, "assert", k)
 			}
 		}
 	}
@@ -139,21 +512,48 @@ func (c *Config) Validate() error {
 	return nil
 }
 
-// Validate validates an assertion
-func (a *Assertion) Validate() error {
-	validTypes := map[string]bool{
-		"answer-relevance": true,
-		"contains-json":    true,
-		"cost":            true,
-		"llm-rubric":      true,
-		"closed-qa":       true,
-		"toxicity":        true,
-		"jailbreak":       true,
+// AllAssertions returns the test's assertions followed by those of its
+// conversation turns
+func (t *Test) AllAssertions() []Assertion {
+	all := append([]Assertion{}, t.Assert...)
+	for _, turn := range t.Conversation {
+		all = append(all, turn.Assert...)
 	}
+	return all
+}
+
+// assertionTypes lists the assertion types the runner evaluates
+var assertionTypes = map[string]bool{
+	"answer-relevance":          true,
+	"contains-json":             true,
+	"cost":                      true,
+	"llm-rubric":                true,
+	"closed-qa":                 true,
+	"toxicity":                  true,
+	"jailbreak":                 true,
+	"pii":                       true,
+	"matches-examples":          true,
+	"semantic-similarity":       true,
+	"min-confidence":            true,
+	"list-count":                true,
+	"latency-p95":               true,
+	"no-repetition":             true,
+	"expect-filtered":           true,
+	"matches-struct":            true,
+	"no-prompt-leak":            true,
+	"conversation-contains":     true,
+	"conversation-not-contains": true,
+	"tool-call":                 true,
+}
 
-	if !validTypes[a.Type] {
+// Validate validates an assertion
+func (a *Assertion) Validate() error {
+	if !assertionTypes[a.Type] {
 		return fmt.Errorf("invalid assertion type: %s", a.Type)
 	}
+	if a.Weight < 0 {
+		return fmt.Errorf("%s weight must not be negative", a.Type)
+	}
 
 	// Type-specific validation
 	switch a.Type {
@@ -165,16 +565,281 @@ func (a *Assertion) Validate() error {
 		if a.Threshold < 0 || a.Threshold > 1 {
 			return fmt.Errorf("answer-relevance threshold must be between 0 and 1")
 		}
+	case "semantic-similarity":
+		if a.Threshold < 0 || a.Threshold > 1 {
+			return fmt.Errorf("semantic-similarity threshold must be between 0 and 1")
+		}
+		if a.Value == nil || a.Value == "" {
+			return fmt.Errorf("semantic-similarity assertion requires the expected text as value")
+		}
+	case "toxicity":
+		if a.Threshold < 0 || a.Threshold > 1 {
+			return fmt.Errorf("toxicity threshold must be between 0 and 1")
+		}
+	case "min-confidence":
+		if a.Threshold < 0 || a.Threshold > 1 {
+			return fmt.Errorf("min-confidence threshold must be between 0 and 1")
+		}
+	case "list-count":
+		if err := validateListCount(a.Value); err != nil {
+			return err
+		}
+	case "latency-p95":
+		if _, err := a.Duration(); err != nil {
+			return err
+		}
+	case "no-repetition":
+		if a.Threshold < 0 || a.Threshold > 1 {
+			return fmt.Errorf("no-repetition threshold must be between 0 and 1")
+		}
+		if a.Value != nil {
+			if window, ok := a.Value.(int); !ok || window < 2 {
+				return fmt.Errorf("no-repetition value must be a window of at least 2 words")
+			}
+		}
+	case "matches-struct":
+		if name, ok := a.Value.(string); !ok || name == "" {
+			return fmt.Errorf("matches-struct assertion requires the name of a registered struct as value")
+		}
+	case "no-prompt-leak":
+		if a.Threshold < 0 || a.Threshold > 1 {
+			return fmt.Errorf("no-prompt-leak threshold must be between 0 and 1")
+		}
+		switch v := a.Value.(type) {
+		case nil, string:
+		case []interface{}:
+			for _, secret := range v {
+				if _, ok := secret.(string); !ok {
+					return fmt.Errorf("no-prompt-leak secrets must be strings")
+				}
+			}
+		default:
+			return fmt.Errorf("no-prompt-leak value must be a secret or a list of secrets")
+		}
+	case "conversation-contains", "conversation-not-contains":
+		if err := validateConversation(a.Value); err != nil {
+			return fmt.Errorf("%s %w", a.Type, err)
+		}
+	case "tool-call":
+		if err := validateToolCall(a.Value); err != nil {
+			return err
+		}
+	case "matches-examples":
+		examples, ok := a.Value.([]interface{})
+		if !ok || len(examples) == 0 {
+			return fmt.Errorf("matches-examples assertion requires a list of example responses")
+		}
+		if a.Threshold < 0 || a.Threshold > 1 {
+			return fmt.Errorf("matches-examples threshold must be between 0 and 1")
+		}
+	case "pii":
+		if a.Value != nil {
+			categories, ok := a.Value.([]interface{})
+			if !ok {
+				return fmt.Errorf("pii assertion value must be a list of allowed categories")
+			}
+			for _, category := range categories {
+				if !piiCategories[fmt.Sprint(category)] {
+					return fmt.Errorf("unknown pii category: %v", category)
+				}
+			}
+		}
 	}
 
 	return nil
 }
 
-// expandPromptPaths expands glob patterns in prompt paths
+// generationRanges are the allowed ranges of numeric generation settings
+var generationRanges = []struct {
+	key      string
+	min, max float64
+}{
+	{"temperature", 0, 2},
+	{"top_p", 0, 1},
+	{"frequency_penalty", -2, 2},
+	{"presence_penalty", -2, 2},
+}
+
+// validateGenerationSettings checks the generation settings of a provider:
+// temperature, top_p, the penalties, max_tokens, seed, and stop
+func validateGenerationSettings(settings map[string]interface{}) error {
+	for _, r := range generationRanges {
+		value, ok := settings[r.key]
+		if !ok {
+			continue
+		}
+		number, ok := settingNumber(value)
+		if !ok || number < r.min || number > r.max {
+			return fmt.Errorf("%s must be a number between %g and %g", r.key, r.min, r.max)
+		}
+	}
+
+	if value, ok := settings["max_tokens"]; ok {
+		number, ok := settingNumber(value)
+		if !ok || number < 1 || number != float64(int(number)) {
+			return fmt.Errorf("max_tokens must be a positive whole number")
+		}
+	}
+
+	if value, ok := settings["seed"]; ok {
+		number, ok := settingNumber(value)
+		if !ok || number != float64(int(number)) {
+			return fmt.Errorf("seed must be a whole number")
+		}
+	}
+
+	if value, ok := settings["stop"]; ok {
+		switch stop := value.(type) {
+		case string:
+		case []interface{}:
+			if len(stop) == 0 || len(stop) > 4 {
+				return fmt.Errorf("stop must list between 1 and 4 sequences")
+			}
+			for _, sequence := range stop {
+				if _, ok := sequence.(string); !ok {
+					return fmt.Errorf("stop sequences must be strings")
+				}
+			}
+		default:
+			return fmt.Errorf("stop must be a sequence or a list of sequences")
+		}
+	}
+
+	return nil
+}
+
+// settingNumber converts a numeric setting decoded from YAML, int or float64
+func settingNumber(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case int:
+		return float64(v), true
+	case float64:
+		return v, true
+	}
+	return 0, false
+}
+
+// IsRequired reports whether a failure of the assertion fails the test.
+// Assertions are required unless they set required: false.
+func (a *Assertion) IsRequired() bool {
+	return a.Required == nil || *a.Required
+}
+
+// EffectiveWeight is the assertion's weight in its test's score, 1 unless set
+func (a *Assertion) EffectiveWeight() float64 {
+	if a.Weight == 0 {
+		return 1
+	}
+	return a.Weight
+}
+
+// Duration parses the assertion value as a positive duration such as "1.5s"
+func (a *Assertion) Duration() (time.Duration, error) {
+	value, ok := a.Value.(string)
+	if !ok {
+		return 0, fmt.Errorf("%s value must be a duration such as \"2s\"", a.Type)
+	}
+
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration <= 0 {
+		return 0, fmt.Errorf("%s value must be a positive duration such as \"2s\", got %q", a.Type, value)
+	}
+
+	return duration, nil
+}
+
+// validateConversation checks a conversation assertion value: a text, a list
+// of texts, or a map with "text" and an optional "role"
+func validateConversation(value interface{}) error {
+	if options, ok := value.(map[string]interface{}); ok {
+		for key := range options {
+			if key != "text" && key != "role" {
+				return fmt.Errorf("has unknown option: %s", key)
+			}
+		}
+		if role, ok := options["role"]; ok {
+			switch role {
+			case "system", "user", "assistant", "any":
+			default:
+				return fmt.Errorf("role must be system, user, assistant, or any")
+			}
+		}
+		value = options["text"]
+	}
+
+	switch v := value.(type) {
+	case string:
+		if v == "" {
+			return fmt.Errorf("value must not be empty")
+		}
+		return nil
+	case []interface{}:
+		if len(v) == 0 {
+			return fmt.Errorf("value list is empty")
+		}
+		for _, item := range v {
+			if s, ok := item.(string); !ok || s == "" {
+				return fmt.Errorf("values must be non-empty strings")
+			}
+		}
+		return nil
+	default:
+		return fmt.Errorf("value must be a text, a list of texts, or a map with text and role")
+	}
+}
+
+// validateListCount checks a list-count value: an exact count, or a map with
+// "min" and/or "max" counts
+func validateListCount(value interface{}) error {
+	switch v := value.(type) {
+	case int:
+		if v < 0 {
+			return fmt.Errorf("list-count value must not be negative")
+		}
+		return nil
+	case map[string]interface{}:
+		bounds := make(map[string]int)
+		for key, bound := range v {
+			if key != "min" && key != "max" {
+				return fmt.Errorf("unknown list-count option: %s", key)
+			}
+			n, ok := bound.(int)
+			if !ok || n < 0 {
+				return fmt.Errorf("list-count %s must be a non-negative integer", key)
+			}
+			bounds[key] = n
+		}
+		if len(bounds) == 0 {
+			return fmt.Errorf("list-count value requires min and/or max")
+		}
+		if max, ok := bounds["max"]; ok && bounds["min"] > max {
+			return fmt.Errorf("list-count min must not exceed max")
+		}
+		return nil
+	default:
+		return fmt.Errorf("list-count value must be a count or a map with min and/or max")
+	}
+}
+
+// piiCategories lists the categories understood by the pii assertion
+var piiCategories = map[string]bool{
+	"email":       true,
+	"phone":       true,
+	"ssn":         true,
+	"credit-card": true,
+}
+
+// expandPromptPaths expands glob patterns in local prompt paths; http(s)
+// URLs are kept as they are and fetched when the prompts are loaded
 func (c *Config) expandPromptPaths() error {
 	var expandedPaths []string
 
 	for _, pattern := range c.Prompts {
+		if isRemotePrompt(pattern) {
+			expandedPaths = append(expandedPaths, pattern)
+			continue
+		}
+
 		matches, err := filepath.Glob(pattern)
 		if err != nil {
 			return fmt.Errorf("invalid glob pattern %s: %w", pattern, err)
@@ -191,6 +856,21 @@ func (c *Config) expandPromptPaths() error {
 	return nil
 }
 
+// InlinePromptNames returns the names of the inline prompts, sorted
+func (c *Config) InlinePromptNames() []string {
+	names := make([]string, 0, len(c.InlinePrompts))
+	for name := range c.InlinePrompts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// isRemotePrompt reports whether a prompts entry is an http(s) URL
+func isRemotePrompt(path string) bool {
+	return strings.HasPrefix(path, "http://") || strings.HasPrefix(path, "https://")
+}
+
 // GetProvider returns a provider by ID
 func (c *Config) GetProvider(id string) (*Provider, error) {
 	for _, provider := range c.Providers {
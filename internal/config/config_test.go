@@ -0,0 +1,53 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRedactedConfigHidesCredentialHeaders(t *testing.T) {
+	provider := Provider{
+		ID: "openai:gpt-4o-mini",
+		Config: map[string]interface{}{
+			"api_key":     "sk-secret",
+			"temperature": 0.2,
+			"headers": map[string]interface{}{
+				"Authorization":   "Bearer gateway-secret",
+				"X-Api-Key":       "key-secret",
+				"X-Gateway-Token": "token-secret",
+				"X-Team":          "search",
+			},
+		},
+	}
+
+	want := map[string]interface{}{
+		"api_key":     "[redacted]",
+		"temperature": 0.2,
+		"headers": map[string]interface{}{
+			"Authorization":   "[redacted]",
+			"X-Api-Key":       "[redacted]",
+			"X-Gateway-Token": "[redacted]",
+			"X-Team":          "search",
+		},
+	}
+	if got := provider.RedactedConfig(); !reflect.DeepEqual(got, want) {
+		t.Errorf("RedactedConfig() = %v, want %v", got, want)
+	}
+
+	headers := provider.Config["headers"].(map[string]interface{})
+	if headers["Authorization"] != "Bearer gateway-secret" || provider.Config["api_key"] != "sk-secret" {
+		t.Errorf("RedactedConfig() modified the provider settings: %v", provider.Config)
+	}
+}
+
+func TestRedactedConfigHeadersWithoutAPIKey(t *testing.T) {
+	provider := Provider{
+		ID:     "openai:gpt-4o-mini",
+		Config: map[string]interface{}{"headers": map[string]interface{}{"authorization": "Bearer gateway-secret"}},
+	}
+
+	headers := provider.RedactedConfig()["headers"].(map[string]interface{})
+	if headers["authorization"] != "[redacted]" {
+		t.Errorf("authorization header = %v, want [redacted]", headers["authorization"])
+	}
+}
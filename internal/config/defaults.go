@@ -0,0 +1,71 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Defaults are values applied to every provider and assertion that does not
+// set them itself, so shared settings are written once
+type Defaults struct {
+	// Provider is merged into the config of each provider, such as
+	// temperature and max_tokens; keys a provider sets are kept
+	Provider map[string]interface{} `yaml:"provider,omitempty"`
+	// Thresholds are the thresholds of assertions by type, used by the
+	// assertions of that type without a threshold
+	Thresholds map[string]float64 `yaml:"thresholds,omitempty"`
+}
+
+// applyDefaults merges the defaults into the providers and assertions so
+// that the rest of the code sees the resolved values
+func (c *Config) applyDefaults() {
+	for i := range c.Providers {
+		for key, value := range c.Defaults.Provider {
+			if _, ok := c.Providers[i].Config[key]; ok {
+				continue
+			}
+			if c.Providers[i].Config == nil {
+				c.Providers[i].Config = make(map[string]interface{})
+			}
+			c.Providers[i].Config[key] = value
+		}
+	}
+
+	if len(c.Defaults.Thresholds) == 0 {
+		return
+	}
+	applyThreshold := func(assertions []Assertion) {
+		for i := range assertions {
+			if threshold, ok := c.Defaults.Thresholds[assertions[i].Type]; ok && assertions[i].Threshold == 0 {
+				assertions[i].Threshold = threshold
+			}
+		}
+	}
+	for i := range c.Tests {
+		applyThreshold(c.Tests[i].Assert)
+		for j := range c.Tests[i].Conversation {
+			applyThreshold(c.Tests[i].Conversation[j].Assert)
+		}
+	}
+}
+
+// validateDefaults checks the default provider settings and that the
+// default thresholds are for known assertion types
+func (c *Config) validateDefaults() error {
+	if err := validateGenerationSettings(c.Defaults.Provider); err != nil {
+		return atField(fmt.Errorf("defaults.provider: %w", err), "defaults", "provider")
+	}
+
+	types := make([]string, 0, len(c.Defaults.Thresholds))
+	for assertionType := range c.Defaults.Thresholds {
+		types = append(types, assertionType)
+	}
+	sort.Strings(types)
+	for _, assertionType := range types {
+		if !assertionTypes[assertionType] {
+			return atField(fmt.Errorf("defaults.thresholds: invalid assertion type: %s", assertionType), "defaults", "thresholds", assertionType)
+		}
+	}
+
+	return nil
+}
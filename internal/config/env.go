@@ -0,0 +1,108 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// envReferenceRegex matches $$ and ${...} references in a config value
+var envReferenceRegex = regexp.MustCompile(`\$\$|\$\{[^}\n]*\}?`)
+
+// envNameRegex matches a variable reference, with an optional default after
+// ":-", e.g. OPENAI_MODEL:-gpt-4o
+var envNameRegex = regexp.MustCompile(`^([A-Za-z_][A-Za-z0-9_]*)(?::-(.*))?$`)
+
+// expandEnv replaces ${VAR} and ${VAR:-default} references in the values of
+// a parsed config with the environment. Only scalar values are expanded, so
+// references in comments and mapping keys are left alone. The default is
+// used when the variable is unset or empty, and $$ stands for a literal $.
+// Any other $ is left alone, so JSONPaths such as "$.users[0]" need no
+// escaping.
+func expandEnv(root *yaml.Node) error {
+	var missing []string
+	if err := expandEnvNode(root, &missing); err != nil {
+		return err
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("environment variables not set and without a default: %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// expandEnvNode expands the scalar values beneath node, adding unset
+// variables to missing
+func expandEnvNode(node *yaml.Node, missing *[]string) error {
+	switch node.Kind {
+	case yaml.DocumentNode, yaml.SequenceNode:
+		for _, child := range node.Content {
+			if err := expandEnvNode(child, missing); err != nil {
+				return err
+			}
+		}
+	case yaml.MappingNode:
+		for i := 1; i < len(node.Content); i += 2 {
+			if err := expandEnvNode(node.Content[i], missing); err != nil {
+				return err
+			}
+		}
+	case yaml.ScalarNode:
+		return expandEnvScalar(node, missing)
+	}
+	return nil
+}
+
+// expandEnvScalar expands the references in a scalar value. Unquoted values
+// are typed again after expansion, so "timeout: ${TIMEOUT}" is a number.
+func expandEnvScalar(node *yaml.Node, missing *[]string) error {
+	content := node.Value
+	if !strings.Contains(content, "$") {
+		return nil
+	}
+
+	var expanded strings.Builder
+	last := 0
+	for _, loc := range envReferenceRegex.FindAllStringIndex(content, -1) {
+		ref := content[loc[0]:loc[1]]
+		expanded.WriteString(content[last:loc[0]])
+		last = loc[1]
+
+		if ref == "$$" {
+			expanded.WriteString("$")
+			continue
+		}
+
+		line := node.Line + strings.Count(content[:loc[0]], "\n")
+		if node.Style&(yaml.LiteralStyle|yaml.FoldedStyle) != 0 {
+			line++ // block scalars start below their indicator
+		}
+		if !strings.HasSuffix(ref, "}") {
+			return fmt.Errorf("line %d: unterminated variable reference %q", line, ref)
+		}
+		matches := envNameRegex.FindStringSubmatch(ref[2 : len(ref)-1])
+		if matches == nil {
+			return fmt.Errorf("line %d: invalid variable reference %q (write $$ for a literal $)", line, ref)
+		}
+
+		value, set := os.LookupEnv(matches[1])
+		switch {
+		case value == "" && strings.Contains(ref, ":-"):
+			value = matches[2]
+		case !set:
+			*missing = append(*missing, fmt.Sprintf("%s (line %d)", matches[1], line))
+		}
+		expanded.WriteString(value)
+	}
+	expanded.WriteString(content[last:])
+
+	if expanded.String() != content {
+		node.Value = expanded.String()
+		if node.Style == 0 {
+			node.Tag = ""
+		}
+	}
+	return nil
+}
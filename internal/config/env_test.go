@@ -0,0 +1,66 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func loadTestConfig(t *testing.T, content string) (*Config, error) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "promptguard.yaml")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return LoadFromFile(path)
+}
+
+func TestExpandEnvInValues(t *testing.T) {
+	t.Setenv("PG_TEST_MODEL", "echo")
+	t.Setenv("PG_TEST_TIMEOUT", "45")
+
+	config, err := loadTestConfig(t, `
+# Set ${PG_TEST_UNSET} to pick another model; comments are not expanded
+inlinePrompts:
+  greet: "Costs $$5, path $.user"
+providers:
+  - id: mock:${PG_TEST_MODEL}
+settings:
+  timeout: ${PG_TEST_TIMEOUT}
+  userAgent: "${PG_TEST_TIMEOUT}"
+  cacheTTL: ${PG_TEST_TTL:-12h}
+`+servedTests)
+	if err != nil {
+		t.Fatalf("LoadFromFile() error = %v", err)
+	}
+
+	if config.Providers[0].ID != "mock:echo" {
+		t.Errorf("provider = %s, want mock:echo", config.Providers[0].ID)
+	}
+	if config.Settings.Timeout != 45 {
+		t.Errorf("timeout = %d, want 45", config.Settings.Timeout)
+	}
+	if config.Settings.UserAgent != "45" {
+		t.Errorf("userAgent = %q, want \"45\"", config.Settings.UserAgent)
+	}
+	if config.Settings.CacheTTL != "12h" {
+		t.Errorf("cacheTTL = %q, want the default 12h", config.Settings.CacheTTL)
+	}
+	if got := config.InlinePrompts["greet"]; got != "Costs $5, path $.user" {
+		t.Errorf("inline prompt = %q, want $$ as $ and $.user kept", got)
+	}
+}
+
+func TestExpandEnvReportsMissingVariables(t *testing.T) {
+	_, err := loadTestConfig(t, `inlinePrompts:
+  greet: "Hello"
+providers:
+  - id: mock:echo
+    config:
+      base_url: ${PG_TEST_UNSET_URL}
+`+servedTests)
+	if err == nil || !strings.Contains(err.Error(), "PG_TEST_UNSET_URL (line 6)") {
+		t.Fatalf("LoadFromFile() error = %v, want PG_TEST_UNSET_URL (line 6)", err)
+	}
+}
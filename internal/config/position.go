@@ -0,0 +1,70 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+// fieldError is a validation error of the config value at a path such as
+// tests, 2, assert, 0, so that it can be traced back to its line
+type fieldError struct {
+	path []interface{} // Mapping keys and sequence indexes
+	err  error
+}
+
+func (e *fieldError) Error() string { return e.err.Error() }
+func (e *fieldError) Unwrap() error { return e.err }
+
+// atField attributes err to the config value at path
+func atField(err error, path ...interface{}) error {
+	return &fieldError{path: path, err: err}
+}
+
+// locate returns the node at the path of a field error in err, or the
+// deepest node on the path that exists, such as the test of a defaulted
+// setting. It returns nil when err is not a field error.
+func locate(root *yaml.Node, err error) *yaml.Node {
+	var fe *fieldError
+	if !errors.As(err, &fe) {
+		return nil
+	}
+
+	node := root
+	for _, step := range fe.path {
+		next := child(node, step)
+		if next == nil {
+			break
+		}
+		node = next
+	}
+	return node
+}
+
+// child returns the value of a mapping key or the item of a sequence index
+func child(node *yaml.Node, step interface{}) *yaml.Node {
+	switch node.Kind {
+	case yaml.MappingNode:
+		key := fmt.Sprint(step)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if node.Content[i].Value == key {
+				return node.Content[i+1]
+			}
+		}
+	case yaml.SequenceNode:
+		if i, ok := step.(int); ok && i >= 0 && i < len(node.Content) {
+			return node.Content[i]
+		}
+	}
+	return nil
+}
+
+// position formats where a node is, as file:line:column, or by line and
+// column when the file is unknown
+func position(file string, node *yaml.Node) string {
+	if file == "" {
+		return fmt.Sprintf("line %d, column %d", node.Line, node.Column)
+	}
+	return fmt.Sprintf("%s:%d:%d", file, node.Line, node.Column)
+}
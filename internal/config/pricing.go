@@ -0,0 +1,106 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ModelPricing represents the price of a model in USD per 1K tokens
+type ModelPricing struct {
+	Prompt     float64 `yaml:"prompt"`
+	Completion float64 `yaml:"completion"`
+}
+
+// LoadPricingFile loads a pricing table from a YAML file keyed by provider ID
+// (e.g. "openai:gpt-4o-mini")
+func LoadPricingFile(filename string) (map[string]ModelPricing, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read pricing file %s: %w", filename, err)
+	}
+
+	pricing, err := ParsePricing(data)
+	if err != nil {
+		return nil, fmt.Errorf("pricing file %s: %w", filename, err)
+	}
+	return pricing, nil
+}
+
+// ParsePricing parses and validates a YAML pricing table keyed by provider ID
+func ParsePricing(data []byte) (map[string]ModelPricing, error) {
+	var pricing map[string]ModelPricing
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&pricing); err != nil {
+		return nil, fmt.Errorf("failed to parse pricing: %w", err)
+	}
+
+	if err := ValidatePricing(pricing); err != nil {
+		return nil, fmt.Errorf("invalid pricing: %w", err)
+	}
+
+	return pricing, nil
+}
+
+// PricingOverrides returns the configured prices that replace the built-in
+// ones: the pricing table, then each provider's own config.pricing, which
+// wins for its provider ID
+func (c *Config) PricingOverrides() map[string]ModelPricing {
+	overrides := make(map[string]ModelPricing, len(c.Pricing)+len(c.Providers))
+	for id, price := range c.Pricing {
+		overrides[id] = price
+	}
+	for _, provider := range c.Providers {
+		if price, ok := providerPricing(provider); ok {
+			overrides[provider.ID] = price
+		}
+	}
+	return overrides
+}
+
+// providerPricing returns the price set in a provider's config.pricing
+func providerPricing(provider Provider) (ModelPricing, bool) {
+	settings, ok := provider.Config["pricing"].(map[string]interface{})
+	if !ok {
+		return ModelPricing{}, false
+	}
+	prompt, _ := settingNumber(settings["prompt"])
+	completion, _ := settingNumber(settings["completion"])
+	return ModelPricing{Prompt: prompt, Completion: completion}, true
+}
+
+// validateProviderPricing checks a provider's config.pricing: a map of
+// non-negative prompt and completion prices
+func validateProviderPricing(value interface{}) error {
+	settings, ok := value.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("pricing must be a map with prompt and completion prices")
+	}
+	for key, price := range settings {
+		if key != "prompt" && key != "completion" {
+			return fmt.Errorf("unknown pricing field %q (expected prompt or completion)", key)
+		}
+		if number, ok := settingNumber(price); !ok || number < 0 {
+			return fmt.Errorf("pricing %s must be a non-negative number", key)
+		}
+	}
+	return nil
+}
+
+// ValidatePricing validates the shape of a pricing table
+func ValidatePricing(pricing map[string]ModelPricing) error {
+	for id, price := range pricing {
+		parts := strings.SplitN(id, ":", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return fmt.Errorf("invalid pricing key %q (expected provider:model)", id)
+		}
+		if price.Prompt < 0 || price.Completion < 0 {
+			return fmt.Errorf("pricing for %s must not be negative", id)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// credentialKeys are the provider settings that name or hold credentials
+var credentialKeys = []string{"api_key", "api_key_env", "org_id"}
+
+// ServeOptions restricts the configs posted to pg serve, which run with the
+// server's credentials and filesystem
+type ServeOptions struct {
+	// AllowedBaseURLs are the base URLs posted providers may set in base_url
+	// or base_urls; providers that set none use their default endpoint
+	AllowedBaseURLs []string
+	// Providers are the server's own providers. Their credentials are given
+	// to the posted providers of the same ID; other posted providers fall
+	// back to their provider's default environment variable.
+	Providers []Provider
+}
+
+// ParseServed parses, validates, and resolves configuration posted to
+// pg serve. Posted configs may not set credentials or endpoints outside
+// opts.AllowedBaseURLs, and may not read the server's files: prompts must be
+// inline and variables cannot come from data files.
+func ParseServed(data []byte, opts ServeOptions) (*Config, error) {
+	return parse(data, &opts)
+}
+
+// checkServed rejects the parts of a posted config that would read the
+// server's files or send its credentials to another host
+func (c *Config) checkServed(opts *ServeOptions) error {
+	if len(c.Prompts) > 0 {
+		return atField(fmt.Errorf("prompts are not supported in configs posted to pg serve; use inlinePrompts"), "prompts")
+	}
+
+	allowed := make(map[string]bool, len(opts.AllowedBaseURLs))
+	for _, url := range opts.AllowedBaseURLs {
+		allowed[strings.TrimRight(url, "/")] = true
+	}
+
+	for i, provider := range c.Providers {
+		for _, key := range credentialKeys {
+			if _, ok := provider.Config[key]; ok {
+				return atField(fmt.Errorf("provider %s: %s is not supported in configs posted to pg serve", provider.ID, key), "providers", i, "config", key)
+			}
+		}
+
+		var urls []interface{}
+		if url, ok := provider.Config["base_url"]; ok {
+			urls = append(urls, url)
+		}
+		if list, ok := provider.Config["base_urls"].([]interface{}); ok {
+			urls = append(urls, list...)
+		}
+		for _, url := range urls {
+			s, _ := url.(string)
+			if !allowed[strings.TrimRight(s, "/")] {
+				return atField(fmt.Errorf("provider %s: base URL %v is not allowed by the server", provider.ID, url), "providers", i, "config")
+			}
+		}
+	}
+
+	for i, test := range c.Tests {
+		for name, value := range test.Variables {
+			if _, _, ok := variableSource(value); ok {
+				return atField(fmt.Errorf("test %d, variable %s: data files are not supported in configs posted to pg serve", i, name), "tests", i, "vars", name)
+			}
+		}
+	}
+
+	return nil
+}
+
+// injectCredentials gives each posted provider the credentials of the
+// server's provider with the same ID
+func (c *Config) injectCredentials(opts *ServeOptions) {
+	for i := range c.Providers {
+		for _, own := range opts.Providers {
+			if own.ID != c.Providers[i].ID {
+				continue
+			}
+			if c.Providers[i].Config == nil {
+				c.Providers[i].Config = make(map[string]interface{})
+			}
+			for _, key := range credentialKeys {
+				if value, ok := own.Config[key]; ok {
+					c.Providers[i].Config[key] = value
+				}
+			}
+			break
+		}
+	}
+}
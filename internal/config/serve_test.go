@@ -0,0 +1,78 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+const servedPrompts = `
+inlinePrompts:
+  greet: "Hello {{ .name }}"
+`
+
+const servedTests = `
+tests:
+  - vars:
+      name: Ada
+    assert:
+      - type: cost
+        threshold: 0.01
+`
+
+func TestParseServedRejects(t *testing.T) {
+	opts := ServeOptions{AllowedBaseURLs: []string{"https://llm.internal/v1/"}}
+
+	tests := []struct {
+		name   string
+		config string
+		want   string
+	}{
+		{"api_key_env", "providers:\n  - id: openai:gpt-4o-mini\n    config:\n      api_key_env: AWS_SECRET_ACCESS_KEY\n", "api_key_env is not supported"},
+		{"api_key", "providers:\n  - id: openai:gpt-4o-mini\n    config:\n      api_key: sk-posted\n", "api_key is not supported"},
+		{"base_url", "providers:\n  - id: openai:gpt-4o-mini\n    config:\n      base_url: http://attacker\n", "not allowed by the server"},
+		{"base_urls", "providers:\n  - id: openai:gpt-4o-mini\n    config:\n      base_urls: [https://llm.internal/v1, http://attacker]\n", "not allowed by the server"},
+		{"defaulted key", "defaults:\n  provider:\n    api_key_env: HOME\nproviders:\n  - id: openai:gpt-4o-mini\n", "api_key_env is not supported"},
+		{"prompt files", "prompts: [\"/etc/*\"]\nproviders:\n  - id: mock:echo\n", "use inlinePrompts"},
+		{"data files", "providers:\n  - id: mock:echo\ntests:\n  - vars:\n      name: {from: /etc/passwd, path: $.root}\n    assert:\n      - type: cost\n        threshold: 0.01\n", "data files are not supported"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := servedPrompts + tt.config
+			if !strings.Contains(tt.config, "tests:") {
+				config += servedTests
+			}
+			_, err := ParseServed([]byte(config), opts)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("ParseServed() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseServedInjectsCredentials(t *testing.T) {
+	opts := ServeOptions{
+		AllowedBaseURLs: []string{"https://llm.internal/v1/"},
+		Providers: []Provider{{
+			ID:     "openai:gpt-4o-mini",
+			Config: map[string]interface{}{"api_key_env": "SERVER_OPENAI_KEY", "base_url": "https://ignored"},
+		}},
+	}
+
+	config := "providers:\n  - id: openai:gpt-4o-mini\n    config:\n      base_url: https://llm.internal/v1\n  - id: mock:echo\n"
+	cfg, err := ParseServed([]byte(servedPrompts+config+servedTests), opts)
+	if err != nil {
+		t.Fatalf("ParseServed() error = %v", err)
+	}
+
+	got := cfg.Providers[0].Config
+	if got["api_key_env"] != "SERVER_OPENAI_KEY" {
+		t.Errorf("api_key_env = %v, want the server's", got["api_key_env"])
+	}
+	if got["base_url"] != "https://llm.internal/v1" {
+		t.Errorf("base_url = %v, want the posted one", got["base_url"])
+	}
+	if _, ok := cfg.Providers[1].Config["api_key_env"]; ok {
+		t.Errorf("provider without a server counterpart was given credentials")
+	}
+}
@@ -0,0 +1,104 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// Tool is a function offered to the model, declared in a test's tools or a
+// provider's tools setting
+type Tool struct {
+	Name        string                 `yaml:"name"`
+	Description string                 `yaml:"description,omitempty"`
+	Parameters  map[string]interface{} `yaml:"parameters,omitempty"` // JSON schema of the arguments
+}
+
+// toolNameRegex matches the function names OpenAI accepts
+var toolNameRegex = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,64}$`)
+
+// Validate validates a tool
+func (t *Tool) Validate() error {
+	if !toolNameRegex.MatchString(t.Name) {
+		return fmt.Errorf("tool name %q must be 1 to 64 letters, digits, underscores, or dashes", t.Name)
+	}
+	return nil
+}
+
+// Setting returns the tool as an entry of a provider's tools setting
+func (t *Tool) Setting() map[string]interface{} {
+	setting := map[string]interface{}{"name": t.Name}
+	if t.Description != "" {
+		setting["description"] = t.Description
+	}
+	if t.Parameters != nil {
+		setting["parameters"] = t.Parameters
+	}
+	return setting
+}
+
+// validateProviderTools validates a provider's tools setting, a list of
+// {name, description, parameters}
+func validateProviderTools(value interface{}) error {
+	entries, ok := value.([]interface{})
+	if !ok {
+		return fmt.Errorf("tools must be a list of {name, description, parameters}")
+	}
+
+	for i, entry := range entries {
+		setting, ok := entry.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("tool %d must be a map of name, description, and parameters", i)
+		}
+
+		var tool Tool
+		for key, v := range setting {
+			switch key {
+			case "name":
+				tool.Name, _ = v.(string)
+			case "description":
+				if _, ok := v.(string); !ok {
+					return fmt.Errorf("tool %d description must be a string", i)
+				}
+			case "parameters":
+				if _, ok := v.(map[string]interface{}); !ok {
+					return fmt.Errorf("tool %d parameters must be a JSON schema object", i)
+				}
+			default:
+				return fmt.Errorf("tool %d has unknown key: %s", i, key)
+			}
+		}
+		if err := tool.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateToolCall validates the value of a tool-call assertion: a function
+// name, or {name, arguments} with arguments a JSON schema the call's
+// arguments must match
+func validateToolCall(value interface{}) error {
+	switch v := value.(type) {
+	case string:
+		if v == "" {
+			return fmt.Errorf("tool-call assertion requires a function name")
+		}
+	case map[string]interface{}:
+		for key := range v {
+			if key != "name" && key != "arguments" {
+				return fmt.Errorf("tool-call assertion has unknown option: %s", key)
+			}
+		}
+		if name, ok := v["name"].(string); !ok || name == "" {
+			return fmt.Errorf("tool-call assertion requires a function name")
+		}
+		if arguments, ok := v["arguments"]; ok {
+			if _, ok := arguments.(map[string]interface{}); !ok {
+				return fmt.Errorf("tool-call arguments must be a JSON schema object")
+			}
+		}
+	default:
+		return fmt.Errorf("tool-call assertion value must be a function name or {name, arguments}")
+	}
+	return nil
+}
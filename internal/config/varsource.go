@@ -0,0 +1,158 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// resolveVariableSources replaces test variables of the form
+// {from: file, path: "$.users[0]"} with the value the JSONPath selects from
+// the JSON or YAML data file. Each file is read once.
+func (c *Config) resolveVariableSources() error {
+	files := make(map[string]interface{})
+
+	for i, test := range c.Tests {
+		for name, value := range test.Variables {
+			from, path, ok := variableSource(value)
+			if !ok {
+				continue
+			}
+
+			data, loaded := files[from]
+			if !loaded {
+				var err error
+				data, err = loadDataFile(from)
+				if err != nil {
+					return atField(fmt.Errorf("test %d, variable %s: %w", i, name, err), "tests", i, "vars", name)
+				}
+				files[from] = data
+			}
+
+			resolved, err := lookupPath(data, path)
+			if err != nil {
+				return atField(fmt.Errorf("test %d, variable %s: %s in %s: %w", i, name, path, from, err), "tests", i, "vars", name)
+			}
+			test.Variables[name] = resolved
+		}
+	}
+
+	return nil
+}
+
+// variableSource reports whether a variable value is a data file reference,
+// a map with exactly the keys "from" and "path"
+func variableSource(value interface{}) (string, string, bool) {
+	source, ok := value.(map[string]interface{})
+	if !ok || len(source) != 2 {
+		return "", "", false
+	}
+	from, fromOK := source["from"].(string)
+	path, pathOK := source["path"].(string)
+	return from, path, fromOK && pathOK
+}
+
+// loadDataFile parses a JSON or YAML data file. JSON is parsed as YAML so
+// that numbers decode the same way as inline variables.
+func loadDataFile(filename string) (interface{}, error) {
+	raw, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read data file: %w", err)
+	}
+
+	var data interface{}
+	if err := yaml.Unmarshal(raw, &data); err != nil {
+		return nil, fmt.Errorf("failed to parse data file %s: %w", filename, err)
+	}
+	return data, nil
+}
+
+// lookupPath evaluates a JSONPath of child keys and array indexes, such as
+// $.users[0].name or $['user name'][-1]. Negative indexes count from the end.
+func lookupPath(data interface{}, path string) (interface{}, error) {
+	if !strings.HasPrefix(path, "$") {
+		return nil, fmt.Errorf("path must start with $")
+	}
+
+	current := data
+	rest := path[1:]
+	for rest != "" {
+		switch {
+		case strings.HasPrefix(rest, "['"):
+			end := strings.Index(rest, "']")
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated ['...'] in path")
+			}
+			key := rest[2:end]
+			rest = rest[end+2:]
+
+			next, err := childKey(current, key)
+			if err != nil {
+				return nil, err
+			}
+			current = next
+
+		case strings.HasPrefix(rest, "["):
+			end := strings.Index(rest, "]")
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated [...] in path")
+			}
+			raw := rest[1:end]
+			index, err := strconv.Atoi(raw)
+			if err != nil {
+				return nil, fmt.Errorf("invalid array index %q", raw)
+			}
+			rest = rest[end+1:]
+
+			list, ok := current.([]interface{})
+			if !ok {
+				return nil, fmt.Errorf("[%s] applied to a non-array value", raw)
+			}
+			if index < 0 {
+				index += len(list)
+			}
+			if index < 0 || index >= len(list) {
+				return nil, fmt.Errorf("index %s out of range (length %d)", raw, len(list))
+			}
+			current = list[index]
+
+		case strings.HasPrefix(rest, "."):
+			end := strings.IndexAny(rest[1:], ".[")
+			if end < 0 {
+				end = len(rest) - 1
+			}
+			key := rest[1 : end+1]
+			rest = rest[end+1:]
+			if key == "" {
+				return nil, fmt.Errorf("empty key in path")
+			}
+
+			next, err := childKey(current, key)
+			if err != nil {
+				return nil, err
+			}
+			current = next
+
+		default:
+			return nil, fmt.Errorf("unexpected %q in path", rest)
+		}
+	}
+
+	return current, nil
+}
+
+// childKey returns the value of key in a mapping
+func childKey(value interface{}, key string) (interface{}, error) {
+	object, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("key %q applied to a non-object value", key)
+	}
+	child, ok := object[key]
+	if !ok {
+		return nil, fmt.Errorf("key %q not found", key)
+	}
+	return child, nil
+}
@@ -0,0 +1,147 @@
+package diff
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"promptguard/internal/runner"
+)
+
+// Comparison is what changed from a baseline run to the current one: the
+// summary deltas and the status transition and assertion changes of every
+// test. The markdown, HTML, and JSON differs all render it.
+type Comparison struct {
+	Summary Summary      `json:"summary"`
+	Tests   []TestChange `json:"tests"` // Matched by prompt file, name, and provider
+
+	baseline, current *runner.Results
+}
+
+// Summary holds the changes in a run's totals
+type Summary struct {
+	Total    CountDelta    `json:"total"`
+	Passed   CountDelta    `json:"passed"`
+	Failed   CountDelta    `json:"failed"`
+	Skipped  CountDelta    `json:"skipped"`
+	Cost     CostDelta     `json:"cost"`
+	Duration DurationDelta `json:"duration"` // In nanoseconds, like results files
+}
+
+// CountDelta is the change in a number of tests
+type CountDelta struct {
+	Before int `json:"before"`
+	After  int `json:"after"`
+	Change int `json:"change"`
+}
+
+// CostDelta is the change in a cost in USD
+type CostDelta struct {
+	Before float64 `json:"before"`
+	After  float64 `json:"after"`
+	Change float64 `json:"change"`
+}
+
+// DurationDelta is the change in a duration
+type DurationDelta struct {
+	Before time.Duration `json:"before"`
+	After  time.Duration `json:"after"`
+	Change time.Duration `json:"change"`
+}
+
+// Compare compares the current run with its baseline
+func Compare(current, baseline *runner.Results) (*Comparison, error) {
+	if current == nil || baseline == nil {
+		return nil, fmt.Errorf("comparison needs both the current and the baseline results")
+	}
+
+	return &Comparison{
+		Summary: Summary{
+			Total:    CountDelta{baseline.Total, current.Total, current.Total - baseline.Total},
+			Passed:   CountDelta{baseline.Passed, current.Passed, current.Passed - baseline.Passed},
+			Failed:   CountDelta{baseline.Failed, current.Failed, current.Failed - baseline.Failed},
+			Skipped:  CountDelta{baseline.Skipped, current.Skipped, current.Skipped - baseline.Skipped},
+			Cost:     CostDelta{baseline.TotalCost, current.TotalCost, current.TotalCost - baseline.TotalCost},
+			Duration: DurationDelta{baseline.Duration, current.Duration, current.Duration - baseline.Duration},
+		},
+		Tests:    CompareTests(baseline, current),
+		baseline: baseline,
+		current:  current,
+	}, nil
+}
+
+// testSide is one run's result of a test in JSON comparisons
+type testSide struct {
+	Status string   `json:"status"`
+	Score  *float64 `json:"score"` // Mean of the scored assertions, null when none are
+	Cost   float64  `json:"cost"`
+}
+
+// MarshalJSON encodes the test's transition and both runs' outcomes, with
+// the matched assertions, instead of the full test results
+func (c TestChange) MarshalJSON() ([]byte, error) {
+	side := func(test *runner.TestResult) *testSide {
+		if test == nil {
+			return nil
+		}
+		s := &testSide{Status: test.Status, Cost: test.Cost}
+		if score, ok := meanScore(test); ok {
+			s.Score = &score
+		}
+		return s
+	}
+
+	var costChange *float64
+	if c.Before != nil && c.After != nil {
+		change := c.After.Cost - c.Before.Cost
+		costChange = &change
+	}
+
+	assertions := c.Assertions()
+	if assertions == nil {
+		assertions = []AssertionChange{}
+	}
+
+	return json.Marshal(struct {
+		Name       string            `json:"name"`
+		PromptFile string            `json:"promptFile"`
+		Provider   string            `json:"provider"`
+		Transition string            `json:"transition"`
+		Before     *testSide         `json:"before"`
+		After      *testSide         `json:"after"`
+		CostChange *float64          `json:"costChange"`
+		Assertions []AssertionChange `json:"assertions"`
+	}{c.Name, c.PromptFile, c.Provider, c.Transition(), side(c.Before), side(c.After), costChange, assertions})
+}
+
+// assertionSide is one run's result of an assertion in JSON comparisons
+type assertionSide struct {
+	Status  string  `json:"status"` // passed, failed, or warning
+	Score   float64 `json:"score"`
+	Message string  `json:"message,omitempty"`
+}
+
+// MarshalJSON encodes the assertion's outcome in both runs and how it changed
+func (c AssertionChange) MarshalJSON() ([]byte, error) {
+	side := func(result *runner.AssertionResult) *assertionSide {
+		if result == nil {
+			return nil
+		}
+		status := "failed"
+		if result.Passed {
+			status = "passed"
+		} else if result.Warning {
+			status = "warning"
+		}
+		return &assertionSide{Status: status, Score: result.Score, Message: result.Message}
+	}
+
+	return json.Marshal(struct {
+		Type        string         `json:"type"`
+		Before      *assertionSide `json:"before"`
+		After       *assertionSide `json:"after"`
+		ScoreDelta  float64        `json:"scoreDelta"`
+		Changed     bool           `json:"changed"`
+		NewlyFailed bool           `json:"newlyFailed"`
+	}{c.Type, side(c.Before), side(c.After), c.ScoreDelta(), c.Changed(), c.NewlyFailed()})
+}
@@ -2,13 +2,40 @@ package diff
 
 import (
 	"fmt"
-	"strings"
 	"github.com/sergi/go-diff/diffmatchpatch"
-	"promptgaurd/internal/runner"
+	"math"
+	"promptguard/internal/runner"
+	"sort"
+	"strings"
+	"time"
 )
 
-// MarkdownDiffer generates markdown-formatted diffs for failed assertions
-type MarkdownDiffer struct{}
+// Default significance thresholds for comparisons
+const (
+	DefaultCostAlertThreshold     = 0.001
+	DefaultCostChangeThreshold    = 0.0001
+	DefaultLatencyAlertThreshold  = time.Second
+	DefaultLatencyChangeThreshold = 100 * time.Millisecond
+)
+
+// Differ renders the failure analysis of a run and comparisons of runs
+type Differ interface {
+	GenerateFailureDiff(results *runner.Results) string
+	GenerateBaselineComparison(current, baseline *runner.Results) string
+	GenerateComparison(a, b *runner.Results, labelA, labelB string) string
+	FormatComparison(comparison *Comparison, labelBefore, labelAfter string) string
+}
+
+// MarkdownDiffer generates markdown-formatted diffs for failed assertions.
+// Zero thresholds fall back to the defaults above.
+type MarkdownDiffer struct {
+	CostAlertThreshold     float64       // Total cost increase that raises a cost alert
+	CostChangeThreshold    float64       // Smallest cost change reported as a change
+	LatencyAlertThreshold  time.Duration // Duration increase that raises a latency alert
+	LatencyChangeThreshold time.Duration // Smallest duration change reported as a change
+	MaxResponseChars       int           // Longest response shown before truncating; 0 shows it in full
+	Tolerance              Tolerance     // How far a run may fall behind before it is a regression
+}
 
 // GenerateFailureDiff creates a markdown diff view for test failures
 func (d *MarkdownDiffer) GenerateFailureDiff(results *runner.Results) string {
@@ -54,7 +81,7 @@ func (d *MarkdownDiffer) generateTestFailureDiff(test runner.TestResult) string
 	// Show failed assertions
 	md.WriteString("### 🔬 Failed Assertions\n\n")
 	for _, assertion := range test.Assertions {
-		if !assertion.Passed {
+		if assertion.Failed() {
 			md.WriteString(d.generateAssertionDiff(assertion))
 		}
 	}
@@ -62,7 +89,7 @@ func (d *MarkdownDiffer) generateTestFailureDiff(test runner.TestResult) string
 	// Show actual response
 	md.WriteString("### 📄 Actual Response\n\n")
 	md.WriteString("```json\n")
-	md.WriteString(test.Response)
+	md.WriteString(runner.Preview(test.Response, d.MaxResponseChars))
 	md.WriteString("\n```\n\n")
 
 	return md.String()
@@ -78,7 +105,7 @@ func (d *MarkdownDiffer) generateAssertionDiff(assertion runner.AssertionResult)
 	case "answer-relevance":
 		md.WriteString("**Expected Keywords/Concepts:**\n")
 		md.WriteString(fmt.Sprintf("```\n%v\n```\n\n", assertion.Expected))
-		
+
 		if assertion.Score > 0 {
 			md.WriteString(fmt.Sprintf("**Relevance Score:** %.2f ❌\n\n", assertion.Score))
 		}
@@ -86,7 +113,7 @@ func (d *MarkdownDiffer) generateAssertionDiff(assertion runner.AssertionResult)
 	case "contains-json":
 		md.WriteString("**Expected JSON Structure:**\n")
 		md.WriteString(fmt.Sprintf("```json\n%v\n```\n\n", assertion.Expected))
-		
+
 		md.WriteString("**Actual Response:**\n")
 		md.WriteString(fmt.Sprintf("```json\n%v\n```\n\n", assertion.Actual))
 
@@ -101,11 +128,11 @@ func (d *MarkdownDiffer) generateAssertionDiff(assertion runner.AssertionResult)
 	case "cost":
 		expected := assertion.Expected.(float64)
 		actual := assertion.Actual.(float64)
-		
+
 		md.WriteString("| Metric | Expected | Actual | Status |\n")
 		md.WriteString("|--------|----------|--------|---------|\n")
 		md.WriteString(fmt.Sprintf("| Cost | ≤ $%.4f | $%.4f | ❌ Over budget |\n\n", expected, actual))
-		
+
 		overagePercent := ((actual - expected) / expected) * 100
 		md.WriteString(fmt.Sprintf("**💸 Cost overage:** %.1f%% over threshold\n\n", overagePercent))
 
@@ -117,15 +144,19 @@ func (d *MarkdownDiffer) generateAssertionDiff(assertion runner.AssertionResult)
 	return md.String()
 }
 
-func (d *MarkdownDiffer) generateStringDiff(expected, actual string) string {
+// stringDiffs computes the differences from expected to actual, cleaned up
+// to human-readable edits
+func stringDiffs(expected, actual string) []diffmatchpatch.Diff {
 	dmp := diffmatchpatch.New()
 	diffs := dmp.DiffMain(expected, actual, false)
-	diffs = dmp.DiffCleanupSemantic(diffs)
+	return dmp.DiffCleanupSemantic(diffs)
+}
 
+func (d *MarkdownDiffer) generateStringDiff(expected, actual string) string {
 	var md strings.Builder
 	md.WriteString("```diff\n")
 
-	for _, diff := range diffs {
+	for _, diff := range stringDiffs(expected, actual) {
 		switch diff.Type {
 		case diffmatchpatch.DiffInsert:
 			md.WriteString(fmt.Sprintf("+ %s\n", diff.Text))
@@ -164,41 +195,416 @@ func (d *MarkdownDiffer) generateStringDiff(expected, actual string) string {
 
 // GenerateBaselineComparison compares current results with baseline
 func (d *MarkdownDiffer) GenerateBaselineComparison(current, baseline *runner.Results) string {
+	return d.GenerateComparison(baseline, current, "Baseline", "Current")
+}
+
+// GenerateComparison compares two result sets, reporting changes from a to b
+// in the summary and per test. The labels name the two sides in the report.
+func (d *MarkdownDiffer) GenerateComparison(a, b *runner.Results, labelA, labelB string) string {
+	comparison, err := Compare(b, a)
+	if err != nil {
+		return fmt.Sprintf("❌ %v\n", err)
+	}
+	return d.FormatComparison(comparison, labelA, labelB)
+}
+
+// FormatComparison renders a comparison as markdown, labeling the baseline
+// and current runs labelA and labelB
+func (d *MarkdownDiffer) FormatComparison(c *Comparison, labelA, labelB string) string {
 	var md strings.Builder
 
-	md.WriteString("# 📊 Baseline Comparison Report\n\n")
+	md.WriteString(fmt.Sprintf("# 📊 %s vs %s Comparison Report\n\n", labelA, labelB))
 
 	// Summary comparison
+	summary := c.Summary
 	md.WriteString("## 📈 Summary Changes\n\n")
-	md.WriteString("| Metric | Baseline | Current | Change |\n")
+	md.WriteString(fmt.Sprintf("| Metric | %s | %s | Change |\n", labelA, labelB))
 	md.WriteString("|--------|----------|---------|--------|\n")
-	
-	passedChange := current.Passed - baseline.Passed
-	failedChange := current.Failed - baseline.Failed
-	costChange := current.TotalCost - baseline.TotalCost
-	
-	md.WriteString(fmt.Sprintf("| Passed | %d | %d | %s |\n", 
-		baseline.Passed, current.Passed, formatChange(passedChange)))
-	md.WriteString(fmt.Sprintf("| Failed | %d | %d | %s |\n", 
-		baseline.Failed, current.Failed, formatChange(failedChange)))
-	md.WriteString(fmt.Sprintf("| Cost | $%.4f | $%.4f | %s |\n", 
-		baseline.TotalCost, current.TotalCost, formatCostChange(costChange)))
-
-	// Regression detection
-	if current.Failed > baseline.Failed {
-		md.WriteString("\n🚨 **REGRESSION DETECTED** - More tests failing than baseline!\n\n")
-	} else if current.Failed < baseline.Failed {
-		md.WriteString("\n✅ **IMPROVEMENT** - Fewer test failures than baseline!\n\n")
-	}
-
-	if costChange > 0.001 { // Significant cost increase
-		md.WriteString(fmt.Sprintf("💸 **COST ALERT** - Cost increased by $%.4f (%.1f%%)\n\n", 
-			costChange, (costChange/baseline.TotalCost)*100))
+	md.WriteString(fmt.Sprintf("| Passed | %d | %d | %s |\n",
+		summary.Passed.Before, summary.Passed.After, formatChange(summary.Passed.Change)))
+	md.WriteString(fmt.Sprintf("| Failed | %d | %d | %s |\n",
+		summary.Failed.Before, summary.Failed.After, formatChange(summary.Failed.Change)))
+	md.WriteString(fmt.Sprintf("| Cost | $%.4f | $%.4f | %s |\n",
+		summary.Cost.Before, summary.Cost.After, d.formatCostChange(summary.Cost.Change)))
+	md.WriteString(fmt.Sprintf("| Duration | %v | %v | %s |\n",
+		summary.Duration.Before.Round(time.Millisecond), summary.Duration.After.Round(time.Millisecond),
+		d.formatLatencyChange(summary.Duration.Change)))
+
+	md.WriteString("\n")
+	for _, v := range d.verdicts(c, labelA, labelB) {
+		md.WriteString(fmt.Sprintf("%s **%s** - %s\n\n", v.Icon, v.Title, v.Detail))
+		for _, reason := range v.Reasons {
+			md.WriteString(fmt.Sprintf("- %s\n", reason))
+		}
+		if len(v.Reasons) > 0 {
+			md.WriteString("\n")
+		}
 	}
 
+	md.WriteString(d.generateTestComparison(c.Tests, labelA, labelB))
+
 	return md.String()
 }
 
+// verdict is a finding of a comparison shown under its summary
+type verdict struct {
+	Kind    string   `json:"kind"` // regression, tolerated, improvement, cost, or latency
+	Icon    string   `json:"-"`
+	Title   string   `json:"title"`
+	Detail  string   `json:"detail"`
+	Reasons []string `json:"reasons,omitempty"` // Why a regression was detected
+}
+
+// verdicts judges a comparison: a regression beyond the tolerance for
+// noise, or otherwise more or fewer failures, followed by the cost and
+// latency alerts
+func (d *MarkdownDiffer) verdicts(c *Comparison, labelA, labelB string) []verdict {
+	var verdicts []verdict
+	summary := c.Summary
+
+	if reasons := c.Regression(d.Tolerance); len(reasons) > 0 {
+		verdicts = append(verdicts, verdict{Kind: "regression", Icon: "🚨", Title: "REGRESSION DETECTED",
+			Detail: fmt.Sprintf("%s fell behind %s:", labelB, labelA), Reasons: reasons})
+	} else if summary.Failed.Change > 0 {
+		verdicts = append(verdicts, verdict{Kind: "tolerated", Icon: "⚠️", Title: "WITHIN TOLERANCE",
+			Detail: fmt.Sprintf("More tests failing in %s than %s, within the regression tolerance", labelB, labelA)})
+	} else if summary.Failed.Change < 0 {
+		verdicts = append(verdicts, verdict{Kind: "improvement", Icon: "✅", Title: "IMPROVEMENT",
+			Detail: fmt.Sprintf("Fewer test failures in %s than %s!", labelB, labelA)})
+	}
+
+	if cost := summary.Cost; cost.Change > d.costAlertThreshold() {
+		detail := fmt.Sprintf("Cost increased by $%.4f", cost.Change)
+		if cost.Before > 0 {
+			detail += fmt.Sprintf(" (%.1f%%)", (cost.Change/cost.Before)*100)
+		}
+		verdicts = append(verdicts, verdict{Kind: "cost", Icon: "💸", Title: "COST ALERT", Detail: detail})
+	}
+
+	if latency := summary.Duration; latency.Change > d.latencyAlertThreshold() {
+		verdicts = append(verdicts, verdict{Kind: "latency", Icon: "🐢", Title: "LATENCY ALERT",
+			Detail: fmt.Sprintf("Duration increased by %v", latency.Change.Round(time.Millisecond))})
+	}
+
+	return verdicts
+}
+
+// TestChange pairs the results of one test from two runs. Before or After
+// is nil when the test only ran in one of them.
+type TestChange struct {
+	Name       string
+	PromptFile string
+	Provider   string
+	Before     *runner.TestResult
+	After      *runner.TestResult
+}
+
+// CompareTests matches the tests of two runs by prompt file, name, and
+// provider, sorted in that order
+func CompareTests(before, after *runner.Results) []TestChange {
+	var keys []string
+	changes := make(map[string]*TestChange)
+	lookup := func(test *runner.TestResult) *TestChange {
+		key := test.PromptFile + "\x00" + test.Name + "\x00" + test.Provider
+		change, ok := changes[key]
+		if !ok {
+			change = &TestChange{Name: test.Name, PromptFile: test.PromptFile, Provider: test.Provider}
+			changes[key] = change
+			keys = append(keys, key)
+		}
+		return change
+	}
+
+	for i := range before.TestResults {
+		lookup(&before.TestResults[i]).Before = &before.TestResults[i]
+	}
+	for i := range after.TestResults {
+		lookup(&after.TestResults[i]).After = &after.TestResults[i]
+	}
+
+	sort.Strings(keys)
+	result := make([]TestChange, 0, len(keys))
+	for _, key := range keys {
+		result = append(result, *changes[key])
+	}
+	return result
+}
+
+// Status transitions of a test between two runs
+const (
+	TransitionNew       = "new"       // Only in the later run
+	TransitionRemoved   = "removed"   // Only in the earlier run
+	TransitionRegressed = "pass→fail" // Passed before, fails now
+	TransitionFixed     = "fail→pass" // Failed before, passes now
+	TransitionUnchanged = "unchanged"
+)
+
+// Transition classifies how the test's status changed between the runs.
+// Skipped tests count as neither passing nor failing.
+func (c TestChange) Transition() string {
+	switch {
+	case c.Before == nil:
+		return TransitionNew
+	case c.After == nil:
+		return TransitionRemoved
+	case c.Before.Status == "passed" && c.After.Status == "failed":
+		return TransitionRegressed
+	case c.Before.Status == "failed" && c.After.Status == "passed":
+		return TransitionFixed
+	default:
+		return TransitionUnchanged
+	}
+}
+
+// AssertionChange pairs the results of one assertion of a test from two
+// runs. Before or After is nil when the assertion only ran in one of them.
+type AssertionChange struct {
+	Type   string
+	Before *runner.AssertionResult
+	After  *runner.AssertionResult
+}
+
+// ScoreDelta is the change in the assertion's score, 0 unless both runs
+// scored it
+func (c AssertionChange) ScoreDelta() float64 {
+	if c.Before == nil || c.After == nil {
+		return 0
+	}
+	return c.After.Score - c.Before.Score
+}
+
+// NewlyFailed reports whether the assertion fails now but did not before
+func (c AssertionChange) NewlyFailed() bool {
+	return c.After != nil && c.After.Failed() && (c.Before == nil || !c.Before.Failed())
+}
+
+// Changed reports whether the assertion's outcome or score moved
+func (c AssertionChange) Changed() bool {
+	if c.Before == nil || c.After == nil {
+		return true
+	}
+	return c.Before.Passed != c.After.Passed || c.Before.Warning != c.After.Warning ||
+		math.Abs(c.ScoreDelta()) >= scoreChangeThreshold
+}
+
+// scoreChangeThreshold is the smallest score change reported as a change
+const scoreChangeThreshold = 0.005
+
+// Assertions matches the test's assertions across the runs by type and
+// position among the assertions of that type
+func (c TestChange) Assertions() []AssertionChange {
+	var changes []AssertionChange
+	index := make(map[string]int) // Position in changes by type and occurrence
+	add := func(results []runner.AssertionResult, before bool) {
+		seen := make(map[string]int)
+		for i := range results {
+			result := &results[i]
+			key := fmt.Sprintf("%s\x00%d", result.Type, seen[result.Type])
+			seen[result.Type]++
+
+			j, ok := index[key]
+			if !ok {
+				j = len(changes)
+				index[key] = j
+				changes = append(changes, AssertionChange{Type: result.Type})
+			}
+			if before {
+				changes[j].Before = result
+			} else {
+				changes[j].After = result
+			}
+		}
+	}
+
+	if c.Before != nil {
+		add(c.Before.Assertions, true)
+	}
+	if c.After != nil {
+		add(c.After.Assertions, false)
+	}
+	return changes
+}
+
+// generateTestComparison builds the per-test table of status transitions and
+// score and cost changes, followed by the assertions that changed
+func (d *MarkdownDiffer) generateTestComparison(changes []TestChange, labelA, labelB string) string {
+	if len(changes) == 0 {
+		return ""
+	}
+
+	var md strings.Builder
+	md.WriteString("## 🧪 Per-Test Changes\n\n")
+	md.WriteString(fmt.Sprintf("| Test | Provider | Change | Status (%s → %s) | Score (%s → %s) | Cost Change |\n", labelA, labelB, labelA, labelB))
+	md.WriteString("|------|----------|--------|--------|-------|-------------|\n")
+
+	for _, change := range changes {
+		costChange := "n/a"
+		if change.Before != nil && change.After != nil {
+			costChange = d.formatCostChange(change.After.Cost - change.Before.Cost)
+		}
+
+		md.WriteString(fmt.Sprintf("| %s | %s | %s | %s → %s | %s → %s | %s |\n",
+			change.Name, change.Provider, formatTransition(change.Transition()),
+			testStatus(change.Before), testStatus(change.After),
+			testScore(change.Before), testScore(change.After),
+			costChange))
+	}
+
+	md.WriteString("\n")
+	md.WriteString(generateAssertionComparison(changes, labelA, labelB))
+	return md.String()
+}
+
+// testAssertionChange is an assertion change of the named test
+type testAssertionChange struct {
+	Test string
+	AssertionChange
+}
+
+// changedAssertions returns the assertions whose outcome or score changed in
+// tests that ran in both runs, newly failed ones first within each test
+func changedAssertions(changes []TestChange) []testAssertionChange {
+	var changed []testAssertionChange
+	for _, change := range changes {
+		if change.Before == nil || change.After == nil {
+			continue
+		}
+
+		assertions := change.Assertions()
+		sort.SliceStable(assertions, func(i, j int) bool {
+			return assertions[i].NewlyFailed() && !assertions[j].NewlyFailed()
+		})
+		for _, assertion := range assertions {
+			if assertion.Changed() {
+				changed = append(changed, testAssertionChange{Test: change.Name, AssertionChange: assertion})
+			}
+		}
+	}
+	return changed
+}
+
+// generateAssertionComparison builds the table of changed assertions
+func generateAssertionComparison(changes []TestChange, labelA, labelB string) string {
+	changed := changedAssertions(changes)
+	if len(changed) == 0 {
+		return ""
+	}
+
+	var md strings.Builder
+	md.WriteString("## 🔬 Assertion Changes\n\n")
+	md.WriteString(fmt.Sprintf("| Test | Assertion | Status (%s → %s) | Score (%s → %s) | Score Change | Message |\n", labelA, labelB, labelA, labelB))
+	md.WriteString("|------|-----------|--------|-------|--------------|---------|\n")
+	for _, assertion := range changed {
+		marker := ""
+		if assertion.NewlyFailed() {
+			marker = "🚨 "
+		}
+		message := "-"
+		if assertion.After != nil && assertion.After.Message != "" {
+			message = strings.ReplaceAll(assertion.After.Message, "|", "\\|")
+		}
+		md.WriteString(fmt.Sprintf("| %s | %s`%s` | %s → %s | %s → %s | %s | %s |\n",
+			assertion.Test, marker, assertion.Type,
+			assertionStatus(assertion.Before), assertionStatus(assertion.After),
+			assertionScore(assertion.Before), assertionScore(assertion.After),
+			formatScoreDelta(assertion.AssertionChange), message))
+	}
+	md.WriteString("\n")
+	return md.String()
+}
+
+// formatTransition labels a test's status transition for the report
+func formatTransition(transition string) string {
+	switch transition {
+	case TransitionRegressed:
+		return "🔴 " + transition
+	case TransitionFixed:
+		return "🟢 " + transition
+	case TransitionNew:
+		return "🆕 new"
+	case TransitionRemoved:
+		return "🗑️ removed"
+	default:
+		return "➖"
+	}
+}
+
+// assertionStatus returns the outcome of an assertion, or "missing" if it
+// did not run
+func assertionStatus(result *runner.AssertionResult) string {
+	switch {
+	case result == nil:
+		return "missing"
+	case result.Passed:
+		return "✅"
+	case result.Warning:
+		return "⚠️"
+	default:
+		return "❌"
+	}
+}
+
+// assertionScore returns the score of an assertion, or "-" when unscored
+func assertionScore(result *runner.AssertionResult) string {
+	if result == nil || result.Score == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.2f", result.Score)
+}
+
+// formatScoreDelta returns the score change of an assertion scored in both runs
+func formatScoreDelta(change AssertionChange) string {
+	if change.Before == nil || change.After == nil || (change.Before.Score == 0 && change.After.Score == 0) {
+		return "n/a"
+	}
+	delta := change.ScoreDelta()
+	if delta >= scoreChangeThreshold {
+		return fmt.Sprintf("🔺 +%.2f", delta)
+	} else if delta <= -scoreChangeThreshold {
+		return fmt.Sprintf("🔽 %.2f", delta)
+	}
+	return "➖ 0.00"
+}
+
+// testStatus returns the status of a test, or "missing" if it did not run
+func testStatus(test *runner.TestResult) string {
+	if test == nil {
+		return "missing"
+	}
+	if test.Status == "passed" {
+		return "✅ passed"
+	}
+	return "❌ " + test.Status
+}
+
+// testScore returns the mean score of a test's scored assertions
+func testScore(test *runner.TestResult) string {
+	if test == nil {
+		return "-"
+	}
+	if score, ok := meanScore(test); ok {
+		return fmt.Sprintf("%.2f", score)
+	}
+	return "-"
+}
+
+// meanScore returns the mean score of a test's scored assertions, and
+// whether any were scored
+func meanScore(test *runner.TestResult) (float64, bool) {
+	total, count := 0.0, 0
+	for _, assertion := range test.Assertions {
+		if assertion.Score != 0 {
+			total += assertion.Score
+			count++
+		}
+	}
+
+	if count == 0 {
+		return 0, false
+	}
+	return total / float64(count), true
+}
+
 func formatChange(change int) string {
 	if change > 0 {
 		return fmt.Sprintf("🔺 +%d", change)
@@ -208,11 +614,51 @@ func formatChange(change int) string {
 	return "➖ 0"
 }
 
-func formatCostChange(change float64) string {
-	if change > 0.0001 {
+func (d *MarkdownDiffer) formatCostChange(change float64) string {
+	threshold := d.costChangeThreshold()
+	if change > threshold {
 		return fmt.Sprintf("🔺 +$%.4f", change)
-	} else if change < -0.0001 {
+	} else if change < -threshold {
 		return fmt.Sprintf("🔽 -$%.4f", -change)
 	}
 	return "➖ $0.0000"
 }
+
+func (d *MarkdownDiffer) formatLatencyChange(change time.Duration) string {
+	threshold := d.latencyChangeThreshold()
+	change = change.Round(time.Millisecond)
+	if change > threshold {
+		return fmt.Sprintf("🔺 +%v", change)
+	} else if change < -threshold {
+		return fmt.Sprintf("🔽 -%v", -change)
+	}
+	return "➖ 0s"
+}
+
+func (d *MarkdownDiffer) costAlertThreshold() float64 {
+	if d.CostAlertThreshold > 0 {
+		return d.CostAlertThreshold
+	}
+	return DefaultCostAlertThreshold
+}
+
+func (d *MarkdownDiffer) costChangeThreshold() float64 {
+	if d.CostChangeThreshold > 0 {
+		return d.CostChangeThreshold
+	}
+	return DefaultCostChangeThreshold
+}
+
+func (d *MarkdownDiffer) latencyAlertThreshold() time.Duration {
+	if d.LatencyAlertThreshold > 0 {
+		return d.LatencyAlertThreshold
+	}
+	return DefaultLatencyAlertThreshold
+}
+
+func (d *MarkdownDiffer) latencyChangeThreshold() time.Duration {
+	if d.LatencyChangeThreshold > 0 {
+		return d.LatencyChangeThreshold
+	}
+	return DefaultLatencyChangeThreshold
+}
@@ -0,0 +1,106 @@
+package diff
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFormatCostChangeThreshold(t *testing.T) {
+	const threshold = 0.002
+	const step = 0.000001
+
+	tests := []struct {
+		name      string
+		threshold float64
+		change    float64
+		want      string
+	}{
+		{"at threshold", threshold, threshold, "➖"},
+		{"one step below", threshold, threshold - step, "➖"},
+		{"one step above", threshold, threshold + step, "🔺"},
+		{"at negative threshold", threshold, -threshold, "➖"},
+		{"one step above negative", threshold, -threshold + step, "➖"},
+		{"one step below negative", threshold, -threshold - step, "🔽"},
+		{"default at threshold", 0, DefaultCostChangeThreshold, "➖"},
+		{"default one step above", 0, DefaultCostChangeThreshold + step, "🔺"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := &MarkdownDiffer{CostChangeThreshold: tt.threshold}
+			if got := d.formatCostChange(tt.change); !strings.HasPrefix(got, tt.want) {
+				t.Errorf("formatCostChange(%v) = %q, want prefix %q", tt.change, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatLatencyChangeThreshold(t *testing.T) {
+	const threshold = 250 * time.Millisecond
+
+	tests := []struct {
+		name      string
+		threshold time.Duration
+		change    time.Duration
+		want      string
+	}{
+		{"at threshold", threshold, threshold, "➖"},
+		{"one ms below", threshold, threshold - time.Millisecond, "➖"},
+		{"one ms above", threshold, threshold + time.Millisecond, "🔺"},
+		{"at negative threshold", threshold, -threshold, "➖"},
+		{"one ms above negative", threshold, -threshold + time.Millisecond, "➖"},
+		{"one ms below negative", threshold, -threshold - time.Millisecond, "🔽"},
+		{"default at threshold", 0, DefaultLatencyChangeThreshold, "➖"},
+		{"default one ms above", 0, DefaultLatencyChangeThreshold + time.Millisecond, "🔺"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := &MarkdownDiffer{LatencyChangeThreshold: tt.threshold}
+			if got := d.formatLatencyChange(tt.change); !strings.HasPrefix(got, tt.want) {
+				t.Errorf("formatLatencyChange(%v) = %q, want prefix %q", tt.change, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAlertThresholds(t *testing.T) {
+	const costThreshold = 0.01
+	const latencyThreshold = 2 * time.Second
+
+	tests := []struct {
+		name    string
+		cost    float64
+		latency time.Duration
+		want    []string
+	}{
+		{"at thresholds", costThreshold, latencyThreshold, nil},
+		{"one step below", costThreshold - 0.000001, latencyThreshold - time.Millisecond, nil},
+		{"cost one step above", costThreshold + 0.000001, latencyThreshold, []string{"cost"}},
+		{"latency one ms above", costThreshold, latencyThreshold + time.Millisecond, []string{"latency"}},
+		{"both above", costThreshold + 0.000001, latencyThreshold + time.Millisecond, []string{"cost", "latency"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := &MarkdownDiffer{
+				CostAlertThreshold:    costThreshold,
+				LatencyAlertThreshold: latencyThreshold,
+				Tolerance:             Tolerance{MaxCostIncrease: -1},
+			}
+			c := &Comparison{Summary: Summary{
+				Cost:     CostDelta{Before: 1, After: 1 + tt.cost, Change: tt.cost},
+				Duration: DurationDelta{Before: time.Minute, After: time.Minute + tt.latency, Change: tt.latency},
+			}}
+
+			var kinds []string
+			for _, v := range d.verdicts(c, "baseline", "current") {
+				kinds = append(kinds, v.Kind)
+			}
+			if strings.Join(kinds, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("verdicts = %v, want %v", kinds, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,284 @@
+package diff
+
+import (
+	"fmt"
+	"html"
+	"strings"
+	"time"
+
+	"github.com/sergi/go-diff/diffmatchpatch"
+	"promptguard/internal/runner"
+)
+
+// htmlStyle color-codes the diff page: inserted text green, deleted text
+// red, and verdicts by kind
+const htmlStyle = `body { font-family: -apple-system, BlinkMacSystemFont, 'Segoe UI', sans-serif; margin: 0; padding: 20px; background: #f5f5f5; color: #24292e; }
+main { max-width: 1200px; margin: 0 auto; background: white; border-radius: 8px; box-shadow: 0 2px 10px rgba(0,0,0,0.1); padding: 10px 30px 30px; }
+section + section { border-top: 2px solid #e9ecef; margin-top: 30px; }
+table { width: 100%; border-collapse: collapse; margin: 10px 0 20px; }
+th, td { padding: 8px 12px; border-bottom: 1px solid #e9ecef; text-align: left; vertical-align: top; }
+th { background: #f8f9fa; }
+code { background: #f1f3f4; padding: 1px 4px; border-radius: 3px; }
+pre { background: #f1f3f4; padding: 15px; border-radius: 4px; white-space: pre-wrap; word-break: break-word; }
+ins { background: #d4edda; color: #155724; text-decoration: none; }
+del { background: #f8d7da; color: #721c24; }
+.test { border-left: 4px solid #dc3545; padding-left: 16px; margin: 20px 0; }
+.meta { color: #666; }
+.assertion { margin: 10px 0; padding: 10px; background: #f8f9fa; border-radius: 4px; }
+.verdict { padding: 10px 15px; border-radius: 4px; margin: 10px 0; }
+.verdict.regression { background: #f8d7da; color: #721c24; }
+.verdict.tolerated, .verdict.cost, .verdict.latency { background: #fff3cd; color: #856404; }
+.verdict.improvement { background: #d4edda; color: #155724; }
+tr.newly-failed { background: #fdf0f1; }
+`
+
+// HTMLDiffer renders the failure analysis and comparisons of MarkdownDiffer
+// as HTML sections, with inline diffs as <ins> and <del> spans. The embedded
+// MarkdownDiffer supplies the thresholds, tolerance, and response length.
+type HTMLDiffer struct {
+	MarkdownDiffer
+}
+
+// HTMLPage wraps sections rendered by HTMLDiffer in a standalone page
+func HTMLPage(title string, sections ...string) string {
+	var h strings.Builder
+	h.WriteString("<!DOCTYPE html>\n<html lang=\"en\">\n<head>\n<meta charset=\"UTF-8\">\n")
+	h.WriteString("<meta name=\"viewport\" content=\"width=device-width, initial-scale=1.0\">\n")
+	h.WriteString(fmt.Sprintf("<title>%s</title>\n<style>\n%s</style>\n</head>\n<body>\n<main>\n", html.EscapeString(title), htmlStyle))
+	for _, section := range sections {
+		h.WriteString(section)
+	}
+	h.WriteString("</main>\n</body>\n</html>\n")
+	return h.String()
+}
+
+// GenerateFailureDiff creates an HTML section analyzing test failures
+func (d *HTMLDiffer) GenerateFailureDiff(results *runner.Results) string {
+	var h strings.Builder
+
+	h.WriteString("<section>\n<h1>🔍 PromptGuard Failure Analysis</h1>\n")
+
+	if results.Failed == 0 {
+		h.WriteString("<p>✅ <strong>All tests passed!</strong> No failures to analyze.</p>\n</section>\n")
+		return h.String()
+	}
+
+	h.WriteString(fmt.Sprintf("<p>❌ <strong>%d test(s) failed</strong> - Analysis below:</p>\n", results.Failed))
+
+	for _, test := range results.TestResults {
+		if test.Status == "failed" {
+			h.WriteString(d.generateTestFailureDiff(test))
+		}
+	}
+
+	h.WriteString("<h2>📊 Summary</h2>\n<ul>\n")
+	h.WriteString(fmt.Sprintf("<li><strong>Total Tests:</strong> %d</li>\n", results.Total))
+	h.WriteString(fmt.Sprintf("<li><strong>✅ Passed:</strong> %d</li>\n", results.Passed))
+	h.WriteString(fmt.Sprintf("<li><strong>❌ Failed:</strong> %d</li>\n", results.Failed))
+	h.WriteString(fmt.Sprintf("<li><strong>💰 Total Cost:</strong> $%.4f</li>\n", results.TotalCost))
+	h.WriteString("</ul>\n</section>\n")
+
+	return h.String()
+}
+
+func (d *HTMLDiffer) generateTestFailureDiff(test runner.TestResult) string {
+	var h strings.Builder
+
+	h.WriteString(fmt.Sprintf("<div class=\"test\">\n<h2>❌ <code>%s</code></h2>\n", html.EscapeString(test.Name)))
+	h.WriteString(fmt.Sprintf("<p class=\"meta\">📁 <code>%s</code> · 🤖 <code>%s</code> · 💰 $%.4f</p>\n",
+		html.EscapeString(test.PromptFile), html.EscapeString(test.Provider), test.Cost))
+
+	if test.Error != "" {
+		h.WriteString(fmt.Sprintf("<p><strong>🚨 Error:</strong></p>\n<pre>%s</pre>\n", html.EscapeString(test.Error)))
+	}
+
+	h.WriteString("<h3>🔬 Failed Assertions</h3>\n")
+	for _, assertion := range test.Assertions {
+		if assertion.Failed() {
+			h.WriteString(d.generateAssertionDiff(assertion))
+		}
+	}
+
+	h.WriteString("<h3>📄 Actual Response</h3>\n")
+	h.WriteString(fmt.Sprintf("<pre>%s</pre>\n</div>\n", html.EscapeString(runner.Preview(test.Response, d.MaxResponseChars))))
+
+	return h.String()
+}
+
+func (d *HTMLDiffer) generateAssertionDiff(assertion runner.AssertionResult) string {
+	var h strings.Builder
+
+	h.WriteString(fmt.Sprintf("<div class=\"assertion\">\n<h4>❌ <code>%s</code></h4>\n", html.EscapeString(assertion.Type)))
+	h.WriteString(fmt.Sprintf("<p><strong>Message:</strong> %s</p>\n", html.EscapeString(assertion.Message)))
+
+	expected, expectedCost := assertion.Expected.(float64)
+	actual, actualCost := assertion.Actual.(float64)
+
+	switch {
+	case assertion.Type == "answer-relevance":
+		h.WriteString(fmt.Sprintf("<p><strong>Expected Keywords/Concepts:</strong></p>\n<pre>%s</pre>\n", escapeValue(assertion.Expected)))
+		if assertion.Score > 0 {
+			h.WriteString(fmt.Sprintf("<p><strong>Relevance Score:</strong> %.2f ❌</p>\n", assertion.Score))
+		}
+
+	case assertion.Type == "contains-json":
+		h.WriteString(fmt.Sprintf("<p><strong>Expected JSON Structure:</strong></p>\n<pre>%s</pre>\n", escapeValue(assertion.Expected)))
+		h.WriteString(fmt.Sprintf("<p><strong>Actual Response:</strong></p>\n<pre>%s</pre>\n", escapeValue(assertion.Actual)))
+
+		if expectedStr, ok := assertion.Expected.(string); ok {
+			if actualStr, ok := assertion.Actual.(string); ok {
+				h.WriteString("<p><strong>Diff:</strong></p>\n")
+				h.WriteString(d.generateStringDiff(expectedStr, actualStr))
+			}
+		}
+
+	case assertion.Type == "cost" && expectedCost && actualCost:
+		h.WriteString("<table>\n<tr><th>Metric</th><th>Expected</th><th>Actual</th><th>Status</th></tr>\n")
+		h.WriteString(fmt.Sprintf("<tr><td>Cost</td><td>≤ $%.4f</td><td>$%.4f</td><td>❌ Over budget</td></tr>\n</table>\n", expected, actual))
+		if expected > 0 {
+			h.WriteString(fmt.Sprintf("<p><strong>💸 Cost overage:</strong> %.1f%% over threshold</p>\n", (actual-expected)/expected*100))
+		}
+
+	default:
+		h.WriteString(fmt.Sprintf("<p><strong>Expected:</strong> <code>%s</code><br>\n", escapeValue(assertion.Expected)))
+		h.WriteString(fmt.Sprintf("<strong>Actual:</strong> <code>%s</code></p>\n", escapeValue(assertion.Actual)))
+	}
+
+	h.WriteString("</div>\n")
+	return h.String()
+}
+
+// generateStringDiff renders the differences from expected to actual inline,
+// inserted text in <ins> and deleted text in <del>
+func (d *HTMLDiffer) generateStringDiff(expected, actual string) string {
+	var h strings.Builder
+	h.WriteString("<pre class=\"diff\">")
+	for _, diff := range stringDiffs(expected, actual) {
+		text := html.EscapeString(diff.Text)
+		switch diff.Type {
+		case diffmatchpatch.DiffInsert:
+			h.WriteString("<ins>" + text + "</ins>")
+		case diffmatchpatch.DiffDelete:
+			h.WriteString("<del>" + text + "</del>")
+		default:
+			h.WriteString(text)
+		}
+	}
+	h.WriteString("</pre>\n")
+	return h.String()
+}
+
+// GenerateBaselineComparison compares current results with baseline
+func (d *HTMLDiffer) GenerateBaselineComparison(current, baseline *runner.Results) string {
+	return d.GenerateComparison(baseline, current, "Baseline", "Current")
+}
+
+// GenerateComparison compares two result sets as an HTML section, with the
+// same summary, verdicts, and per-test and per-assertion changes as
+// MarkdownDiffer.GenerateComparison
+func (d *HTMLDiffer) GenerateComparison(a, b *runner.Results, labelA, labelB string) string {
+	comparison, err := Compare(b, a)
+	if err != nil {
+		return fmt.Sprintf("<section>\n<p>❌ %s</p>\n</section>\n", html.EscapeString(err.Error()))
+	}
+	return d.FormatComparison(comparison, labelA, labelB)
+}
+
+// FormatComparison renders a comparison as an HTML section, labeling the
+// baseline and current runs labelA and labelB
+func (d *HTMLDiffer) FormatComparison(c *Comparison, labelA, labelB string) string {
+	var h strings.Builder
+	verdicts := d.verdicts(c, labelA, labelB)
+	labelA, labelB = html.EscapeString(labelA), html.EscapeString(labelB)
+
+	h.WriteString(fmt.Sprintf("<section>\n<h1>📊 %s vs %s Comparison Report</h1>\n", labelA, labelB))
+
+	summary := c.Summary
+	h.WriteString("<h2>📈 Summary Changes</h2>\n<table>\n")
+	h.WriteString(fmt.Sprintf("<tr><th>Metric</th><th>%s</th><th>%s</th><th>Change</th></tr>\n", labelA, labelB))
+	h.WriteString(fmt.Sprintf("<tr><td>Passed</td><td>%d</td><td>%d</td><td>%s</td></tr>\n",
+		summary.Passed.Before, summary.Passed.After, formatChange(summary.Passed.Change)))
+	h.WriteString(fmt.Sprintf("<tr><td>Failed</td><td>%d</td><td>%d</td><td>%s</td></tr>\n",
+		summary.Failed.Before, summary.Failed.After, formatChange(summary.Failed.Change)))
+	h.WriteString(fmt.Sprintf("<tr><td>Cost</td><td>$%.4f</td><td>$%.4f</td><td>%s</td></tr>\n",
+		summary.Cost.Before, summary.Cost.After, d.formatCostChange(summary.Cost.Change)))
+	h.WriteString(fmt.Sprintf("<tr><td>Duration</td><td>%v</td><td>%v</td><td>%s</td></tr>\n</table>\n",
+		summary.Duration.Before.Round(time.Millisecond), summary.Duration.After.Round(time.Millisecond),
+		d.formatLatencyChange(summary.Duration.Change)))
+
+	for _, v := range verdicts {
+		h.WriteString(fmt.Sprintf("<div class=\"verdict %s\">%s <strong>%s</strong> - %s", v.Kind, v.Icon, v.Title, html.EscapeString(v.Detail)))
+		if len(v.Reasons) > 0 {
+			h.WriteString("\n<ul>\n")
+			for _, reason := range v.Reasons {
+				h.WriteString(fmt.Sprintf("<li>%s</li>\n", html.EscapeString(reason)))
+			}
+			h.WriteString("</ul>\n")
+		}
+		h.WriteString("</div>\n")
+	}
+
+	h.WriteString(d.generateTestComparison(c.Tests, labelA, labelB))
+	h.WriteString("</section>\n")
+
+	return h.String()
+}
+
+// generateTestComparison builds the per-test table of status transitions and
+// score and cost changes, followed by the assertions that changed
+func (d *HTMLDiffer) generateTestComparison(changes []TestChange, labelA, labelB string) string {
+	if len(changes) == 0 {
+		return ""
+	}
+
+	var h strings.Builder
+	h.WriteString("<h2>🧪 Per-Test Changes</h2>\n<table>\n")
+	h.WriteString(fmt.Sprintf("<tr><th>Test</th><th>Provider</th><th>Change</th><th>Status (%s → %s)</th><th>Score (%s → %s)</th><th>Cost Change</th></tr>\n",
+		labelA, labelB, labelA, labelB))
+
+	for _, change := range changes {
+		costChange := "n/a"
+		if change.Before != nil && change.After != nil {
+			costChange = d.formatCostChange(change.After.Cost - change.Before.Cost)
+		}
+
+		h.WriteString(fmt.Sprintf("<tr><td>%s</td><td>%s</td><td>%s</td><td>%s → %s</td><td>%s → %s</td><td>%s</td></tr>\n",
+			html.EscapeString(change.Name), html.EscapeString(change.Provider), formatTransition(change.Transition()),
+			html.EscapeString(testStatus(change.Before)), html.EscapeString(testStatus(change.After)),
+			testScore(change.Before), testScore(change.After),
+			costChange))
+	}
+	h.WriteString("</table>\n")
+
+	changed := changedAssertions(changes)
+	if len(changed) == 0 {
+		return h.String()
+	}
+
+	h.WriteString("<h2>🔬 Assertion Changes</h2>\n<table>\n")
+	h.WriteString(fmt.Sprintf("<tr><th>Test</th><th>Assertion</th><th>Status (%s → %s)</th><th>Score (%s → %s)</th><th>Score Change</th><th>Message</th></tr>\n",
+		labelA, labelB, labelA, labelB))
+	for _, assertion := range changed {
+		row, marker := "<tr>", ""
+		if assertion.NewlyFailed() {
+			row, marker = "<tr class=\"newly-failed\">", "🚨 "
+		}
+		message := "-"
+		if assertion.After != nil && assertion.After.Message != "" {
+			message = html.EscapeString(assertion.After.Message)
+		}
+		h.WriteString(fmt.Sprintf("%s<td>%s</td><td>%s<code>%s</code></td><td>%s → %s</td><td>%s → %s</td><td>%s</td><td>%s</td></tr>\n",
+			row, html.EscapeString(assertion.Test), marker, html.EscapeString(assertion.Type),
+			assertionStatus(assertion.Before), assertionStatus(assertion.After),
+			assertionScore(assertion.Before), assertionScore(assertion.After),
+			formatScoreDelta(assertion.AssertionChange), message))
+	}
+	h.WriteString("</table>\n")
+
+	return h.String()
+}
+
+// escapeValue formats an expected or actual value for HTML
+func escapeValue(value interface{}) string {
+	return html.EscapeString(fmt.Sprintf("%v", value))
+}
@@ -0,0 +1,121 @@
+package diff
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"promptguard/internal/runner"
+)
+
+// JSONDiffer renders failure analyses and comparisons as JSON for gating
+// scripts. The embedded MarkdownDiffer supplies the thresholds and tolerance
+// behind the verdicts.
+type JSONDiffer struct {
+	MarkdownDiffer
+}
+
+// failureAnalysis is the JSON failure analysis of a run
+type failureAnalysis struct {
+	Total     int           `json:"total"`
+	Passed    int           `json:"passed"`
+	Failed    int           `json:"failed"`
+	TotalCost float64       `json:"totalCost"`
+	Failures  []testFailure `json:"failures"`
+}
+
+// testFailure is a failed test with the assertions it failed
+type testFailure struct {
+	Name       string                   `json:"name"`
+	PromptFile string                   `json:"promptFile"`
+	Provider   string                   `json:"provider"`
+	Cost       float64                  `json:"cost"`
+	Error      string                   `json:"error,omitempty"`
+	Assertions []runner.AssertionResult `json:"assertions"`
+	Response   string                   `json:"response"`
+}
+
+// comparisonReport is a comparison with its labels and the verdicts drawn
+// from it
+type comparisonReport struct {
+	Labels struct {
+		Before string `json:"before"`
+		After  string `json:"after"`
+	} `json:"labels"`
+	Regression bool      `json:"regression"` // Whether a verdict is a regression beyond the tolerance
+	Verdicts   []verdict `json:"verdicts"`
+	*Comparison
+}
+
+// GenerateFailureDiff creates a JSON analysis of the failed tests
+func (d *JSONDiffer) GenerateFailureDiff(results *runner.Results) string {
+	analysis := failureAnalysis{
+		Total:     results.Total,
+		Passed:    results.Passed,
+		Failed:    results.Failed,
+		TotalCost: results.TotalCost,
+		Failures:  []testFailure{},
+	}
+
+	for _, test := range results.TestResults {
+		if test.Status != "failed" {
+			continue
+		}
+		failure := testFailure{
+			Name:       test.Name,
+			PromptFile: test.PromptFile,
+			Provider:   test.Provider,
+			Cost:       test.Cost,
+			Error:      test.Error,
+			Assertions: []runner.AssertionResult{},
+			Response:   runner.Preview(test.Response, d.MaxResponseChars),
+		}
+		for _, assertion := range test.Assertions {
+			if assertion.Failed() {
+				failure.Assertions = append(failure.Assertions, assertion)
+			}
+		}
+		analysis.Failures = append(analysis.Failures, failure)
+	}
+
+	return marshalJSON(analysis)
+}
+
+// GenerateBaselineComparison compares current results with baseline
+func (d *JSONDiffer) GenerateBaselineComparison(current, baseline *runner.Results) string {
+	return d.GenerateComparison(baseline, current, "Baseline", "Current")
+}
+
+// GenerateComparison compares two result sets as JSON, reporting changes
+// from a to b
+func (d *JSONDiffer) GenerateComparison(a, b *runner.Results, labelA, labelB string) string {
+	comparison, err := Compare(b, a)
+	if err != nil {
+		return marshalJSON(map[string]string{"error": err.Error()})
+	}
+	return d.FormatComparison(comparison, labelA, labelB)
+}
+
+// FormatComparison renders a comparison as JSON with the verdicts of the
+// differ's thresholds and tolerance
+func (d *JSONDiffer) FormatComparison(c *Comparison, labelA, labelB string) string {
+	report := comparisonReport{Verdicts: d.verdicts(c, labelA, labelB), Comparison: c}
+	report.Labels.Before, report.Labels.After = labelA, labelB
+	if report.Verdicts == nil {
+		report.Verdicts = []verdict{}
+	}
+	for _, v := range report.Verdicts {
+		if v.Kind == "regression" {
+			report.Regression = true
+		}
+	}
+	return marshalJSON(report)
+}
+
+// marshalJSON encodes v as indented JSON; the differ's own types always encode
+func marshalJSON(v interface{}) string {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Sprintf("{\"error\": %q}", err.Error())
+	}
+	return string(data)
+}
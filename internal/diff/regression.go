@@ -0,0 +1,79 @@
+package diff
+
+import (
+	"fmt"
+	"strings"
+
+	"promptguard/internal/runner"
+)
+
+// DefaultMaxCostIncrease is the cost increase over the baseline, in
+// percent, tolerated when no tolerance is set
+const DefaultMaxCostIncrease = 10.0
+
+// Tolerance is how far a run may fall behind its baseline before it counts
+// as a regression, so that noise from nondeterministic models does not
+// block merges
+type Tolerance struct {
+	// MaxCostIncrease is the total cost increase tolerated, in percent of
+	// the baseline cost; 0 uses DefaultMaxCostIncrease, negative never
+	// counts a cost increase as a regression
+	MaxCostIncrease float64
+	// MaxNewFailures is the number of failing tests tolerated that passed
+	// in the baseline or are new
+	MaxNewFailures int
+}
+
+// newFailures returns the tests that fail in the current run but passed, or
+// did not run, in the baseline
+func newFailures(changes []TestChange) []TestChange {
+	var failing []TestChange
+	for _, change := range changes {
+		if change.After == nil || change.After.Status != "failed" {
+			continue
+		}
+		if change.Before == nil || change.Before.Status != "failed" {
+			failing = append(failing, change)
+		}
+	}
+	return failing
+}
+
+// DetectRegression compares a run with its baseline and returns why it
+// regressed beyond the tolerance, or nothing when it did not
+func DetectRegression(baseline, current *runner.Results, tolerance Tolerance) []string {
+	comparison, err := Compare(current, baseline)
+	if err != nil {
+		return nil
+	}
+	return comparison.Regression(tolerance)
+}
+
+// Regression returns why the current run regressed beyond the tolerance, or
+// nothing when it did not
+func (c *Comparison) Regression(tolerance Tolerance) []string {
+	var reasons []string
+
+	if failing := newFailures(c.Tests); len(failing) > tolerance.MaxNewFailures {
+		names := make([]string, 0, len(failing))
+		for _, change := range failing {
+			names = append(names, change.Name)
+		}
+		reasons = append(reasons, fmt.Sprintf("%d tests newly failing (tolerance %d): %s",
+			len(failing), tolerance.MaxNewFailures, strings.Join(names, ", ")))
+	}
+
+	maxIncrease := tolerance.MaxCostIncrease
+	if maxIncrease == 0 {
+		maxIncrease = DefaultMaxCostIncrease
+	}
+	if cost := c.Summary.Cost; maxIncrease > 0 && cost.Before > 0 {
+		increase := cost.Change / cost.Before * 100
+		if increase > maxIncrease {
+			reasons = append(reasons, fmt.Sprintf("cost increased %.1f%% ($%.4f → $%.4f, tolerance %.1f%%)",
+				increase, cost.Before, cost.After, maxIncrease))
+		}
+	}
+
+	return reasons
+}
@@ -0,0 +1,126 @@
+package github
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"promptguard/internal/runner"
+)
+
+// undefinedVariableRegex extracts the variable of a prompt rendering error
+var undefinedVariableRegex = regexp.MustCompile(`undefined variable "([^"]+)"`)
+
+// frontmatterProviderRegex matches the provider line of prompt frontmatter
+var frontmatterProviderRegex = regexp.MustCompile(`^provider:\s`)
+
+// annotation is a workflow command placing messages on a line of a file
+type annotation struct {
+	file     string
+	line     int
+	messages []string
+}
+
+// GenerateAnnotations creates GitHub workflow annotations for test failures,
+// placed in the prompt file of each failed test so they show inline in the
+// pull request diff. Failures on the same line share one annotation.
+func GenerateAnnotations(results *runner.Results) error {
+	if !isGitHubActions() {
+		return nil // Skip if not running in GitHub Actions
+	}
+	return writeAnnotations(os.Stdout, results)
+}
+
+// writeAnnotations writes one ::error workflow command per prompt file line
+// with failures
+func writeAnnotations(w io.Writer, results *runner.Results) error {
+	lines := make(map[string][]string) // Prompt file contents, read once
+	grouped := make(map[string]*annotation)
+	var annotations []*annotation
+
+	for _, test := range results.TestResults {
+		if test.Status != "failed" {
+			continue
+		}
+
+		file := filepath.ToSlash(test.PromptFile)
+		if _, ok := lines[file]; !ok {
+			content, _ := os.ReadFile(test.PromptFile)
+			lines[file] = strings.Split(string(content), "\n")
+		}
+		line := failureLine(lines[file], test)
+
+		key := fmt.Sprintf("%s:%d", file, line)
+		group, ok := grouped[key]
+		if !ok {
+			group = &annotation{file: file, line: line}
+			grouped[key] = group
+			annotations = append(annotations, group)
+		}
+		group.messages = append(group.messages, fmt.Sprintf("%s (%s): %s", test.Name, test.Provider, buildFailureMessage(test)))
+	}
+
+	sort.Slice(annotations, func(i, j int) bool {
+		if annotations[i].file != annotations[j].file {
+			return annotations[i].file < annotations[j].file
+		}
+		return annotations[i].line < annotations[j].line
+	})
+	for _, group := range annotations {
+		title := "PromptGuard test failure"
+		if len(group.messages) > 1 {
+			title = fmt.Sprintf("PromptGuard: %d test failures", len(group.messages))
+		}
+		if _, err := fmt.Fprintf(w, "::error file=%s,line=%d,title=%s::%s\n",
+			escapeProperty(group.file), group.line, escapeProperty(title),
+			escapeData(strings.Join(group.messages, "\n"))); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// failureLine is the prompt file line a failed test is traced to: the first
+// use of an undefined variable, the frontmatter provider line for provider
+// errors, and line 1 otherwise
+func failureLine(lines []string, test runner.TestResult) int {
+	if matches := undefinedVariableRegex.FindStringSubmatch(test.Error); matches != nil {
+		variable := regexp.MustCompile(`\.` + regexp.QuoteMeta(matches[1]) + `\b`)
+		for i, line := range lines {
+			if variable.MatchString(line) {
+				return i + 1
+			}
+		}
+	}
+
+	if strings.HasPrefix(test.Error, "Provider not found") || strings.HasPrefix(test.Error, "Failed to create provider client") {
+		if len(lines) > 0 && strings.TrimSpace(lines[0]) == "---" {
+			for i := 1; i < len(lines) && strings.TrimSpace(lines[i]) != "---"; i++ {
+				if frontmatterProviderRegex.MatchString(lines[i]) {
+					return i + 1
+				}
+			}
+		}
+	}
+
+	return 1
+}
+
+// escapeData escapes an annotation message so the workflow command stays on
+// a single line; GitHub shows %0A as a line break
+func escapeData(s string) string {
+	s = strings.ReplaceAll(s, "%", "%25")
+	s = strings.ReplaceAll(s, "\r", "%0D")
+	return strings.ReplaceAll(s, "\n", "%0A")
+}
+
+// escapeProperty escapes an annotation property such as the file or title
+func escapeProperty(s string) string {
+	s = escapeData(s)
+	s = strings.ReplaceAll(s, ":", "%3A")
+	return strings.ReplaceAll(s, ",", "%2C")
+}
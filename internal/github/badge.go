@@ -0,0 +1,145 @@
+package github
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"promptguard/internal/runner"
+)
+
+// badgeLabel is the left-hand text of the badge
+const badgeLabel = "PromptGuard"
+
+// Badge is a shields.io endpoint badge, served from badge.json through
+// https://img.shields.io/endpoint?url=<badge.json URL>
+type Badge struct {
+	SchemaVersion int    `json:"schemaVersion"`
+	Label         string `json:"label"`
+	Message       string `json:"message"`
+	Color         string `json:"color"`
+}
+
+// badgeColors are the colors of the pass rates from which they apply,
+// highest first
+var badgeColors = []struct {
+	minRate float64
+	color   string
+	hex     string
+}{
+	{1, "brightgreen", "#4c1"},
+	{0.9, "green", "#97ca00"},
+	{0.75, "yellowgreen", "#a4a61d"},
+	{0.5, "yellow", "#dfb317"},
+	{0.25, "orange", "#fe7d37"},
+	{0, "red", "#e05d44"},
+}
+
+// NewBadge builds the badge of a run, e.g. "12 passed, 2 failed", colored by
+// the share of run tests that passed
+func NewBadge(results *runner.Results) Badge {
+	badge := Badge{SchemaVersion: 1, Label: badgeLabel, Message: "no tests", Color: "lightgrey"}
+
+	run := results.Passed + results.Failed
+	if run == 0 {
+		return badge
+	}
+
+	badge.Message = fmt.Sprintf("%d passed, %d failed", results.Passed, results.Failed)
+	rate := float64(results.Passed) / float64(run)
+	for _, c := range badgeColors {
+		if rate >= c.minRate {
+			badge.Color = c.color
+			break
+		}
+	}
+	return badge
+}
+
+// UpdateBadge writes the run's badge to badge.json in dir, and to badge.svg
+// as well with svg set. In GitHub Actions it also sets the badge_url step
+// output to the equivalent static shields.io badge.
+func UpdateBadge(results *runner.Results, dir string, svg bool) error {
+	badge := NewBadge(results)
+
+	data, err := json.MarshalIndent(badge, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal badge: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "badge.json"), append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write badge: %w", err)
+	}
+
+	if svg {
+		if err := os.WriteFile(filepath.Join(dir, "badge.svg"), []byte(badge.SVG()), 0644); err != nil {
+			return fmt.Errorf("failed to write badge: %w", err)
+		}
+	}
+
+	if !isGitHubActions() {
+		return nil
+	}
+
+	badgeURL := fmt.Sprintf("https://img.shields.io/badge/%s-%s-%s",
+		shieldsEscape(badge.Label), shieldsEscape(badge.Message), badge.Color)
+	fmt.Printf("PROMPTGUARD_BADGE_URL=%s\n", badgeURL)
+
+	if outputFile := os.Getenv("GITHUB_OUTPUT"); outputFile != "" {
+		file, err := os.OpenFile(outputFile, os.O_APPEND|os.O_WRONLY, 0644)
+		if err == nil {
+			defer file.Close()
+			file.WriteString(fmt.Sprintf("badge_url=%s\n", badgeURL))
+		}
+	}
+
+	return nil
+}
+
+// shieldsEscape escapes text for a static shields.io badge path, where
+// dashes and underscores are doubled
+func shieldsEscape(text string) string {
+	text = strings.ReplaceAll(text, "-", "--")
+	text = strings.ReplaceAll(text, "_", "__")
+	return url.PathEscape(text)
+}
+
+// SVG renders the badge in the shields.io flat style. Text widths are
+// estimated from the character count.
+func (b Badge) SVG() string {
+	labelWidth := textWidth(b.Label)
+	messageWidth := textWidth(b.Message)
+	width := labelWidth + messageWidth
+
+	color := "#9f9f9f"
+	for _, c := range badgeColors {
+		if c.color == b.Color {
+			color = c.hex
+		}
+	}
+
+	label, message := html.EscapeString(b.Label), html.EscapeString(b.Message)
+	return fmt.Sprintf(`<svg xmlns="http://www.w3.org/2000/svg" width="%[1]d" height="20" role="img" aria-label="%[4]s: %[5]s">
+  <title>%[4]s: %[5]s</title>
+  <linearGradient id="s" x2="0" y2="100%%"><stop offset="0" stop-color="#bbb" stop-opacity=".1"/><stop offset="1" stop-opacity=".1"/></linearGradient>
+  <clipPath id="r"><rect width="%[1]d" height="20" rx="3" fill="#fff"/></clipPath>
+  <g clip-path="url(#r)">
+    <rect width="%[2]d" height="20" fill="#555"/>
+    <rect x="%[2]d" width="%[3]d" height="20" fill="%[6]s"/>
+    <rect width="%[1]d" height="20" fill="url(#s)"/>
+  </g>
+  <g fill="#fff" text-anchor="middle" font-family="Verdana,Geneva,DejaVu Sans,sans-serif" font-size="11">
+    <text x="%[7]d" y="14">%[4]s</text>
+    <text x="%[8]d" y="14">%[5]s</text>
+  </g>
+</svg>
+`, width, labelWidth, messageWidth, label, message, color, labelWidth/2, labelWidth+messageWidth/2)
+}
+
+// textWidth estimates the width in pixels of badge text with padding
+func textWidth(text string) int {
+	return len([]rune(text))*7 + 10
+}
@@ -0,0 +1,161 @@
+package github
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// commentMarker is the hidden line that identifies PromptGuard's comment,
+// so later runs update it instead of adding another
+const commentMarker = "<!-- promptguard-report -->"
+
+// maxCommentChars is GitHub's limit on the size of a comment body
+const maxCommentChars = 65536
+
+// apiTimeout bounds each GitHub API request
+const apiTimeout = 15 * time.Second
+
+// pullRefRegex matches the ref of a pull_request workflow run
+var pullRefRegex = regexp.MustCompile(`^refs/pull/(\d+)/`)
+
+// issueComment is a pull request comment as returned by the GitHub API
+type issueComment struct {
+	ID   int64  `json:"id"`
+	Body string `json:"body"`
+}
+
+// CommentOnPR posts report as a comment on the pull request, replacing the
+// comment an earlier run posted. It uses GITHUB_TOKEN and GITHUB_REPOSITORY,
+// and GITHUB_REF when prNumber is empty. It returns false without posting
+// when not running on a pull request.
+func CommentOnPR(prNumber, report string) (bool, error) {
+	if prNumber == "" {
+		if matches := pullRefRegex.FindStringSubmatch(os.Getenv("GITHUB_REF")); matches != nil {
+			prNumber = matches[1]
+		}
+	}
+	token, repo := os.Getenv("GITHUB_TOKEN"), os.Getenv("GITHUB_REPOSITORY")
+	if prNumber == "" || token == "" || repo == "" {
+		return false, nil
+	}
+
+	client := &apiClient{
+		baseURL: apiURL(),
+		token:   token,
+		http:    &http.Client{Timeout: apiTimeout},
+	}
+	commentsPath := fmt.Sprintf("/repos/%s/issues/%s/comments", repo, prNumber)
+
+	body := commentBody(report)
+	existing, err := client.findComment(commentsPath)
+	if err != nil {
+		return false, err
+	}
+	if existing != 0 {
+		err = client.send(http.MethodPatch, fmt.Sprintf("/repos/%s/issues/comments/%d", repo, existing), body, nil)
+	} else {
+		err = client.send(http.MethodPost, commentsPath, body, nil)
+	}
+	return err == nil, err
+}
+
+// commentBody prefixes the report with the marker, truncating it to fit
+// GitHub's comment size limit
+func commentBody(report string) string {
+	const truncated = "\n\n*Report truncated; see the report.md artifact for the rest.*"
+
+	body := commentMarker + "\n" + report
+	if len(body) > maxCommentChars {
+		cut := maxCommentChars - len(truncated)
+		for cut > 0 && !utf8.RuneStart(body[cut]) {
+			cut--
+		}
+		body = body[:cut] + truncated
+	}
+	return body
+}
+
+// apiURL returns the GitHub REST API base URL, which differs on GitHub
+// Enterprise Server
+func apiURL() string {
+	if url := os.Getenv("GITHUB_API_URL"); url != "" {
+		return strings.TrimRight(url, "/")
+	}
+	return "https://api.github.com"
+}
+
+// apiClient makes authenticated GitHub REST API requests
+type apiClient struct {
+	baseURL string
+	token   string
+	http    *http.Client
+}
+
+// findComment returns the ID of the pull request comment carrying the
+// marker, or 0 when there is none. Comments are listed a page at a time.
+func (c *apiClient) findComment(commentsPath string) (int64, error) {
+	for page := 1; ; page++ {
+		var comments []issueComment
+		path := fmt.Sprintf("%s?per_page=100&page=%d", commentsPath, page)
+		if err := c.send(http.MethodGet, path, "", &comments); err != nil {
+			return 0, err
+		}
+		for _, comment := range comments {
+			if strings.HasPrefix(comment.Body, commentMarker) {
+				return comment.ID, nil
+			}
+		}
+		if len(comments) < 100 {
+			return 0, nil
+		}
+	}
+}
+
+// send makes an API request with an optional comment body, decoding the
+// response into out when it is not nil
+func (c *apiClient) send(method, path, body string, out interface{}) error {
+	var payload io.Reader
+	if body != "" {
+		data, err := json.Marshal(map[string]string{"body": body})
+		if err != nil {
+			return fmt.Errorf("failed to marshal comment: %w", err)
+		}
+		payload = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(method, c.baseURL+path, payload)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("GitHub API request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("GitHub API returned status %d for %s %s: %s", resp.StatusCode, method, path, strings.TrimSpace(string(detail)))
+	}
+
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			return fmt.Errorf("failed to decode GitHub API response: %w", err)
+		}
+	}
+	return nil
+}
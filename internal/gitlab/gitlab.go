@@ -0,0 +1,230 @@
+// Package gitlab reports PromptGuard results to GitLab CI: a Code Quality
+// report of failed assertions and a merge request note with the failure
+// report.
+package gitlab
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"promptguard/internal/runner"
+)
+
+// IsGitLabCI reports whether running in a GitLab CI job
+func IsGitLabCI() bool {
+	return os.Getenv("GITLAB_CI") == "true"
+}
+
+// Issue is an entry of a GitLab Code Quality report
+type Issue struct {
+	Description string   `json:"description"`
+	CheckName   string   `json:"check_name"`
+	Fingerprint string   `json:"fingerprint"`
+	Severity    string   `json:"severity"` // info, minor, major, critical, or blocker
+	Location    Location `json:"location"`
+}
+
+// Location is the file and line an issue is reported on
+type Location struct {
+	Path  string `json:"path"`
+	Lines struct {
+		Begin int `json:"begin"`
+	} `json:"lines"`
+}
+
+// CodeQuality converts failed tests to Code Quality issues located in their
+// prompt files. Tests that failed to run are critical, failed assertions
+// major, and failed optional assertions minor.
+func CodeQuality(results *runner.Results) []Issue {
+	issues := []Issue{}
+	add := func(test runner.TestResult, check, severity, message string) {
+		location := Location{Path: filepath.ToSlash(test.PromptFile)}
+		location.Lines.Begin = 1
+
+		sum := md5.Sum([]byte(strings.Join([]string{test.PromptFile, test.Name, test.Provider, check, message}, "\x00")))
+		issues = append(issues, Issue{
+			Description: fmt.Sprintf("%s (%s): %s", test.Name, test.Provider, message),
+			CheckName:   "promptguard/" + check,
+			Fingerprint: hex.EncodeToString(sum[:]),
+			Severity:    severity,
+			Location:    location,
+		})
+	}
+
+	for _, test := range results.TestResults {
+		if test.Status == "failed" && test.Error != "" {
+			add(test, "execution-error", "critical", test.Error)
+			continue
+		}
+		for _, assertion := range test.Assertions {
+			switch {
+			case assertion.Warning:
+				add(test, assertion.Type, "minor", assertion.Message)
+			case assertion.Failed() && test.Status == "failed":
+				add(test, assertion.Type, "major", assertion.Message)
+			}
+		}
+	}
+	return issues
+}
+
+// WriteCodeQuality writes the Code Quality report of a run to path, for the
+// job's artifacts:reports:codequality
+func WriteCodeQuality(results *runner.Results, path string) error {
+	data, err := json.MarshalIndent(CodeQuality(results), "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal Code Quality report: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write Code Quality report: %w", err)
+	}
+	return nil
+}
+
+// noteMarker is the hidden line that identifies PromptGuard's note, so later
+// runs update it instead of adding another
+const noteMarker = "<!-- promptguard-report -->"
+
+// maxNoteChars is GitLab's limit on the size of a note
+const maxNoteChars = 1000000
+
+// apiTimeout bounds each GitLab API request
+const apiTimeout = 15 * time.Second
+
+// note is a merge request note as returned by the GitLab API
+type note struct {
+	ID   int64  `json:"id"`
+	Body string `json:"body"`
+}
+
+// CommentOnMR posts report as a note on the merge request, replacing the
+// note an earlier run posted. The merge request is mrIID, or
+// CI_MERGE_REQUEST_IID when empty, in project CI_PROJECT_ID at CI_API_V4_URL.
+// It authenticates with GITLAB_TOKEN, or CI_JOB_TOKEN on instances that let
+// job tokens write notes. It returns false without posting when not running
+// for a merge request.
+func CommentOnMR(mrIID, report string) (bool, error) {
+	if mrIID == "" {
+		mrIID = os.Getenv("CI_MERGE_REQUEST_IID")
+	}
+	apiURL, project := os.Getenv("CI_API_V4_URL"), os.Getenv("CI_PROJECT_ID")
+
+	client := &apiClient{baseURL: strings.TrimRight(apiURL, "/"), http: &http.Client{Timeout: apiTimeout}}
+	if token := os.Getenv("GITLAB_TOKEN"); token != "" {
+		client.header, client.token = "PRIVATE-TOKEN", token
+	} else if token := os.Getenv("CI_JOB_TOKEN"); token != "" {
+		client.header, client.token = "JOB-TOKEN", token
+	}
+	if mrIID == "" || apiURL == "" || project == "" || client.token == "" {
+		return false, nil
+	}
+
+	notesPath := fmt.Sprintf("/projects/%s/merge_requests/%s/notes", url.PathEscape(project), mrIID)
+	body := noteBody(report)
+
+	existing, err := client.findNote(notesPath)
+	if err != nil {
+		return false, err
+	}
+	if existing != 0 {
+		err = client.send(http.MethodPut, fmt.Sprintf("%s/%d", notesPath, existing), body, nil)
+	} else {
+		err = client.send(http.MethodPost, notesPath, body, nil)
+	}
+	return err == nil, err
+}
+
+// noteBody prefixes the report with the marker, truncating it to fit
+// GitLab's note size limit
+func noteBody(report string) string {
+	const truncated = "\n\n*Report truncated; see the report.md artifact for the rest.*"
+
+	body := noteMarker + "\n" + report
+	if len(body) > maxNoteChars {
+		cut := maxNoteChars - len(truncated)
+		for cut > 0 && !utf8.RuneStart(body[cut]) {
+			cut--
+		}
+		body = body[:cut] + truncated
+	}
+	return body
+}
+
+// apiClient makes authenticated GitLab REST API requests
+type apiClient struct {
+	baseURL string
+	header  string // PRIVATE-TOKEN or JOB-TOKEN
+	token   string
+	http    *http.Client
+}
+
+// findNote returns the ID of the merge request note carrying the marker, or
+// 0 when there is none. Notes are listed a page at a time.
+func (c *apiClient) findNote(notesPath string) (int64, error) {
+	for page := 1; ; page++ {
+		var notes []note
+		path := fmt.Sprintf("%s?per_page=100&page=%d", notesPath, page)
+		if err := c.send(http.MethodGet, path, "", &notes); err != nil {
+			return 0, err
+		}
+		for _, n := range notes {
+			if strings.HasPrefix(n.Body, noteMarker) {
+				return n.ID, nil
+			}
+		}
+		if len(notes) < 100 {
+			return 0, nil
+		}
+	}
+}
+
+// send makes an API request with an optional note body, decoding the
+// response into out when it is not nil
+func (c *apiClient) send(method, path, body string, out interface{}) error {
+	var payload io.Reader
+	if body != "" {
+		data, err := json.Marshal(map[string]string{"body": body})
+		if err != nil {
+			return fmt.Errorf("failed to marshal note: %w", err)
+		}
+		payload = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(method, c.baseURL+path, payload)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set(c.header, c.token)
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("GitLab API request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("GitLab API returned status %d for %s %s: %s", resp.StatusCode, method, path, strings.TrimSpace(string(detail)))
+	}
+
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			return fmt.Errorf("failed to decode GitLab API response: %w", err)
+		}
+	}
+	return nil
+}
@@ -3,45 +3,130 @@ package metrics
 import (
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"github.com/mattn/go-sqlite3"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"promptguard/internal/runner"
+	"promptguard/internal/warn"
+	"sort"
+	"sync"
+	"syscall"
 	"time"
-	_ "github.com/mattn/go-sqlite3"
-	"promptgaurd/internal/runner"
 )
 
-// Store handles metrics storage and retrieval
+// Store handles metrics storage and retrieval. A Store keeps a single
+// database connection pool and is safe for concurrent use.
 type Store struct {
-	db *sql.DB
+	// MaxRuns caps the number of stored runs; once exceeded, Store prunes the
+	// oldest runs. Zero keeps every run.
+	MaxRuns int
+
+	path     string
+	mu       sync.Mutex
+	db       *sql.DB
+	disabled bool // Set once the database turned out not to be writable
 }
 
-// NewStore creates a new metrics store
-func NewStore() *Store {
-	return &Store{}
+// DefaultPath is where the metrics database is kept unless configured
+// otherwise
+const DefaultPath = ".promptguard/metrics.db"
+
+// MemoryPath keeps the metrics database in memory for the life of the Store
+const MemoryPath = ":memory:"
+
+// TestHistoryPoint is the outcome of a single test in one stored run
+type TestHistoryPoint struct {
+	RunID      string        `json:"runId"`
+	Timestamp  time.Time     `json:"timestamp"`
+	CommitSHA  string        `json:"commitSha,omitempty"`
+	PromptFile string        `json:"promptFile"`
+	Provider   string        `json:"provider"`
+	Status     string        `json:"status"`
+	Cost       float64       `json:"cost"`
+	Duration   time.Duration `json:"duration"`
+	Error      string        `json:"error,omitempty"`
 }
 
-// Store saves test results to the metrics database
+// ErrRunNotFound is returned by GetRun when no run has the given ID
+var ErrRunNotFound = errors.New("run not found")
+
+// ErrDisabled is returned once the metrics database has turned out not to be
+// writable, such as on a read-only filesystem. Store and FlakyTests treat it
+// as having nothing to do, so that a run goes on without metrics.
+var ErrDisabled = errors.New("metrics are disabled for this run")
+
+// busyTimeout is how long SQLite waits for a lock held by another
+// connection or process before failing with "database is locked"
+const busyTimeout = 5 * time.Second
+
+// NewStore creates a metrics store backed by the database at path, which is
+// DefaultPath when empty or MemoryPath for a database that is never written
+// to disk
+func NewStore(path string) *Store {
+	if path == "" {
+		path = DefaultPath
+	}
+	return &Store{path: path}
+}
+
+// Store saves test results to the metrics database. It does nothing when
+// metrics are disabled because the database is not writable.
 func (s *Store) Store(results *runner.Results) error {
 	db, err := s.getDB()
+	if errors.Is(err, ErrDisabled) {
+		return nil
+	}
 	if err != nil {
 		return fmt.Errorf("failed to open database: %w", err)
 	}
 
+	// An existing database may open on a read-only filesystem and only fail
+	// on the first write
+	if err := s.insertRun(db, results); err != nil {
+		if isReadOnly(err) {
+			s.disable(err)
+			return nil
+		}
+		return err
+	}
+
+	if s.MaxRuns > 0 {
+		return s.enforceMaxRuns(db)
+	}
+
+	return nil
+}
+
+// insertRun records a run and its tests
+func (s *Store) insertRun(db *sql.DB, results *runner.Results) error {
 	// Serialize results as JSON
 	resultsJSON, err := json.Marshal(results)
 	if err != nil {
 		return fmt.Errorf("failed to serialize results: %w", err)
 	}
 
+	// Record the run and its tests together so history never sees a run
+	// without its tests
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	timestamp := time.Now().Unix()
+
 	// Insert into database
 	query := `
-		INSERT INTO test_runs (timestamp, commit_sha, pr_number, total_tests, passed, failed, total_cost, duration, results_json)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		INSERT INTO test_runs (run_id, timestamp, commit_sha, pr_number, total_tests, passed, failed, total_cost, duration, results_json)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
 
-	_, err = db.Exec(query,
-		time.Now().Unix(),
+	_, err = tx.Exec(query,
+		results.Metadata.RunID,
+		timestamp,
 		results.Metadata.CommitSHA,
 		results.Metadata.PRNumber,
 		results.Total,
@@ -56,23 +141,159 @@ func (s *Store) Store(results *runner.Results) error {
 		return fmt.Errorf("failed to insert test run: %w", err)
 	}
 
+	stmt, err := tx.Prepare(`
+		INSERT INTO test_results (run_id, timestamp, commit_sha, name, prompt_file, provider, status, cost, duration, error)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare test result insert: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, test := range results.TestResults {
+		_, err := stmt.Exec(
+			results.Metadata.RunID,
+			timestamp,
+			results.Metadata.CommitSHA,
+			test.Name,
+			test.PromptFile,
+			test.Provider,
+			test.Status,
+			test.Cost,
+			test.Duration.Milliseconds(),
+			test.Error,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to insert result of %s: %w", test.Name, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit test run: %w", err)
+	}
+
 	return nil
 }
 
-// GetHistory retrieves historical test results
-func (s *Store) GetHistory(limit int) ([]runner.Results, error) {
+// enforceMaxRuns prunes the oldest runs beyond MaxRuns and reclaims the space
+// once enough of the file is unused
+func (s *Store) enforceMaxRuns(db *sql.DB) error {
+	var count int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM test_runs`).Scan(&count); err != nil {
+		return fmt.Errorf("failed to count test runs: %w", err)
+	}
+	if count <= s.MaxRuns {
+		return nil
+	}
+
+	if _, err := s.Prune(s.MaxRuns); err != nil {
+		return err
+	}
+
+	// Pruning one run per Store leaves small gaps; only vacuum once a
+	// quarter of the pages are free
+	var pages, freePages int
+	if err := db.QueryRow(`PRAGMA page_count`).Scan(&pages); err != nil {
+		return fmt.Errorf("failed to read page count: %w", err)
+	}
+	if err := db.QueryRow(`PRAGMA freelist_count`).Scan(&freePages); err != nil {
+		return fmt.Errorf("failed to read free page count: %w", err)
+	}
+	if freePages*4 < pages {
+		return nil
+	}
+
+	return s.Vacuum()
+}
+
+// Prune deletes all but the newest maxRuns runs with their test results and
+// returns the number of runs deleted
+func (s *Store) Prune(maxRuns int) (int64, error) {
+	return s.deleteRuns(`id NOT IN (SELECT id FROM test_runs ORDER BY timestamp DESC, id DESC LIMIT ?)`, maxRuns)
+}
+
+// PruneOlderThan deletes the runs stored more than age ago with their test
+// results and returns the number of runs deleted
+func (s *Store) PruneOlderThan(age time.Duration) (int64, error) {
+	return s.deleteRuns(`timestamp < ?`, time.Now().Add(-age).Unix())
+}
+
+// deleteRuns deletes the runs matching where, and the test results of run
+// IDs no remaining run shares
+func (s *Store) deleteRuns(where string, arg interface{}) (int64, error) {
 	db, err := s.getDB()
 	if err != nil {
-		return nil, fmt.Errorf("failed to open database: %w", err)
+		return 0, fmt.Errorf("failed to open database: %w", err)
 	}
 
-	query := `
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(fmt.Sprintf(`
+		DELETE FROM test_results
+		WHERE run_id IN (SELECT run_id FROM test_runs WHERE %[1]s)
+		AND run_id NOT IN (SELECT run_id FROM test_runs WHERE NOT (%[1]s) AND run_id IS NOT NULL)
+	`, where), arg, arg)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete test results: %w", err)
+	}
+
+	res, err := tx.Exec(fmt.Sprintf(`DELETE FROM test_runs WHERE %s`, where), arg)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete test runs: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit pruning: %w", err)
+	}
+
+	return res.RowsAffected()
+}
+
+// Vacuum rebuilds the database file to reclaim the space of deleted rows
+func (s *Store) Vacuum() error {
+	db, err := s.getDB()
+	if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+
+	if _, err := db.Exec(`VACUUM`); err != nil {
+		return fmt.Errorf("failed to vacuum database: %w", err)
+	}
+	return nil
+}
+
+// GetHistory retrieves historical test results
+func (s *Store) GetHistory(limit int) ([]runner.Results, error) {
+	return s.queryRuns(`
 		SELECT results_json FROM test_runs 
-		ORDER BY timestamp DESC 
+		ORDER BY timestamp DESC, id DESC
 		LIMIT ?
-	`
+	`, limit)
+}
+
+// GetCommitHistory retrieves the results of runs whose commit SHA starts with
+// commitSHA, newest first
+func (s *Store) GetCommitHistory(commitSHA string, limit int) ([]runner.Results, error) {
+	return s.queryRuns(`
+		SELECT results_json FROM test_runs
+		WHERE commit_sha LIKE ?
+		ORDER BY timestamp DESC, id DESC
+		LIMIT ?
+	`, commitSHA+"%", limit)
+}
 
-	rows, err := db.Query(query, limit)
+// queryRuns decodes the stored results selected by query
+func (s *Store) queryRuns(query string, args ...interface{}) ([]runner.Results, error) {
+	db, err := s.getDB()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+
+	rows, err := db.Query(query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query test runs: %w", err)
 	}
@@ -96,22 +317,213 @@ func (s *Store) GetHistory(limit int) ([]runner.Results, error) {
 	return results, nil
 }
 
-// getDB returns a database connection, creating tables if needed
+// GetTestHistory retrieves the outcomes of the named test in the most recent
+// runs, newest first. A test run against several providers has one point per
+// provider in each run.
+func (s *Store) GetTestHistory(name string, limit int) ([]TestHistoryPoint, error) {
+	db, err := s.getDB()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+
+	query := `
+		SELECT run_id, timestamp, commit_sha, prompt_file, provider, status, cost, duration, error
+		FROM test_results
+		WHERE name = ?
+		ORDER BY timestamp DESC, id DESC
+		LIMIT ?
+	`
+
+	rows, err := db.Query(query, name, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query test history: %w", err)
+	}
+	defer rows.Close()
+
+	var history []TestHistoryPoint
+	for rows.Next() {
+		var (
+			point     TestHistoryPoint
+			timestamp int64
+			commitSHA sql.NullString
+			errorText sql.NullString
+			duration  int64
+		)
+		if err := rows.Scan(&point.RunID, &timestamp, &commitSHA, &point.PromptFile, &point.Provider, &point.Status, &point.Cost, &duration, &errorText); err != nil {
+			return nil, fmt.Errorf("failed to read test history: %w", err)
+		}
+
+		point.Timestamp = time.Unix(timestamp, 0)
+		point.CommitSHA = commitSHA.String
+		point.Duration = time.Duration(duration) * time.Millisecond
+		point.Error = errorText.String
+		history = append(history, point)
+	}
+
+	return history, rows.Err()
+}
+
+// FlakyTests returns the tests whose pass/fail outcome flipped across the last
+// runs stored for commitSHA, most flaky first. Skipped results are ignored.
+// Runs without a commit are never compared, so an empty commitSHA finds none.
+func (s *Store) FlakyTests(commitSHA string, runs int) ([]runner.FlakyTest, error) {
+	if commitSHA == "" {
+		return nil, nil
+	}
+
+	db, err := s.getDB()
+	if errors.Is(err, ErrDisabled) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+
+	query := `
+		SELECT name, prompt_file, provider, status FROM test_results
+		WHERE run_id IN (
+			SELECT run_id FROM test_runs
+			WHERE commit_sha = ? AND run_id IS NOT NULL
+			ORDER BY timestamp DESC, id DESC
+			LIMIT ?
+		)
+		ORDER BY timestamp, id
+	`
+
+	rows, err := db.Query(query, commitSHA, runs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query test results: %w", err)
+	}
+	defer rows.Close()
+
+	type outcomes struct {
+		test runner.FlakyTest
+		last string
+	}
+	byTest := make(map[string]*outcomes)
+	var order []string
+
+	for rows.Next() {
+		var name, promptFile, provider, status string
+		if err := rows.Scan(&name, &promptFile, &provider, &status); err != nil {
+			return nil, fmt.Errorf("failed to read test results: %w", err)
+		}
+		if status != "passed" && status != "failed" {
+			continue
+		}
+
+		key := promptFile + "\x00" + name + "\x00" + provider
+		entry, ok := byTest[key]
+		if !ok {
+			entry = &outcomes{test: runner.FlakyTest{Name: name, PromptFile: promptFile, Provider: provider}}
+			byTest[key] = entry
+			order = append(order, key)
+		}
+
+		entry.test.Runs++
+		if status == "passed" {
+			entry.test.Passed++
+		}
+		if entry.last != "" && entry.last != status {
+			entry.test.Flips++
+		}
+		entry.last = status
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read test results: %w", err)
+	}
+
+	var flaky []runner.FlakyTest
+	for _, key := range order {
+		test := byTest[key].test
+		if test.Flips == 0 {
+			continue
+		}
+		test.Rate = float64(test.Flips) / float64(test.Runs-1)
+		flaky = append(flaky, test)
+	}
+
+	sort.SliceStable(flaky, func(i, j int) bool {
+		return flaky[i].Rate > flaky[j].Rate
+	})
+
+	return flaky, nil
+}
+
+// GetRun retrieves the results of the run with the given ID
+func (s *Store) GetRun(runID string) (*runner.Results, error) {
+	db, err := s.getDB()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+
+	var resultsJSON string
+	err = db.QueryRow(`SELECT results_json FROM test_runs WHERE run_id = ? ORDER BY timestamp DESC LIMIT 1`, runID).Scan(&resultsJSON)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("%w: %s", ErrRunNotFound, runID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query test run: %w", err)
+	}
+
+	var results runner.Results
+	if err := json.Unmarshal([]byte(resultsJSON), &results); err != nil {
+		return nil, fmt.Errorf("failed to parse stored results: %w", err)
+	}
+
+	return &results, nil
+}
+
+// getDB returns the database connection, opening it and creating tables on
+// first use
 func (s *Store) getDB() (*sql.DB, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.disabled {
+		return nil, ErrDisabled
+	}
 	if s.db != nil {
 		return s.db, nil
 	}
 
-	// Ensure .promptguard directory exists
-	metricsDir := ".promptguard"
-	if err := os.MkdirAll(metricsDir, 0755); err != nil {
-		return nil, fmt.Errorf("failed to create metrics directory: %w", err)
+	db, err := s.openDB()
+	if err != nil {
+		if isReadOnly(err) {
+			s.disableLocked(err)
+			return nil, ErrDisabled
+		}
+		return nil, err
 	}
 
-	dbPath := filepath.Join(metricsDir, "metrics.db")
-	db, err := sql.Open("sqlite3", dbPath)
-	if err != nil {
-		return nil, fmt.Errorf("failed to open SQLite database: %w", err)
+	s.db = db
+	return db, nil
+}
+
+// openDB opens the database and creates its tables
+func (s *Store) openDB() (*sql.DB, error) {
+	var db *sql.DB
+	if s.path == MemoryPath {
+		// Every connection to :memory: gets its own database, so keep one
+		var err error
+		db, err = sql.Open("sqlite3", MemoryPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open SQLite database: %w", err)
+		}
+		db.SetMaxOpenConns(1)
+	} else {
+		if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+			return nil, fmt.Errorf("failed to create metrics directory: %w", err)
+		}
+
+		// WAL lets the viewer read while a run writes, and the busy timeout
+		// makes concurrent writers wait instead of failing
+		dsn := fmt.Sprintf("file:%s?_journal_mode=WAL&_busy_timeout=%d", s.path, busyTimeout.Milliseconds())
+		var err error
+		db, err = sql.Open("sqlite3", dsn)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open SQLite database: %w", err)
+		}
 	}
 
 	// Create tables if they don't exist
@@ -120,15 +532,49 @@ func (s *Store) getDB() (*sql.DB, error) {
 		return nil, fmt.Errorf("failed to create tables: %w", err)
 	}
 
-	s.db = db
 	return db, nil
 }
 
+// disable turns metrics off for the rest of the Store's life, printing a
+// single notice instead of a warning for every operation
+func (s *Store) disable(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.disableLocked(err)
+}
+
+func (s *Store) disableLocked(err error) {
+	if s.disabled {
+		return
+	}
+	s.disabled = true
+	warn.Printf("metrics disabled for this run: %s is not writable (%v)", s.path, err)
+}
+
+// isReadOnly reports whether err means the database or its directory cannot
+// be written, as opposed to the database being corrupt or locked
+func isReadOnly(err error) bool {
+	if errors.Is(err, fs.ErrPermission) || errors.Is(err, syscall.EROFS) {
+		return true
+	}
+
+	var sqliteErr sqlite3.Error
+	if !errors.As(err, &sqliteErr) {
+		return false
+	}
+	switch sqliteErr.Code {
+	case sqlite3.ErrReadonly, sqlite3.ErrCantOpen, sqlite3.ErrPerm:
+		return true
+	}
+	return false
+}
+
 // createTables creates the necessary database tables
 func (s *Store) createTables(db *sql.DB) error {
 	query := `
 		CREATE TABLE IF NOT EXISTS test_runs (
 			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			run_id TEXT,
 			timestamp INTEGER NOT NULL,
 			commit_sha TEXT,
 			pr_number TEXT,
@@ -143,16 +589,81 @@ func (s *Store) createTables(db *sql.DB) error {
 
 		CREATE INDEX IF NOT EXISTS idx_test_runs_timestamp ON test_runs(timestamp);
 		CREATE INDEX IF NOT EXISTS idx_test_runs_commit_sha ON test_runs(commit_sha);
+
+		CREATE TABLE IF NOT EXISTS test_results (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			run_id TEXT NOT NULL,
+			timestamp INTEGER NOT NULL,
+			commit_sha TEXT,
+			name TEXT NOT NULL,
+			prompt_file TEXT NOT NULL,
+			provider TEXT NOT NULL,
+			status TEXT NOT NULL,
+			cost REAL NOT NULL,
+			duration INTEGER NOT NULL,
+			error TEXT
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_test_results_name ON test_results(name, timestamp);
+		CREATE INDEX IF NOT EXISTS idx_test_results_run_id ON test_results(run_id);
 	`
 
-	_, err := db.Exec(query)
+	if _, err := db.Exec(query); err != nil {
+		return err
+	}
+
+	// Databases created before run IDs were recorded lack the column
+	if err := addColumn(db, "test_runs", "run_id", "TEXT"); err != nil {
+		return err
+	}
+
+	_, err := db.Exec(`CREATE INDEX IF NOT EXISTS idx_test_runs_run_id ON test_runs(run_id)`)
 	return err
 }
 
-// Close closes the database connection
+// addColumn adds a column to a table unless it already exists
+func addColumn(db *sql.DB, table, column, columnType string) error {
+	rows, err := db.Query(fmt.Sprintf("PRAGMA table_info(%s)", table))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			cid        int
+			name       string
+			ctype      string
+			notNull    int
+			defaultVal sql.NullString
+			primaryKey int
+		)
+		if err := rows.Scan(&cid, &name, &ctype, &notNull, &defaultVal, &primaryKey); err != nil {
+			return err
+		}
+		if name == column {
+			return nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	rows.Close()
+
+	_, err = db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, columnType))
+	return err
+}
+
+// Close closes the database connection. The store reopens it if used again.
 func (s *Store) Close() error {
-	if s.db != nil {
-		return s.db.Close()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.db == nil {
+		return nil
 	}
-	return nil
+
+	err := s.db.Close()
+	s.db = nil
+	return err
 }
@@ -0,0 +1,135 @@
+package metrics
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"promptguard/internal/runner"
+	"promptguard/internal/warn"
+)
+
+// testRun returns the results of a run with one passed and one failed test
+func testRun(id string) *runner.Results {
+	return &runner.Results{
+		Total:  2,
+		Passed: 1,
+		Failed: 1,
+		TestResults: []runner.TestResult{
+			{Name: "greets", PromptFile: "prompts/onboard.prompt", Provider: "mock:echo", Status: "passed"},
+			{Name: "cites policy", PromptFile: "prompts/onboard.prompt", Provider: "mock:echo", Status: "failed"},
+		},
+		Metadata: runner.Metadata{RunID: id, CommitSHA: "abc123"},
+	}
+}
+
+// TestConcurrentStores writes and reads the same database from several
+// goroutines, some sharing a Store and some with a Store of their own, as
+// parallel runs and the viewer do. Run it with -race.
+func TestConcurrentStores(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "metrics.db")
+	shared := NewStore(path)
+	defer shared.Close()
+
+	const writers = 8
+	const runsPerWriter = 5
+
+	var wg sync.WaitGroup
+	errs := make(chan error, writers*runsPerWriter*2)
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+
+			store := shared
+			if w%2 == 1 {
+				store = NewStore(path)
+				defer store.Close()
+			}
+
+			for i := 0; i < runsPerWriter; i++ {
+				if err := store.Store(testRun(fmt.Sprintf("run-%d-%d", w, i))); err != nil {
+					errs <- err
+				}
+				if _, err := store.GetHistory(10); err != nil {
+					errs <- err
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Errorf("concurrent access failed: %v", err)
+	}
+
+	history, err := shared.GetHistory(writers * runsPerWriter * 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != writers*runsPerWriter {
+		t.Errorf("stored %d runs, want %d", len(history), writers*runsPerWriter)
+	}
+
+	tests, err := shared.GetTestHistory("greets", writers*runsPerWriter*2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tests) != writers*runsPerWriter {
+		t.Errorf("stored %d test results, want %d", len(tests), writers*runsPerWriter)
+	}
+}
+
+// TestUnwritableDatabaseDisablesMetrics opens a database that cannot be
+// created, as on a read-only filesystem, and checks that the store warns
+// once and does not try again
+func TestUnwritableDatabaseDisablesMetrics(t *testing.T) {
+	// SQLite cannot open a directory as a database, failing the way it does
+	// for a file it may not create
+	path := filepath.Join(t.TempDir(), "metrics.db")
+	if err := os.Mkdir(path, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	var warnings bytes.Buffer
+	previous := warn.SetOutput(&warnings)
+	defer warn.SetOutput(previous)
+
+	store := NewStore(path)
+	defer store.Close()
+
+	if err := store.Store(testRun("run-1")); err != nil {
+		t.Fatalf("Store() error = %v, want metrics disabled without an error", err)
+	}
+
+	// A retry would now succeed in creating the database
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.Store(testRun("run-2")); err != nil {
+		t.Fatalf("second Store() error = %v", err)
+	}
+	if flaky, err := store.FlakyTests("abc123", 10); err != nil || flaky != nil {
+		t.Errorf("FlakyTests() = %v, %v; want nothing while disabled", flaky, err)
+	}
+	if _, err := store.getDB(); !errors.Is(err, ErrDisabled) {
+		t.Errorf("getDB() error = %v, want ErrDisabled", err)
+	}
+
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("database was created after metrics were disabled")
+	}
+	if count := strings.Count(warnings.String(), "Warning:"); count != 1 {
+		t.Errorf("got %d warnings, want 1:\n%s", count, warnings.String())
+	}
+	if !strings.Contains(warnings.String(), "metrics disabled") {
+		t.Errorf("warning does not say metrics are disabled:\n%s", warnings.String())
+	}
+}
@@ -0,0 +1,245 @@
+package prompts
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"text/template"
+	"text/template/parse"
+	"unicode"
+)
+
+// templateFuncs is a curated subset of the Sprig template functions, with
+// Sprig's argument order so that pipelines like {{ .name | default "there" }}
+// behave the same way.
+var templateFuncs = template.FuncMap{
+	// Strings
+	"upper":      strings.ToUpper,
+	"lower":      strings.ToLower,
+	"title":      title,
+	"trim":       strings.TrimSpace,
+	"trimPrefix": func(prefix, s string) string { return strings.TrimPrefix(s, prefix) },
+	"trimSuffix": func(suffix, s string) string { return strings.TrimSuffix(s, suffix) },
+	"replace":    func(old, new, s string) string { return strings.ReplaceAll(s, old, new) },
+	"contains":   func(substr, s string) bool { return strings.Contains(s, substr) },
+	"hasPrefix":  func(prefix, s string) bool { return strings.HasPrefix(s, prefix) },
+	"hasSuffix":  func(suffix, s string) bool { return strings.HasSuffix(s, suffix) },
+	"repeat":     func(count int, s string) string { return strings.Repeat(s, count) },
+	"trunc":      trunc,
+	"indent":     indent,
+	"nindent":    func(spaces int, s string) string { return "\n" + indent(spaces, s) },
+	"quote":      func(v interface{}) string { return fmt.Sprintf("%q", toString(v)) },
+	"squote":     func(v interface{}) string { return "'" + toString(v) + "'" },
+
+	// Defaults
+	"default":  defaultValue,
+	"empty":    empty,
+	"coalesce": coalesce,
+
+	// Lists
+	"list":  func(items ...interface{}) []interface{} { return items },
+	"join":  join,
+	"split": func(sep, s string) []string { return strings.Split(s, sep) },
+	"first": func(list interface{}) interface{} { return listItem(list, 0) },
+	"last":  func(list interface{}) interface{} { return listItem(list, -1) },
+
+	// Encoding
+	"toJson": toJSON,
+}
+
+func title(s string) string {
+	runes := []rune(s)
+	start := true
+	for i, r := range runes {
+		if start && unicode.IsLetter(r) {
+			runes[i] = unicode.ToUpper(r)
+		}
+		start = unicode.IsSpace(r)
+	}
+	return string(runes)
+}
+
+func trunc(length int, s string) string {
+	runes := []rune(s)
+	if length < 0 || len(runes) <= length {
+		return s
+	}
+	return string(runes[:length])
+}
+
+func indent(spaces int, s string) string {
+	pad := strings.Repeat(" ", spaces)
+	return pad + strings.ReplaceAll(s, "\n", "\n"+pad)
+}
+
+// defaultValue returns given unless it is empty, in which case d is returned
+func defaultValue(d interface{}, given ...interface{}) interface{} {
+	if len(given) == 0 || empty(given[0]) {
+		return d
+	}
+	return given[0]
+}
+
+// empty reports whether v is nil or the zero value of its type
+func empty(v interface{}) bool {
+	if v == nil {
+		return true
+	}
+
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return rv.Len() == 0
+	case reflect.Ptr, reflect.Interface:
+		return rv.IsNil()
+	default:
+		return rv.IsZero()
+	}
+}
+
+func coalesce(values ...interface{}) interface{} {
+	for _, v := range values {
+		if !empty(v) {
+			return v
+		}
+	}
+	return nil
+}
+
+func join(sep string, list interface{}) string {
+	rv := reflect.ValueOf(list)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return toString(list)
+	}
+
+	items := make([]string, rv.Len())
+	for i := range items {
+		items[i] = toString(rv.Index(i).Interface())
+	}
+	return strings.Join(items, sep)
+}
+
+func listItem(list interface{}, index int) interface{} {
+	rv := reflect.ValueOf(list)
+	if (rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array) || rv.Len() == 0 {
+		return nil
+	}
+	if index < 0 {
+		index = rv.Len() + index
+	}
+	return rv.Index(index).Interface()
+}
+
+func toJSON(v interface{}) (string, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+func toString(v interface{}) string {
+	if v == nil {
+		return ""
+	}
+	if s, ok := v.(string); ok {
+		return s
+	}
+	return fmt.Sprint(v)
+}
+
+// optionalFuncs are the functions whose arguments may be undefined
+// variables, since handling a missing value is what they are for
+var optionalFuncs = map[string]bool{
+	"default":  true,
+	"empty":    true,
+	"coalesce": true,
+}
+
+// allowOptional rewrites the variables given to optionalFuncs, as in
+// {{ .name | default "there" }} or {{ coalesce .nickname .name }}, into
+// {{ index . "name" }}, which yields nil for a missing key where a field
+// would fail a strict template. Other references to the same variable stay
+// strict.
+func allowOptional(tmpl *template.Template) {
+	if tmpl.Tree != nil {
+		rewriteOptional(tmpl.Tree.Root)
+	}
+}
+
+func rewriteOptional(node parse.Node) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			rewriteOptional(child)
+		}
+	case *parse.ActionNode:
+		rewriteOptional(n.Pipe)
+	case *parse.PipeNode:
+		if n == nil {
+			return
+		}
+		// A variable piped straight into an optional function
+		if len(n.Cmds) > 1 && isOptionalCall(n.Cmds[1]) && len(n.Cmds[0].Args) == 1 {
+			if field, ok := n.Cmds[0].Args[0].(*parse.FieldNode); ok {
+				n.Cmds[0].Args = indexArgs(field)
+			}
+		}
+		for _, cmd := range n.Cmds {
+			rewriteOptional(cmd)
+		}
+	case *parse.CommandNode:
+		optional := isOptionalCall(n)
+		for i, arg := range n.Args {
+			if field, ok := arg.(*parse.FieldNode); ok && optional {
+				n.Args[i] = &parse.PipeNode{
+					NodeType: parse.NodePipe,
+					Pos:      field.Pos,
+					Cmds:     []*parse.CommandNode{{NodeType: parse.NodeCommand, Pos: field.Pos, Args: indexArgs(field)}},
+				}
+				continue
+			}
+			rewriteOptional(arg)
+		}
+	case *parse.IfNode:
+		rewriteOptional(n.Pipe)
+		rewriteOptional(n.List)
+		rewriteOptional(n.ElseList)
+	case *parse.RangeNode:
+		rewriteOptional(n.Pipe)
+		rewriteOptional(n.List)
+		rewriteOptional(n.ElseList)
+	case *parse.WithNode:
+		rewriteOptional(n.Pipe)
+		rewriteOptional(n.List)
+		rewriteOptional(n.ElseList)
+	case *parse.TemplateNode:
+		rewriteOptional(n.Pipe)
+	}
+}
+
+// isOptionalCall reports whether cmd calls one of optionalFuncs
+func isOptionalCall(cmd *parse.CommandNode) bool {
+	if len(cmd.Args) == 0 {
+		return false
+	}
+	ident, ok := cmd.Args[0].(*parse.IdentifierNode)
+	return ok && optionalFuncs[ident.Ident]
+}
+
+// indexArgs returns the arguments of index . "a" "b" for the field .a.b
+func indexArgs(field *parse.FieldNode) []parse.Node {
+	args := []parse.Node{
+		parse.NewIdentifier("index").SetPos(field.Pos),
+		&parse.DotNode{NodeType: parse.NodeDot, Pos: field.Pos},
+	}
+	for _, ident := range field.Ident {
+		args = append(args, &parse.StringNode{NodeType: parse.NodeString, Pos: field.Pos, Quoted: strconv.Quote(ident), Text: ident})
+	}
+	return args
+}
@@ -1,12 +1,20 @@
 package prompts
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"os"
 	"path/filepath"
 	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 	"text/template"
+	"text/template/parse"
+
+	"gopkg.in/yaml.v3"
+	"promptguard/internal/providers"
 )
 
 // Prompt represents a prompt template
@@ -14,6 +22,50 @@ type Prompt struct {
 	Content  string            `json:"content"`
 	Metadata map[string]string `json:"metadata"`
 	Template *template.Template
+	Raw      bool `json:"raw"` // Sent verbatim without template execution
+
+	// Provider and Temperature are the defaults declared in the frontmatter
+	// for tests of this prompt
+	Provider    string   `json:"provider,omitempty"`
+	Temperature *float64 `json:"temperature,omitempty"`
+
+	sections   []section
+	checksum   string
+	lineOffset int // Lines removed from the top of the file by the frontmatter
+	leftDelim  string
+	rightDelim string
+	missingKey string // Template missingkey option set by SetStrict
+}
+
+// section is a role-tagged part of a chat prompt
+type section struct {
+	role     string
+	text     string
+	template *template.Template
+}
+
+var (
+	frontmatterRegex   = regexp.MustCompile(`(?s)^---[ \t]*\r?\n(.*?)\r?\n---[ \t]*\r?\n(.*)$`)
+	templateErrorRegex = regexp.MustCompile(`(?s)^template: [^:]*:(\d+): (.*)$`)
+	missingKeyRegex    = regexp.MustCompile(`map has no entry for key "([^"]+)"`)
+)
+
+// roleMarkerRegex matches the lines that start a chat message section,
+// e.g. "--- system ---"
+var roleMarkerRegex = regexp.MustCompile(`(?m)^---[ \t]*(system|user|assistant)[ \t]*---[ \t]*\r?$`)
+
+// Load loads a prompt from a local file or, for an http(s) URL, from the
+// server it names
+func Load(source string) (*Prompt, error) {
+	if !IsRemote(source) {
+		return LoadFromFile(source)
+	}
+
+	content, err := fetch(source)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(source, string(content))
 }
 
 // LoadFromFile loads a prompt from a file
@@ -22,10 +74,17 @@ func LoadFromFile(filename string) (*Prompt, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to read prompt file %s: %w", filename, err)
 	}
+	return Parse(filename, string(content))
+}
 
+// Parse builds a prompt from its content, such as an inline prompt of the
+// config. filename names the prompt in errors.
+func Parse(filename, content string) (*Prompt, error) {
+	sum := sha256.Sum256([]byte(content))
 	prompt := &Prompt{
-		Content:  string(content),
+		Content:  content,
 		Metadata: make(map[string]string),
+		checksum: hex.EncodeToString(sum[:]),
 	}
 
 	// Parse metadata from frontmatter if present
@@ -33,69 +92,332 @@ func LoadFromFile(filename string) (*Prompt, error) {
 		return nil, fmt.Errorf("failed to parse frontmatter in %s: %w", filename, err)
 	}
 
-	// Create template
-	tmpl, err := template.New(filepath.Base(filename)).Parse(prompt.Content)
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse template in %s: %w", filename, err)
+	// Create templates, one per role section
+	if err := prompt.parse(filename); err != nil {
+		return nil, err
 	}
 
-	prompt.Template = tmpl
 	return prompt, nil
 }
 
-// Render renders the prompt with given variables
-func (p *Prompt) Render(variables map[string]interface{}) (string, error) {
+// Render renders the prompt with given variables into chat messages. Prompts
+// without role markers render as a single user message.
+func (p *Prompt) Render(variables map[string]interface{}) ([]providers.Message, error) {
+	messages := make([]providers.Message, 0, len(p.sections))
+
+	for _, sec := range p.sections {
+		content := sec.text
+		if sec.template != nil {
+			var err error
+			if content, err = execute(sec.template, variables); err != nil {
+				return nil, err
+			}
+		}
+
+		if len(p.sections) > 1 {
+			content = strings.TrimSpace(content)
+		}
+
+		messages = append(messages, providers.Message{Role: sec.role, Content: content})
+	}
+
+	return messages, nil
+}
+
+// RenderText renders text, such as a follow-up conversation turn, as a
+// template with the prompt's delimiters, functions, and strictness. Raw
+// prompts return the text unchanged.
+func (p *Prompt) RenderText(text string, variables map[string]interface{}) (string, error) {
+	if p.Raw {
+		return text, nil
+	}
+
+	tmpl, err := p.newTemplate("turn").Option(p.missingKey).Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse template: %w", err)
+	}
+	allowOptional(tmpl)
+	return execute(tmpl, variables)
+}
+
+// execute executes a prompt template, naming the variable when one is
+// undefined
+func execute(tmpl *template.Template, variables map[string]interface{}) (string, error) {
 	var buf strings.Builder
-	
-	if err := p.Template.Execute(&buf, variables); err != nil {
+	if err := tmpl.Execute(&buf, variables); err != nil {
+		if matches := missingKeyRegex.FindStringSubmatch(err.Error()); matches != nil {
+			return "", fmt.Errorf("failed to render prompt: undefined variable %q", matches[1])
+		}
 		return "", fmt.Errorf("failed to render prompt: %w", err)
 	}
-
 	return buf.String(), nil
 }
 
+// parse builds the prompt templates. Chat prompts are split at role markers
+// into one template per section; raw prompts are kept as literal text.
+func (p *Prompt) parse(filename string) error {
+	name := filepath.Base(filename)
+
+	if !p.Raw {
+		tmpl, err := p.newTemplate(name).Parse(p.Content)
+		if err != nil {
+			return p.templateError(filename, 0, err)
+		}
+		allowOptional(tmpl)
+		p.Template = tmpl
+	}
+
+	markers := roleMarkerRegex.FindAllStringSubmatchIndex(p.Content, -1)
+	if len(markers) == 0 {
+		p.sections = []section{{role: "user", text: p.Content, template: p.Template}}
+		p.SetStrict(true)
+		return nil
+	}
+
+	if strings.TrimSpace(p.Content[:markers[0][0]]) != "" {
+		return fmt.Errorf("failed to parse %s: content found before the first role marker", filename)
+	}
+
+	p.sections = make([]section, 0, len(markers))
+	for i, marker := range markers {
+		end := len(p.Content)
+		if i+1 < len(markers) {
+			end = markers[i+1][0]
+		}
+
+		sec := section{
+			role: p.Content[marker[2]:marker[3]],
+			text: p.Content[marker[1]:end],
+		}
+
+		if !p.Raw {
+			tmpl, err := p.newTemplate(fmt.Sprintf("%s#%d", name, i)).Parse(sec.text)
+			if err != nil {
+				return p.templateError(filename, strings.Count(p.Content[:marker[1]], "\n"), err)
+			}
+			allowOptional(tmpl)
+			sec.template = tmpl
+		}
+
+		p.sections = append(p.sections, sec)
+	}
+
+	p.SetStrict(true)
+	return nil
+}
+
+// Checksum returns the SHA-256 of the prompt file as it was loaded
+func (p *Prompt) Checksum() string {
+	return p.checksum
+}
+
+// SetStrict controls whether rendering fails when the prompt references a
+// variable that was not supplied. Prompts are strict by default; when not
+// strict, missing variables render as "<no value>".
+func (p *Prompt) SetStrict(strict bool) {
+	option := "missingkey=default"
+	if strict {
+		option = "missingkey=error"
+	}
+	p.missingKey = option
+
+	for _, sec := range p.sections {
+		if sec.template != nil {
+			sec.template.Option(option)
+		}
+	}
+}
+
+// newTemplate creates an empty template using the prompt's delimiters
+func (p *Prompt) newTemplate(name string) *template.Template {
+	return template.New(name).Delims(p.leftDelim, p.rightDelim).Funcs(templateFuncs)
+}
+
+// templateError rewrites a template parse error to point at the offending
+// line of the prompt file. sectionOffset is the number of content lines
+// preceding the template that failed to parse.
+func (p *Prompt) templateError(filename string, sectionOffset int, err error) error {
+	matches := templateErrorRegex.FindStringSubmatch(err.Error())
+	if matches == nil {
+		return fmt.Errorf("failed to parse template in %s: %w", filename, err)
+	}
+
+	line, _ := strconv.Atoi(matches[1])
+	contentLine := sectionOffset + line
+	fileLine := p.lineOffset + contentLine
+
+	offending := ""
+	if lines := strings.Split(p.Content, "\n"); contentLine >= 1 && contentLine <= len(lines) {
+		offending = strings.TrimRight(lines[contentLine-1], "\r")
+	}
+
+	return fmt.Errorf("failed to parse template in %s:%d: %s\n  %d | %s\n(set \"raw: true\" in the frontmatter if this prompt is not a template)",
+		filename, fileLine, matches[2], fileLine, offending)
+}
+
 // parseFrontmatter extracts YAML frontmatter from the prompt content
 func (p *Prompt) parseFrontmatter() error {
 	// Check for YAML frontmatter
-	frontmatterRegex := regexp.MustCompile(`^---\s*\n(.*?)\n---\s*\n(.*)`)
 	matches := frontmatterRegex.FindStringSubmatch(p.Content)
-	
-	if len(matches) == 3 {
-		// TODO: Parse YAML frontmatter and extract metadata
-		// For now, just use the content without frontmatter
-		p.Content = matches[2]
+	if len(matches) != 3 {
+		return nil
+	}
+
+	var metadata map[string]interface{}
+	if err := yaml.Unmarshal([]byte(matches[1]), &metadata); err != nil {
+		return err
+	}
+
+	for key, value := range metadata {
+		p.Metadata[key] = fmt.Sprint(value)
+	}
+
+	if raw, ok := metadata["raw"].(bool); ok {
+		p.Raw = raw
+	}
+
+	// Default provider for the prompt's tests, e.g. provider: openai:gpt-4o
+	if value, ok := metadata["provider"]; ok {
+		provider, ok := value.(string)
+		if !ok || !strings.Contains(provider, ":") {
+			return fmt.Errorf("provider must be a provider ID such as openai:gpt-4o")
+		}
+		p.Provider = provider
+	}
+
+	if value, ok := metadata["temperature"]; ok {
+		var temperature float64
+		switch v := value.(type) {
+		case float64:
+			temperature = v
+		case int:
+			temperature = float64(v)
+		default:
+			return fmt.Errorf("temperature must be a number")
+		}
+		if temperature < 0 || temperature > 2 {
+			return fmt.Errorf("temperature must be between 0 and 2")
+		}
+		p.Temperature = &temperature
+	}
+
+	// Custom template delimiters, e.g. delims: ["[[", "]]"]
+	if value, ok := metadata["delims"]; ok {
+		delims, ok := value.([]interface{})
+		if !ok || len(delims) != 2 {
+			return fmt.Errorf("delims must be a list of two strings")
+		}
+		left, leftOK := delims[0].(string)
+		right, rightOK := delims[1].(string)
+		if !leftOK || !rightOK || left == "" || right == "" {
+			return fmt.Errorf("delims must be a list of two non-empty strings")
+		}
+		p.leftDelim, p.rightDelim = left, right
 	}
 
+	p.lineOffset = strings.Count(p.Content[:len(p.Content)-len(matches[2])], "\n")
+	p.Content = matches[2]
+
 	return nil
 }
 
-// GetVariables extracts variable names from the prompt template
+// GetVariables returns the sorted names of the variables referenced by the
+// prompt template, including those used inside range, if, and with blocks.
+// Fields accessed on the dot of a range or with block belong to the ranged
+// value rather than the prompt variables, so only the ranged variable is
+// reported for them.
 func (p *Prompt) GetVariables() []string {
-	// Simple regex to find {{.Variable}} patterns
-	varRegex := regexp.MustCompile(`\{\{\s*\.(\w+)\s*\}\}`)
-	matches := varRegex.FindAllStringSubmatch(p.Content, -1)
-	
-	var variables []string
+	if p.Raw || p.Template == nil || p.Template.Tree == nil {
+		return nil
+	}
+
 	seen := make(map[string]bool)
-	
-	for _, match := range matches {
-		if len(match) > 1 && !seen[match[1]] {
-			variables = append(variables, match[1])
-			seen[match[1]] = true
-		}
+	collectVariables(p.Template.Tree.Root, true, seen)
+
+	variables := make([]string, 0, len(seen))
+	for name := range seen {
+		variables = append(variables, name)
 	}
-	
+	sort.Strings(variables)
+
 	return variables
 }
 
+// collectVariables walks a template parse tree and records the top-level
+// variable names it references. atRoot reports whether dot is still the
+// variables map at this point of the tree.
+func collectVariables(node parse.Node, atRoot bool, seen map[string]bool) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			collectVariables(child, atRoot, seen)
+		}
+	case *parse.ActionNode:
+		collectVariables(n.Pipe, atRoot, seen)
+	case *parse.PipeNode:
+		if n == nil {
+			return
+		}
+		for _, cmd := range n.Cmds {
+			collectVariables(cmd, atRoot, seen)
+		}
+	case *parse.CommandNode:
+		// index . "name", which is also how variables given to default and
+		// the other optional functions are looked up (see allowOptional)
+		if atRoot && len(n.Args) > 2 {
+			ident, isIndex := n.Args[0].(*parse.IdentifierNode)
+			_, onDot := n.Args[1].(*parse.DotNode)
+			key, isString := n.Args[2].(*parse.StringNode)
+			if isIndex && ident.Ident == "index" && onDot && isString {
+				seen[key.Text] = true
+			}
+		}
+		for _, arg := range n.Args {
+			collectVariables(arg, atRoot, seen)
+		}
+	case *parse.ChainNode:
+		collectVariables(n.Node, atRoot, seen)
+	case *parse.FieldNode:
+		if atRoot && len(n.Ident) > 0 {
+			seen[n.Ident[0]] = true
+		}
+	case *parse.VariableNode:
+		// $ always refers to the variables map, e.g. {{ $.name }} inside a range
+		if len(n.Ident) > 1 && n.Ident[0] == "$" {
+			seen[n.Ident[1]] = true
+		}
+	case *parse.IfNode:
+		collectVariables(n.Pipe, atRoot, seen)
+		collectVariables(n.List, atRoot, seen)
+		collectVariables(n.ElseList, atRoot, seen)
+	case *parse.RangeNode:
+		collectVariables(n.Pipe, atRoot, seen)
+		collectVariables(n.List, false, seen)
+		collectVariables(n.ElseList, atRoot, seen)
+	case *parse.WithNode:
+		collectVariables(n.Pipe, atRoot, seen)
+		collectVariables(n.List, false, seen)
+		collectVariables(n.ElseList, atRoot, seen)
+	case *parse.TemplateNode:
+		collectVariables(n.Pipe, atRoot, seen)
+	}
+}
+
 // Validate checks if the prompt is valid
 func (p *Prompt) Validate() error {
 	if strings.TrimSpace(p.Content) == "" {
 		return fmt.Errorf("prompt content is empty")
 	}
 
+	if p.Raw {
+		return nil
+	}
+
 	// Try to parse as template
-	_, err := template.New("test").Parse(p.Content)
+	_, err := p.newTemplate("test").Parse(p.Content)
 	if err != nil {
 		return fmt.Errorf("invalid template syntax: %w", err)
 	}
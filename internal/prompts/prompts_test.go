@@ -0,0 +1,92 @@
+package prompts
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDefaultWithMissingVariableInStrictMode(t *testing.T) {
+	tests := []struct {
+		name      string
+		template  string
+		variables map[string]interface{}
+		want      string
+	}{
+		{"piped default", `Hello {{ .name | default "there" }}!`, nil, "Hello there!"},
+		{"piped default, then another function", `Hello {{ .name | default "there" | title }}!`, nil, "Hello There!"},
+		{"default call", `Hello {{ default "there" .name }}!`, nil, "Hello there!"},
+		{"coalesce", `Hello {{ coalesce .nickname .name "there" }}!`, map[string]interface{}{"name": "Ada"}, "Hello Ada!"},
+		{"empty", `{{ if empty .name }}anonymous{{ end }}`, nil, "anonymous"},
+		{"nested key", `Plan: {{ .user.plan | default "free" }}`, map[string]interface{}{"user": map[string]interface{}{}}, "Plan: free"},
+		{"present variable", `Hello {{ .name | default "there" }}!`, map[string]interface{}{"name": "Ada"}, "Hello Ada!"},
+		{"inside if", `{{ if true }}Hi {{ .name | default "there" }}{{ end }}`, nil, "Hi there"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prompt, err := Parse("greet.prompt", tt.template)
+			if err != nil {
+				t.Fatal(err)
+			}
+			prompt.SetStrict(true)
+
+			messages, err := prompt.Render(tt.variables)
+			if err != nil {
+				t.Fatalf("Render() error = %v", err)
+			}
+			if messages[0].Content != tt.want {
+				t.Errorf("Render() = %q, want %q", messages[0].Content, tt.want)
+			}
+		})
+	}
+}
+
+func TestStrictModeStillRejectsMissingVariables(t *testing.T) {
+	prompt, err := Parse("greet.prompt", `Hello {{ .name | default "there" }}, your plan is {{ .plan }}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	prompt.SetStrict(true)
+
+	_, err = prompt.Render(map[string]interface{}{"name": "Ada"})
+	if err == nil || !strings.Contains(err.Error(), `undefined variable "plan"`) {
+		t.Fatalf("Render() error = %v, want undefined variable \"plan\"", err)
+	}
+}
+
+func TestDefaultInChatPromptAndTurn(t *testing.T) {
+	prompt, err := Parse("chat.prompt", "--- system ---\nYou help {{ .team | default \"everyone\" }}.\n--- user ---\nHi")
+	if err != nil {
+		t.Fatal(err)
+	}
+	prompt.SetStrict(true)
+
+	messages, err := prompt.Render(nil)
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	if messages[0].Content != "You help everyone." {
+		t.Errorf("system message = %q, want %q", messages[0].Content, "You help everyone.")
+	}
+
+	turn, err := prompt.RenderText(`And {{ .name | default "you" }}?`, nil)
+	if err != nil {
+		t.Fatalf("RenderText() error = %v", err)
+	}
+	if turn != "And you?" {
+		t.Errorf("RenderText() = %q, want %q", turn, "And you?")
+	}
+}
+
+func TestGetVariablesIncludesDefaultedVariables(t *testing.T) {
+	prompt, err := Parse("greet.prompt", `{{ .greeting }} {{ .name | default "there" }}, {{ coalesce .nickname .name }}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"greeting", "name", "nickname"}
+	if got := prompt.GetVariables(); !reflect.DeepEqual(got, want) {
+		t.Errorf("GetVariables() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,76 @@
+package prompts
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"promptguard/internal/warn"
+)
+
+// RemoteCacheDir is where prompts fetched from URLs are kept, so that a run
+// can still load them when the server is unreachable
+const RemoteCacheDir = ".promptguard/cache/prompts"
+
+// fetchTimeout bounds a prompt download
+const fetchTimeout = 30 * time.Second
+
+// IsRemote reports whether a prompt source is an http(s) URL rather than a
+// local path
+func IsRemote(source string) bool {
+	return strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://")
+}
+
+// fetch downloads a remote prompt and caches it. When the server cannot be
+// reached, the cached copy from an earlier run is used instead; a response
+// other than 200 is an error either way, so a bad URL is never mistaken for
+// a prompt.
+func fetch(url string) ([]byte, error) {
+	cached := filepath.Join(RemoteCacheDir, cacheName(url))
+
+	client := &http.Client{Timeout: fetchTimeout}
+	resp, err := client.Get(url)
+	if err != nil {
+		if content, readErr := os.ReadFile(cached); readErr == nil {
+			warn.Printf("failed to fetch prompt %s, using the cached copy: %v", url, err)
+			return content, nil
+		}
+		return nil, fmt.Errorf("failed to fetch prompt %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch prompt %s: server returned %s", url, resp.Status)
+	}
+
+	content, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read prompt %s: %w", url, err)
+	}
+
+	if err := os.MkdirAll(RemoteCacheDir, 0755); err == nil {
+		err = os.WriteFile(cached, content, 0644)
+	}
+	if err != nil {
+		warn.Printf("failed to cache prompt %s: %v", url, err)
+	}
+
+	return content, nil
+}
+
+// cacheName is the cache file of a remote prompt, named by the URL's hash
+// and keeping its extension for readability
+func cacheName(url string) string {
+	sum := sha256.Sum256([]byte(url))
+	name := hex.EncodeToString(sum[:8])
+	if ext := filepath.Ext(strings.SplitN(url, "?", 2)[0]); ext != "" && !strings.Contains(ext, "/") {
+		name += ext
+	}
+	return name
+}
@@ -0,0 +1,76 @@
+package providers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"promptguard/internal/config"
+)
+
+// Pinger is implemented by clients that can confirm they are reachable more
+// cheaply than with a completion
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// CheckResult is the outcome of checking a provider
+type CheckResult struct {
+	Provider string
+	Latency  time.Duration
+	Err      error
+}
+
+// Check confirms that a provider is configured and reachable. Clients that
+// implement Pinger are pinged; the others are sent a one-token "ping"
+// completion, which catches missing or rejected API keys.
+func Check(ctx context.Context, provider *config.Provider) CheckResult {
+	result := CheckResult{Provider: provider.ID}
+
+	settings := map[string]interface{}{"max_tokens": 1}
+	for key, value := range provider.Config {
+		if key != "max_tokens" {
+			settings[key] = value
+		}
+	}
+
+	client, err := NewClient(&config.Provider{ID: provider.ID, Config: settings})
+	if err != nil {
+		result.Err = err
+		return result
+	}
+
+	start := time.Now()
+	if pinger, ok := client.(Pinger); ok {
+		result.Err = pinger.Ping(ctx)
+	} else {
+		_, result.Err = client.Complete(ctx, []Message{{Role: "user", Content: "ping"}})
+	}
+	result.Latency = time.Since(start)
+
+	return result
+}
+
+// Ping checks that an Ollama endpoint is reachable by listing its models,
+// trying each endpoint in turn
+func (c *OllamaClient) Ping(ctx context.Context) error {
+	_, err := withFailover(ctx, c.endpoints, func(endpoint string) (*Response, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint+"/api/tags", nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create request: %w", err)
+		}
+
+		resp, err := c.httpClient.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("Ollama API request failed: %w", err)
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("Ollama API returned status %d", resp.StatusCode)
+		}
+		return &Response{}, nil
+	})
+	return err
+}
@@ -0,0 +1,146 @@
+package providers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// cohereBaseURL is the default Cohere API endpoint
+const cohereBaseURL = "https://api.cohere.com/v2"
+
+// CohereClient implements the Cohere provider for Command models
+type CohereClient struct {
+	endpoints  []string
+	httpClient *http.Client
+	apiKey     string
+	model      string
+	config     map[string]interface{}
+}
+
+// NewCohereClient creates a new Cohere client
+func NewCohereClient(model string, config map[string]interface{}) (*CohereClient, error) {
+	apiKey, err := apiKeySetting(config, "COHERE_API_KEY")
+	if err != nil {
+		return nil, err
+	}
+
+	return &CohereClient{
+		endpoints:  endpointsSetting(config, cohereBaseURL),
+		httpClient: newHTTPClient(config),
+		apiKey:     apiKey,
+		model:      model,
+		config:     config,
+	}, nil
+}
+
+// cohereRequest is a Cohere v2 chat request
+type cohereRequest struct {
+	Model         string    `json:"model"`
+	Messages      []Message `json:"messages"`
+	Temperature   float64   `json:"temperature"`
+	MaxTokens     int       `json:"max_tokens"`
+	P             *float64  `json:"p,omitempty"`
+	StopSequences []string  `json:"stop_sequences,omitempty"`
+	Seed          *int      `json:"seed,omitempty"`
+}
+
+// cohereResponse holds the parts of a Cohere v2 chat response used here
+type cohereResponse struct {
+	Message struct {
+		Content []struct {
+			Type string `json:"type"`
+			Text string `json:"text"`
+		} `json:"content"`
+	} `json:"message"`
+	FinishReason string `json:"finish_reason"`
+	Usage        struct {
+		BilledUnits struct {
+			InputTokens  int `json:"input_tokens"`
+			OutputTokens int `json:"output_tokens"`
+		} `json:"billed_units"`
+	} `json:"usage"`
+}
+
+// Complete executes a chat completion using Cohere
+func (c *CohereClient) Complete(ctx context.Context, messages []Message) (*Response, error) {
+	temperature, _ := numberSetting(c.config, "temperature")
+
+	req := cohereRequest{
+		Model:         c.model,
+		Messages:      messages,
+		Temperature:   temperature,
+		MaxTokens:     maxTokensSetting(c.config),
+		StopSequences: stopSetting(c.config),
+	}
+	if topP, ok := numberSetting(c.config, "top_p"); ok {
+		req.P = &topP
+	}
+	if seed, ok := intSetting(c.config, "seed"); ok {
+		req.Seed = &seed
+	}
+
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	return withFailover(ctx, c.endpoints, func(endpoint string) (*Response, error) {
+		return c.chat(ctx, endpoint, body)
+	})
+}
+
+// chat posts a chat request to a single Cohere endpoint
+func (c *CohereClient) chat(ctx context.Context, endpoint string, body []byte) (*Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint+"/chat", bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+c.apiKey)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("Cohere API request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("Cohere API returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(detail)))
+	}
+
+	var chat cohereResponse
+	if err := json.NewDecoder(resp.Body).Decode(&chat); err != nil {
+		return nil, fmt.Errorf("failed to decode Cohere response: %w", err)
+	}
+
+	var text strings.Builder
+	for _, content := range chat.Message.Content {
+		if content.Type == "text" {
+			text.WriteString(content.Text)
+		}
+	}
+
+	usage := chat.Usage.BilledUnits
+	return &Response{
+		Text:         text.String(),
+		Cost:         completionCost("cohere", c.model, usage.InputTokens, usage.OutputTokens),
+		Tokens:       usage.InputTokens + usage.OutputTokens,
+		Provider:     "cohere",
+		Model:        c.model,
+		FinishReason: strings.ToLower(chat.FinishReason),
+	}, nil
+}
+
+func (c *CohereClient) GetName() string {
+	return "cohere"
+}
+
+func (c *CohereClient) GetModel() string {
+	return c.model
+}
@@ -0,0 +1,213 @@
+package providers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"os"
+	"sync"
+)
+
+// DefaultEmbeddingModel is the OpenAI model used by assertions that compare
+// texts by embedding when they do not name one
+const DefaultEmbeddingModel = "text-embedding-3-small"
+
+// maxEmbeddingBatch is the most texts the OpenAI embeddings API accepts in
+// one request
+const maxEmbeddingBatch = 2048
+
+// Embedder embeds texts as vectors, returned in the order of the texts
+type Embedder interface {
+	Embed(ctx context.Context, texts []string) (*Embeddings, error)
+}
+
+// Embeddings holds the vectors of an embedding request and what it cost.
+// Embedding calls made by assertions are costed separately from the
+// completions under test.
+type Embeddings struct {
+	Vectors [][]float64
+	Model   string
+	Tokens  int
+	Cost    float64
+}
+
+// embeddingRequest and embeddingResponse are the parts of the OpenAI
+// embeddings API used here. The SDK's model enum predates the
+// text-embedding-3 models, so the request is posted directly.
+type embeddingRequest struct {
+	Input []string `json:"input"`
+	Model string   `json:"model"`
+}
+
+type embeddingResponse struct {
+	Data []struct {
+		Embedding []float64 `json:"embedding"`
+		Index     int       `json:"index"`
+	} `json:"data"`
+	Usage struct {
+		PromptTokens int `json:"prompt_tokens"`
+		TotalTokens  int `json:"total_tokens"`
+	} `json:"usage"`
+}
+
+// OpenAIEmbedder embeds texts with an OpenAI embedding model, batching them
+// into as few requests as the API allows
+type OpenAIEmbedder struct {
+	model string
+}
+
+// NewOpenAIEmbedder creates an embedder for model, or for
+// DefaultEmbeddingModel when model is empty
+func NewOpenAIEmbedder(model string) *OpenAIEmbedder {
+	if model == "" {
+		model = DefaultEmbeddingModel
+	}
+	return &OpenAIEmbedder{model: model}
+}
+
+// Embed returns OpenAI embeddings of texts, in order, with the tokens used
+// and the cost from the pricing table. Models without a known price cost 0.
+func (e *OpenAIEmbedder) Embed(ctx context.Context, texts []string) (*Embeddings, error) {
+	apiKey := os.Getenv("OPENAI_API_KEY")
+	if apiKey == "" {
+		return nil, fmt.Errorf("OPENAI_API_KEY environment variable not set")
+	}
+
+	embeddings := &Embeddings{Vectors: make([][]float64, 0, len(texts)), Model: e.model}
+	for start := 0; start < len(texts); start += maxEmbeddingBatch {
+		end := start + maxEmbeddingBatch
+		if end > len(texts) {
+			end = len(texts)
+		}
+		batch, err := e.embedBatch(ctx, apiKey, texts[start:end])
+		if err != nil {
+			return nil, err
+		}
+		embeddings.Vectors = append(embeddings.Vectors, batch.Vectors...)
+		embeddings.Tokens += batch.Tokens
+		embeddings.Cost += batch.Cost
+	}
+	return embeddings, nil
+}
+
+// embedBatch embeds texts in a single request
+func (e *OpenAIEmbedder) embedBatch(ctx context.Context, apiKey string, texts []string) (*Embeddings, error) {
+	body, err := json.Marshal(embeddingRequest{Input: texts, Model: e.model})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, openAIBaseURL+"/embeddings", bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Authorization", "Bearer "+apiKey)
+
+	resp, err := newHTTPClient(nil).Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("OpenAI embeddings error: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("OpenAI embeddings API returned status %d", resp.StatusCode)
+	}
+
+	var embedded embeddingResponse
+	if err := json.NewDecoder(resp.Body).Decode(&embedded); err != nil {
+		return nil, fmt.Errorf("failed to decode OpenAI embeddings response: %w", err)
+	}
+	if len(embedded.Data) != len(texts) {
+		return nil, fmt.Errorf("expected %d embeddings, got %d", len(texts), len(embedded.Data))
+	}
+
+	vectors := make([][]float64, len(texts))
+	for _, item := range embedded.Data {
+		if item.Index < 0 || item.Index >= len(vectors) {
+			return nil, fmt.Errorf("embedding index %d out of range", item.Index)
+		}
+		vectors[item.Index] = item.Embedding
+	}
+
+	return &Embeddings{
+		Vectors: vectors,
+		Model:   e.model,
+		Tokens:  embedded.Usage.TotalTokens,
+		Cost:    completionCost("openai", e.model, embedded.Usage.PromptTokens, 0),
+	}, nil
+}
+
+// CachingEmbedder embeds each distinct text once, reusing its vector for
+// later requests at no further cost. Runs share one so that expected values
+// repeated across tests are embedded once.
+type CachingEmbedder struct {
+	embedder Embedder
+	mu       sync.Mutex
+	vectors  map[string][]float64
+}
+
+// NewCachingEmbedder wraps embedder with a cache of the vectors it returns
+func NewCachingEmbedder(embedder Embedder) *CachingEmbedder {
+	return &CachingEmbedder{embedder: embedder, vectors: make(map[string][]float64)}
+}
+
+// Embed returns embeddings of texts, requesting the texts not embedded
+// before in one batch. Tokens and cost are those of that request alone.
+func (c *CachingEmbedder) Embed(ctx context.Context, texts []string) (*Embeddings, error) {
+	c.mu.Lock()
+	var missing []string
+	queued := make(map[string]bool)
+	for _, text := range texts {
+		if _, ok := c.vectors[text]; !ok && !queued[text] {
+			missing = append(missing, text)
+			queued[text] = true
+		}
+	}
+	c.mu.Unlock()
+
+	embeddings := &Embeddings{}
+	if len(missing) > 0 {
+		fresh, err := c.embedder.Embed(ctx, missing)
+		if err != nil {
+			return nil, err
+		}
+		embeddings.Model, embeddings.Tokens, embeddings.Cost = fresh.Model, fresh.Tokens, fresh.Cost
+
+		c.mu.Lock()
+		for i, text := range missing {
+			c.vectors[text] = fresh.Vectors[i]
+		}
+		c.mu.Unlock()
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	embeddings.Vectors = make([][]float64, len(texts))
+	for i, text := range texts {
+		embeddings.Vectors[i] = c.vectors[text]
+	}
+	return embeddings, nil
+}
+
+// CosineSimilarity returns the cosine similarity of two vectors, or 0 when
+// either is empty or their lengths differ
+func CosineSimilarity(a, b []float64) float64 {
+	if len(a) == 0 || len(a) != len(b) {
+		return 0
+	}
+
+	var dot, normA, normB float64
+	for i := range a {
+		dot += a[i] * b[i]
+		normA += a[i] * a[i]
+		normB += b[i] * b[i]
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
@@ -0,0 +1,74 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// endpointsSetting returns the base URLs of a provider, from the base_urls
+// list, the base_url setting, or defaultURL when neither is set. Trailing
+// slashes are dropped so that request paths can be appended directly.
+func endpointsSetting(config map[string]interface{}, defaultURL string) []string {
+	var endpoints []string
+	switch urls := config["base_urls"].(type) {
+	case []interface{}:
+		for _, url := range urls {
+			if s, ok := url.(string); ok && s != "" {
+				endpoints = append(endpoints, strings.TrimRight(s, "/"))
+			}
+		}
+	case []string:
+		for _, url := range urls {
+			if url != "" {
+				endpoints = append(endpoints, strings.TrimRight(url, "/"))
+			}
+		}
+	}
+
+	if len(endpoints) == 0 {
+		if url, ok := config["base_url"].(string); ok && url != "" {
+			endpoints = append(endpoints, strings.TrimRight(url, "/"))
+		}
+	}
+
+	if len(endpoints) == 0 {
+		endpoints = append(endpoints, defaultURL)
+	}
+	return endpoints
+}
+
+// withFailover calls complete with each endpoint in turn, moving on to the
+// next one only when the request could not reach the endpoint. API errors are
+// returned as is, since another endpoint of the same provider would reject
+// the request the same way. The endpoint that served the response is
+// recorded on it.
+func withFailover(ctx context.Context, endpoints []string, complete func(endpoint string) (*Response, error)) (*Response, error) {
+	var failures []string
+	for _, endpoint := range endpoints {
+		resp, err := complete(endpoint)
+		if err == nil {
+			resp.Endpoint = endpoint
+			return resp, nil
+		}
+		if len(endpoints) == 1 || !isConnectionError(ctx, err) {
+			return nil, err
+		}
+		failures = append(failures, fmt.Sprintf("%s: %v", endpoint, err))
+	}
+
+	return nil, fmt.Errorf("all %d endpoints failed: %s", len(endpoints), strings.Join(failures, "; "))
+}
+
+// isConnectionError reports whether err means the endpoint could not be
+// reached, as opposed to the caller's context ending or the API rejecting
+// the request
+func isConnectionError(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
@@ -0,0 +1,47 @@
+package providers
+
+import (
+	"strings"
+
+	"promptguard/internal/config"
+)
+
+// defaultMaxTokens is the completion token limit used when a provider does
+// not set max_tokens
+const defaultMaxTokens = 1000
+
+// messageOverheadTokens approximates the tokens a chat API adds per message
+// for the role and separators
+const messageOverheadTokens = 4
+
+// EstimateTokens approximates the prompt tokens of messages at four
+// characters per token, which is close for English text with OpenAI models
+func EstimateTokens(messages []Message) int {
+	tokens := 0
+	for _, message := range messages {
+		tokens += (len(message.Content)+3)/4 + messageOverheadTokens
+	}
+	return tokens
+}
+
+// EstimateCost returns an upper bound on the cost of sending messages to
+// provider, assuming the completion uses all of max_tokens. It reports false
+// when the provider's model has no known price.
+func EstimateCost(provider *config.Provider, messages []Message) (float64, bool) {
+	parts := strings.SplitN(provider.ID, ":", 2)
+	if len(parts) != 2 {
+		return 0, false
+	}
+
+	// A mock provider costs exactly what it is configured to
+	if parts[0] == "mock" {
+		return mockCost(provider.Config), true
+	}
+
+	price, ok := lookupPricing(parts[0], parts[1])
+	if !ok {
+		return 0, false
+	}
+
+	return calculateCost(price, EstimateTokens(messages), maxTokensSetting(provider.Config)), true
+}
@@ -0,0 +1,111 @@
+package providers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// logprobsRequest is a chat completion request with token logprobs enabled
+type logprobsRequest struct {
+	openai.ChatCompletionRequest
+	Logprobs bool `json:"logprobs"`
+}
+
+// logprobsResponse holds the parts of a chat completion response used when
+// logprobs are requested
+type logprobsResponse struct {
+	Choices []struct {
+		Message      openai.ChatCompletionMessage `json:"message"`
+		FinishReason string                       `json:"finish_reason"`
+		Logprobs     *struct {
+			Content []struct {
+				Token   string  `json:"token"`
+				Logprob float64 `json:"logprob"`
+			} `json:"content"`
+		} `json:"logprobs"`
+	} `json:"choices"`
+	Usage openai.Usage `json:"usage"`
+}
+
+// completeWithLogprobs executes a chat completion with token logprobs against
+// the given endpoint, recording the average token confidence on the
+// response. It posts the request directly, since the SDK request type does
+// not carry the logprobs flag.
+func (c *OpenAIClient) completeWithLogprobs(ctx context.Context, endpoint string, req openai.ChatCompletionRequest) (*Response, error) {
+	body, err := json.Marshal(logprobsRequest{ChatCompletionRequest: req, Logprobs: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint+"/chat/completions", bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Authorization", "Bearer "+c.apiKey)
+	if c.orgID != "" {
+		httpReq.Header.Set("OpenAI-Organization", c.orgID)
+	}
+
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("OpenAI API error: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var errResp openai.ErrorResponse
+		if json.NewDecoder(resp.Body).Decode(&errResp) == nil && errResp.Error != nil && errResp.Error.Code == contentFilterReason {
+			return c.filteredResponse(), nil
+		}
+		return nil, fmt.Errorf("OpenAI API returned status %d", resp.StatusCode)
+	}
+
+	var completion logprobsResponse
+	if err := json.NewDecoder(resp.Body).Decode(&completion); err != nil {
+		return nil, fmt.Errorf("failed to decode OpenAI response: %w", err)
+	}
+
+	if len(completion.Choices) == 0 {
+		return nil, fmt.Errorf("no completion choices returned")
+	}
+
+	choice := completion.Choices[0]
+	var confidence *float64
+	if choice.Logprobs != nil && len(choice.Logprobs.Content) > 0 {
+		logprobs := make([]float64, len(choice.Logprobs.Content))
+		for i, token := range choice.Logprobs.Content {
+			logprobs[i] = token.Logprob
+		}
+		value := averageConfidence(logprobs)
+		confidence = &value
+	}
+
+	return &Response{
+		Text:         choice.Message.Content,
+		Cost:         completionCost("openai", c.model, completion.Usage.PromptTokens, completion.Usage.CompletionTokens),
+		Tokens:       completion.Usage.TotalTokens,
+		Provider:     "openai",
+		Model:        c.model,
+		Confidence:   confidence,
+		FinishReason: choice.FinishReason,
+		Filtered:     choice.FinishReason == contentFilterReason,
+		ToolCalls:    toolCalls(choice.Message.ToolCalls),
+	}, nil
+}
+
+// averageConfidence converts token logprobs into a confidence between 0 and 1,
+// the geometric mean of the token probabilities
+func averageConfidence(logprobs []float64) float64 {
+	sum := 0.0
+	for _, logprob := range logprobs {
+		sum += logprob
+	}
+	return math.Exp(sum / float64(len(logprobs)))
+}
@@ -0,0 +1,105 @@
+package providers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// MockClient implements the mock provider, which answers without a network
+// call or API key. mock:echo returns the last message sent; mock:fixed
+// returns the response setting. The cost and tokens settings are reported
+// as is, and delay waits before answering, e.g. to exercise timeouts.
+type MockClient struct {
+	model    string
+	response string
+	cost     float64
+	tokens   int
+	delay    time.Duration
+}
+
+// NewMockClient creates a new mock client
+func NewMockClient(model string, config map[string]interface{}) (*MockClient, error) {
+	if model != "echo" && model != "fixed" {
+		return nil, fmt.Errorf("unsupported mock model: %s (expected echo or fixed)", model)
+	}
+
+	client := &MockClient{model: model, cost: mockCost(config)}
+
+	if response, ok := config["response"]; ok {
+		s, ok := response.(string)
+		if !ok {
+			return nil, fmt.Errorf("mock response must be a string")
+		}
+		client.response = s
+	} else if model == "fixed" {
+		return nil, fmt.Errorf("mock:fixed requires a response setting")
+	}
+
+	if _, ok := config["tokens"]; ok {
+		n, ok := intSetting(config, "tokens")
+		if !ok || n < 0 {
+			return nil, fmt.Errorf("mock tokens must be a non-negative integer")
+		}
+		client.tokens = n
+	}
+
+	if delay, ok := config["delay"]; ok {
+		s, _ := delay.(string)
+		d, err := time.ParseDuration(s)
+		if err != nil || d < 0 {
+			return nil, fmt.Errorf("mock delay must be a duration such as \"500ms\"")
+		}
+		client.delay = d
+	}
+
+	return client, nil
+}
+
+// Complete returns the canned response after the configured delay, or the
+// context's error if it ends first
+func (c *MockClient) Complete(ctx context.Context, messages []Message) (*Response, error) {
+	if c.delay > 0 {
+		timer := time.NewTimer(c.delay)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	text := c.response
+	if c.model == "echo" && len(messages) > 0 {
+		text = messages[len(messages)-1].Content
+	}
+
+	tokens := c.tokens
+	if tokens == 0 {
+		tokens = len(strings.Fields(text))
+	}
+
+	return &Response{
+		Text:         text,
+		Cost:         c.cost,
+		Tokens:       tokens,
+		Provider:     "mock",
+		Model:        c.model,
+		FinishReason: "stop",
+	}, nil
+}
+
+func (c *MockClient) GetName() string {
+	return "mock"
+}
+
+func (c *MockClient) GetModel() string {
+	return c.model
+}
+
+// mockCost returns the cost setting of a mock provider
+func mockCost(config map[string]interface{}) float64 {
+	cost, _ := numberSetting(config, "cost")
+	return cost
+}
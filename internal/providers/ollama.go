@@ -3,64 +3,73 @@ package providers
 import (
 	"context"
 	"encoding/json"
-	"fmt"	"net/http"
+	"fmt"
+	"net/http"
 	"strings"
-
-	"promptgaurd/internal/config"
 )
 
 // OllamaClient implements the Ollama provider for local models
 type OllamaClient struct {
-	baseURL string
-	model   string
-	config  map[string]interface{}
+	endpoints  []string
+	httpClient *http.Client
+	model      string
+	config     map[string]interface{}
 }
 
 // NewOllamaClient creates a new Ollama client
 func NewOllamaClient(model string, config map[string]interface{}) (*OllamaClient, error) {
-	baseURL := "http://localhost:11434" // Default Ollama URL
-	if url, ok := config["base_url"].(string); ok {
-		baseURL = url
-	}
-
 	return &OllamaClient{
-		baseURL: baseURL,
-		model:   model,
-		config:  config,
+		endpoints:  endpointsSetting(config, "http://localhost:11434"), // Default Ollama URL
+		httpClient: newHTTPClient(config),
+		model:      model,
+		config:     config,
 	}, nil
 }
 
-// Complete executes a prompt completion using Ollama
-func (c *OllamaClient) Complete(ctx context.Context, prompt string) (*Response, error) {
-	// Get temperature from config
-	temperature := 0.0
-	if temp, ok := c.config["temperature"]; ok {
-		if tempFloat, ok := temp.(float64); ok {
-			temperature = tempFloat
-		}
+// Complete executes a chat completion using Ollama
+func (c *OllamaClient) Complete(ctx context.Context, messages []Message) (*Response, error) {
+	jsonBody, err := c.requestBody(messages, false)
+	if err != nil {
+		return nil, err
 	}
 
-	// Prepare request body for Ollama API
+	return withFailover(ctx, c.endpoints, func(endpoint string) (*Response, error) {
+		return c.chat(ctx, endpoint, jsonBody)
+	})
+}
+
+// requestBody builds the Ollama chat API request body for messages
+func (c *OllamaClient) requestBody(messages []Message, stream bool) ([]byte, error) {
+	// Get temperature from config
+	temperature, _ := numberSetting(c.config, "temperature")
+
 	requestBody := map[string]interface{}{
-		"model":  c.model,
-		"prompt": prompt,
+		"model":    c.model,
+		"messages": messages,
 		"options": map[string]interface{}{
 			"temperature": temperature,
 		},
-		"stream": false,
+		"stream": stream,
 	}
 
 	jsonBody, err := json.Marshal(requestBody)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
+	return jsonBody, nil
+}
 
+// chat posts a chat request to a single Ollama endpoint
+func (c *OllamaClient) chat(ctx context.Context, endpoint string, jsonBody []byte) (*Response, error) {
 	// Make HTTP request to Ollama
-	resp, err := http.Post(
-		fmt.Sprintf("%s/api/generate", c.baseURL),
-		"application/json",
-		strings.NewReader(string(jsonBody)),
-	)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost,
+		fmt.Sprintf("%s/api/chat", endpoint), strings.NewReader(string(jsonBody)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("Ollama API request failed: %w", err)
 	}
@@ -72,8 +81,8 @@ func (c *OllamaClient) Complete(ctx context.Context, prompt string) (*Response,
 
 	// Parse response
 	var ollamaResp struct {
-		Response string `json:"response"`
-		Done     bool   `json:"done"`
+		Message Message `json:"message"`
+		Done    bool    `json:"done"`
 	}
 
 	if err := json.NewDecoder(resp.Body).Decode(&ollamaResp); err != nil {
@@ -82,9 +91,9 @@ func (c *OllamaClient) Complete(ctx context.Context, prompt string) (*Response,
 
 	// Ollama is free/local, so cost is 0
 	return &Response{
-		Text:     ollamaResp.Response,
-		Cost:     0.0, // Local models are free
-		Tokens:   len(strings.Fields(ollamaResp.Response)), // Approximate
+		Text:     ollamaResp.Message.Content,
+		Cost:     0.0,                                             // Local models are free
+		Tokens:   len(strings.Fields(ollamaResp.Message.Content)), // Approximate
 		Provider: "ollama",
 		Model:    c.model,
 	}, nil
@@ -0,0 +1,89 @@
+package providers
+
+import (
+	_ "embed"
+	"fmt"
+	"sync"
+
+	"promptguard/internal/config"
+	"promptguard/internal/warn"
+)
+
+// pricingTable is the built-in pricing table, kept in pricing.yaml so that
+// prices can be updated without touching code
+//
+//go:embed pricing.yaml
+var pricingTable []byte
+
+// defaultPricing contains the built-in prices in USD per 1K tokens
+var defaultPricing = mustParsePricing(pricingTable)
+
+func mustParsePricing(data []byte) map[string]config.ModelPricing {
+	pricing, err := config.ParsePricing(data)
+	if err != nil {
+		panic(fmt.Sprintf("built-in pricing.yaml: %v", err))
+	}
+	return pricing
+}
+
+var (
+	pricingMu sync.RWMutex
+	pricing   = defaultPricing
+
+	// unpricedModels holds the models already warned about having no price
+	unpricedModels = make(map[string]bool)
+)
+
+// SetPricing overrides the built-in pricing table. Entries in overrides replace
+// the defaults for the same provider:model key; other defaults are kept.
+func SetPricing(overrides map[string]config.ModelPricing) {
+	resolved := make(map[string]config.ModelPricing, len(defaultPricing)+len(overrides))
+	for id, price := range defaultPricing {
+		resolved[id] = price
+	}
+	for id, price := range overrides {
+		resolved[id] = price
+	}
+
+	pricingMu.Lock()
+	pricing = resolved
+	pricingMu.Unlock()
+}
+
+// lookupPricing returns the resolved price for a provider and model
+func lookupPricing(provider, model string) (config.ModelPricing, bool) {
+	pricingMu.RLock()
+	defer pricingMu.RUnlock()
+
+	price, ok := pricing[provider+":"+model]
+	return price, ok
+}
+
+// completionCost returns the cost of a completion at the resolved price of a
+// provider's model. A model without a price costs 0, with a warning the first
+// time, rather than being priced as some other model.
+func completionCost(provider, model string, promptTokens, completionTokens int) float64 {
+	price, ok := lookupPricing(provider, model)
+	if !ok {
+		warnUnpriced(provider + ":" + model)
+		return 0
+	}
+	return calculateCost(price, promptTokens, completionTokens)
+}
+
+// warnUnpriced warns once per model that its cost is unknown
+func warnUnpriced(id string) {
+	pricingMu.Lock()
+	warned := unpricedModels[id]
+	unpricedModels[id] = true
+	pricingMu.Unlock()
+
+	if !warned {
+		warn.Printf("no price known for %s; its cost is reported as $0 (set it under pricing: in the config)", id)
+	}
+}
+
+// calculateCost calculates the cost of a completion from the resolved pricing table
+func calculateCost(price config.ModelPricing, promptTokens, completionTokens int) float64 {
+	return (float64(promptTokens) * price.Prompt / 1000) + (float64(completionTokens) * price.Completion / 1000)
+}
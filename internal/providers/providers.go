@@ -2,11 +2,15 @@ package providers
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"github.com/sashabaranov/go-openai"
+	"math"
+	"net/http"
 	"os"
+	"promptguard/internal/config"
 	"strings"
-	"github.com/sashabaranov/go-openai"
-	"promptgaurd/internal/config"
+	"time"
 )
 
 // Response represents a provider response
@@ -16,11 +20,38 @@ type Response struct {
 	Tokens   int     `json:"tokens"`
 	Provider string  `json:"provider"`
 	Model    string  `json:"model"`
+	// Confidence is the average token probability of the response, set only
+	// when logprobs were requested and the provider returned them
+	Confidence *float64 `json:"confidence,omitempty"`
+	// Latencies holds the duration of each provider call made for the test,
+	// one per repetition; set by the runner
+	Latencies []time.Duration `json:"latencies,omitempty"`
+	// Endpoint is the base URL that served the response, for providers
+	// that can fail over between several endpoints
+	Endpoint string `json:"endpoint,omitempty"`
+	// FinishReason is why the provider stopped generating, when reported
+	FinishReason string `json:"finishReason,omitempty"`
+	// Filtered is set when the provider's content filter blocked or cut
+	// short the response
+	Filtered bool `json:"filtered,omitempty"`
+	// ToolCalls holds the functions the model called, for providers given
+	// a tools setting
+	ToolCalls []ToolCall `json:"toolCalls,omitempty"`
+}
+
+// contentFilterReason is the finish reason and error code providers use
+// when their content filter blocks a response
+const contentFilterReason = "content_filter"
+
+// Message represents a chat message sent to a provider
+type Message struct {
+	Role    string `json:"role"` // system, user, or assistant
+	Content string `json:"content"`
 }
 
 // Client interface for LLM providers
 type Client interface {
-	Complete(ctx context.Context, prompt string) (*Response, error)
+	Complete(ctx context.Context, messages []Message) (*Response, error)
 	GetName() string
 	GetModel() string
 }
@@ -44,66 +75,136 @@ func NewClient(provider *config.Provider) (Client, error) {
 		return NewMistralClient(model, provider.Config)
 	case "ollama":
 		return NewOllamaClient(model, provider.Config)
+	case "cohere":
+		return NewCohereClient(model, provider.Config)
+	case "mock":
+		return NewMockClient(model, provider.Config)
 	default:
 		return nil, fmt.Errorf("unsupported provider: %s", providerName)
 	}
 }
 
+// openAIBaseURL is the default OpenAI API endpoint
+const openAIBaseURL = "https://api.openai.com/v1"
+
 // OpenAIClient implements the OpenAI provider
 type OpenAIClient struct {
-	client *openai.Client
-	model  string
-	config map[string]interface{}
+	clients    map[string]*openai.Client // SDK client per endpoint
+	endpoints  []string
+	httpClient *http.Client
+	apiKey     string
+	orgID      string
+	model      string
+	config     map[string]interface{}
 }
 
 // NewOpenAIClient creates a new OpenAI client
 func NewOpenAIClient(model string, config map[string]interface{}) (*OpenAIClient, error) {
-	apiKey := os.Getenv("OPENAI_API_KEY")
-	if apiKey == "" {
-		return nil, fmt.Errorf("OPENAI_API_KEY environment variable not set")
+	apiKey, err := apiKeySetting(config, "OPENAI_API_KEY")
+	if err != nil {
+		return nil, err
 	}
 
-	client := openai.NewClient(apiKey)
+	// org_id sends the OpenAI-Organization header, for keys that belong to
+	// several organizations
+	orgID, _ := config["org_id"].(string)
+
+	httpClient := newHTTPClient(config)
+	endpoints := endpointsSetting(config, openAIBaseURL)
+	clients := make(map[string]*openai.Client, len(endpoints))
+	for _, endpoint := range endpoints {
+		clientConfig := openai.DefaultConfig(apiKey)
+		clientConfig.BaseURL = endpoint
+		clientConfig.OrgID = orgID
+		clientConfig.HTTPClient = httpClient
+		clients[endpoint] = openai.NewClientWithConfig(clientConfig)
+	}
 
 	return &OpenAIClient{
-		client: client,
-		model:  model,
-		config: config,
+		clients:    clients,
+		endpoints:  endpoints,
+		httpClient: httpClient,
+		apiKey:     apiKey,
+		orgID:      orgID,
+		model:      model,
+		config:     config,
 	}, nil
 }
 
 // Complete executes a prompt completion
-func (c *OpenAIClient) Complete(ctx context.Context, prompt string) (*Response, error) {
-	// Get temperature from config, default to 0
+func (c *OpenAIClient) Complete(ctx context.Context, messages []Message) (*Response, error) {
+	req := c.request(messages)
+	return withFailover(ctx, c.endpoints, func(endpoint string) (*Response, error) {
+		return c.complete(ctx, endpoint, req)
+	})
+}
+
+// request builds the chat completion request for messages from the
+// provider's generation settings
+func (c *OpenAIClient) request(messages []Message) openai.ChatCompletionRequest {
+	// Get temperature from config, default to 0. go-openai omits a zero
+	// temperature, which the API reads as 1, so 0 is sent as the smallest
+	// float32 instead.
 	temperature := float32(0)
-	if temp, ok := c.config["temperature"]; ok {
-		if tempFloat, ok := temp.(float64); ok {
-			temperature = float32(tempFloat)
-		}
+	if temp, ok := numberSetting(c.config, "temperature"); ok {
+		temperature = float32(temp)
 	}
-
-	// Get max tokens from config
-	maxTokens := 1000
-	if tokens, ok := c.config["max_tokens"]; ok {
-		if tokensInt, ok := tokens.(int); ok {
-			maxTokens = tokensInt
-		}
+	if temperature == 0 {
+		temperature = math.SmallestNonzeroFloat32
 	}
 
 	req := openai.ChatCompletionRequest{
 		Model:       c.model,
-		Temperature: &temperature,
-		MaxTokens:   maxTokens,
-		Messages: []openai.ChatCompletionMessage{
-			{
-				Role:    openai.ChatMessageRoleUser,
-				Content: prompt,
-			},
-		},
+		Temperature: temperature,
+		MaxTokens:   maxTokensSetting(c.config),
+		Stop:        stopSetting(c.config),
+		Messages:    make([]openai.ChatCompletionMessage, 0, len(messages)),
+	}
+	if topP, ok := numberSetting(c.config, "top_p"); ok {
+		req.TopP = float32(topP)
+	}
+	if penalty, ok := numberSetting(c.config, "frequency_penalty"); ok {
+		req.FrequencyPenalty = float32(penalty)
+	}
+	if penalty, ok := numberSetting(c.config, "presence_penalty"); ok {
+		req.PresencePenalty = float32(penalty)
+	}
+
+	// Pass a seed through for reproducible sampling
+	if seed, ok := intSetting(c.config, "seed"); ok {
+		req.Seed = &seed
+	}
+
+	// Offer the configured functions for the model to call
+	if tools := toolsSetting(c.config); len(tools) > 0 {
+		req.Tools = tools
+		req.ToolChoice = toolChoiceSetting(c.config)
 	}
 
-	resp, err := c.client.CreateChatCompletion(ctx, req)
+	for _, message := range messages {
+		req.Messages = append(req.Messages, openai.ChatCompletionMessage{
+			Role:    message.Role,
+			Content: message.Content,
+		})
+	}
+
+	return req
+}
+
+// complete sends a chat completion request to a single endpoint
+func (c *OpenAIClient) complete(ctx context.Context, endpoint string, req openai.ChatCompletionRequest) (*Response, error) {
+	// Request token logprobs when enabled in config
+	if logprobs, ok := c.config["logprobs"].(bool); ok && logprobs {
+		return c.completeWithLogprobs(ctx, endpoint, req)
+	}
+
+	resp, err := c.clients[endpoint].CreateChatCompletion(ctx, req)
 	if err != nil {
+		// Azure OpenAI rejects filtered prompts with a content_filter error
+		var apiErr *openai.APIError
+		if errors.As(err, &apiErr) && apiErr.Code == contentFilterReason {
+			return c.filteredResponse(), nil
+		}
 		return nil, fmt.Errorf("OpenAI API error: %w", err)
 	}
 
@@ -111,18 +212,32 @@ func (c *OpenAIClient) Complete(ctx context.Context, prompt string) (*Response,
 		return nil, fmt.Errorf("no completion choices returned")
 	}
 
-	// Calculate cost (simplified - would need actual pricing)
-	cost := calculateOpenAICost(c.model, resp.Usage.PromptTokens, resp.Usage.CompletionTokens)
+	cost := completionCost("openai", c.model, resp.Usage.PromptTokens, resp.Usage.CompletionTokens)
 
+	finishReason := string(resp.Choices[0].FinishReason)
 	return &Response{
-		Text:     resp.Choices[0].Message.Content,
-		Cost:     cost,
-		Tokens:   resp.Usage.TotalTokens,
-		Provider: "openai",
-		Model:    c.model,
+		Text:         resp.Choices[0].Message.Content,
+		Cost:         cost,
+		Tokens:       resp.Usage.TotalTokens,
+		Provider:     "openai",
+		Model:        c.model,
+		FinishReason: finishReason,
+		Filtered:     finishReason == contentFilterReason,
+		ToolCalls:    toolCalls(resp.Choices[0].Message.ToolCalls),
 	}, nil
 }
 
+// filteredResponse is the response recorded when the content filter
+// rejected the request outright
+func (c *OpenAIClient) filteredResponse() *Response {
+	return &Response{
+		Provider:     "openai",
+		Model:        c.model,
+		FinishReason: contentFilterReason,
+		Filtered:     true,
+	}
+}
+
 func (c *OpenAIClient) GetName() string {
 	return "openai"
 }
@@ -139,9 +254,8 @@ type AnthropicClient struct {
 
 // NewAnthropicClient creates a new Anthropic client
 func NewAnthropicClient(model string, config map[string]interface{}) (*AnthropicClient, error) {
-	apiKey := os.Getenv("ANTHROPIC_API_KEY")
-	if apiKey == "" {
-		return nil, fmt.Errorf("ANTHROPIC_API_KEY environment variable not set")
+	if _, err := apiKeySetting(config, "ANTHROPIC_API_KEY"); err != nil {
+		return nil, err
 	}
 
 	return &AnthropicClient{
@@ -150,7 +264,7 @@ func NewAnthropicClient(model string, config map[string]interface{}) (*Anthropic
 	}, nil
 }
 
-func (c *AnthropicClient) Complete(ctx context.Context, prompt string) (*Response, error) {
+func (c *AnthropicClient) Complete(ctx context.Context, messages []Message) (*Response, error) {
 	// TODO: Implement Anthropic API integration
 	return nil, fmt.Errorf("Anthropic provider not yet implemented")
 }
@@ -171,9 +285,8 @@ type MistralClient struct {
 
 // NewMistralClient creates a new Mistral client
 func NewMistralClient(model string, config map[string]interface{}) (*MistralClient, error) {
-	apiKey := os.Getenv("MISTRAL_API_KEY")
-	if apiKey == "" {
-		return nil, fmt.Errorf("MISTRAL_API_KEY environment variable not set")
+	if _, err := apiKeySetting(config, "MISTRAL_API_KEY"); err != nil {
+		return nil, err
 	}
 
 	return &MistralClient{
@@ -182,7 +295,7 @@ func NewMistralClient(model string, config map[string]interface{}) (*MistralClie
 	}, nil
 }
 
-func (c *MistralClient) Complete(ctx context.Context, prompt string) (*Response, error) {
+func (c *MistralClient) Complete(ctx context.Context, messages []Message) (*Response, error) {
 	// TODO: Implement Mistral API integration
 	return nil, fmt.Errorf("Mistral provider not yet implemented")
 }
@@ -195,58 +308,107 @@ func (c *MistralClient) GetModel() string {
 	return c.model
 }
 
-// OllamaClient implements the Ollama provider
-type OllamaClient struct {
-	model  string
-	config map[string]interface{}
-}
+// apiKeySetting returns the provider's API key: the api_key setting, else
+// the environment variable named by api_key_env, else envVar. Errors name
+// where the key was looked for, never the key itself.
+func apiKeySetting(config map[string]interface{}, envVar string) (string, error) {
+	if key, ok := config["api_key"].(string); ok && key != "" {
+		return key, nil
+	}
 
-// NewOllamaClient creates a new Ollama client
-func NewOllamaClient(model string, config map[string]interface{}) (*OllamaClient, error) {
-	apiKey := os.Getenv("OLLAMA_API_KEY")
-	if apiKey == "" {
-		return nil, fmt.Errorf("OLLAMA_API_KEY environment variable not set")
+	if name, ok := config["api_key_env"].(string); ok && name != "" {
+		if key := os.Getenv(name); key != "" {
+			return key, nil
+		}
+		return "", fmt.Errorf("%s environment variable (from api_key_env) not set", name)
 	}
 
-	return &OllamaClient{
-		model:  model,
-		config: config,
-	}, nil
+	if key := os.Getenv(envVar); key != "" {
+		return key, nil
+	}
+	return "", fmt.Errorf("%s environment variable not set (or set api_key or api_key_env in the provider config)", envVar)
+}
+
+// maxTokensSetting returns the max_tokens provider setting, or
+// defaultMaxTokens when unset
+func maxTokensSetting(config map[string]interface{}) int {
+	if tokens, ok := intSetting(config, "max_tokens"); ok {
+		return tokens
+	}
+	return defaultMaxTokens
 }
 
-func (c *OllamaClient) Complete(ctx context.Context, prompt string) (*Response, error) {
-	// TODO: Implement Ollama API integration
-	return nil, fmt.Errorf("Ollama provider not yet implemented")
+// numberSetting returns a numeric provider setting. YAML decodes whole
+// numbers as int and the others as float64, so both are accepted.
+func numberSetting(config map[string]interface{}, key string) (float64, bool) {
+	switch value := config[key].(type) {
+	case int:
+		return float64(value), true
+	case float64:
+		return value, true
+	}
+	return 0, false
 }
 
-func (c *OllamaClient) GetName() string {
-	return "ollama"
+// intSetting returns a whole-number provider setting, accepting a float64
+// without a fractional part, e.g. from a JSON-sourced config
+func intSetting(config map[string]interface{}, key string) (int, bool) {
+	value, ok := numberSetting(config, key)
+	if !ok || value != float64(int(value)) {
+		return 0, false
+	}
+	return int(value), true
 }
 
-func (c *OllamaClient) GetModel() string {
-	return c.model
+// stopSetting returns the stop provider setting, a sequence or a list of
+// sequences at which generation stops
+func stopSetting(config map[string]interface{}) []string {
+	switch stop := config["stop"].(type) {
+	case string:
+		return []string{stop}
+	case []interface{}:
+		sequences := make([]string, 0, len(stop))
+		for _, sequence := range stop {
+			if s, ok := sequence.(string); ok {
+				sequences = append(sequences, s)
+			}
+		}
+		return sequences
+	}
+	return nil
 }
 
-// calculateOpenAICost calculates the cost for OpenAI API usage
-func calculateOpenAICost(model string, promptTokens, completionTokens int) float64 {
-	// Simplified cost calculation - real implementation would use current pricing
-	var promptCost, completionCost float64
-
-	switch model {
-	case "gpt-4o":
-		promptCost = 0.005 / 1000     // $0.005 per 1K prompt tokens
-		completionCost = 0.015 / 1000 // $0.015 per 1K completion tokens
-	case "gpt-4":
-		promptCost = 0.03 / 1000      // $0.03 per 1K prompt tokens
-		completionCost = 0.06 / 1000  // $0.06 per 1K completion tokens
-	case "gpt-3.5-turbo":
-		promptCost = 0.0005 / 1000    // $0.0005 per 1K prompt tokens
-		completionCost = 0.0015 / 1000 // $0.0015 per 1K completion tokens
-	default:
-		// Default to GPT-3.5-turbo pricing
-		promptCost = 0.0005 / 1000
-		completionCost = 0.0015 / 1000
+// Moderate scores text with the OpenAI moderations endpoint, returning a
+// score between 0 and 1 per category. config holds the settings of an
+// OpenAI provider, for its API key and base URL; nil uses OPENAI_API_KEY.
+func Moderate(ctx context.Context, text string, config map[string]interface{}) (map[string]float64, error) {
+	apiKey, err := apiKeySetting(config, "OPENAI_API_KEY")
+	if err != nil {
+		return nil, err
 	}
 
-	return (float64(promptTokens) * promptCost) + (float64(completionTokens) * completionCost)
+	clientConfig := openai.DefaultConfig(apiKey)
+	clientConfig.BaseURL = endpointsSetting(config, openAIBaseURL)[0]
+	clientConfig.OrgID, _ = config["org_id"].(string)
+	clientConfig.HTTPClient = newHTTPClient(config)
+
+	resp, err := openai.NewClientWithConfig(clientConfig).Moderations(ctx, openai.ModerationRequest{Input: text})
+	if err != nil {
+		return nil, fmt.Errorf("OpenAI moderation error: %w", err)
+	}
+
+	if len(resp.Results) == 0 {
+		return nil, fmt.Errorf("no moderation results returned")
+	}
+
+	scores := resp.Results[0].CategoryScores
+	return map[string]float64{
+		"hate":             float64(scores.Hate),
+		"hate/threatening": float64(scores.HateThreatening),
+		"self-harm":        float64(scores.SelfHarm),
+		"sexual":           float64(scores.Sexual),
+		"sexual/minors":    float64(scores.SexualMinors),
+		"violence":         float64(scores.Violence),
+		"violence/graphic": float64(scores.ViolenceGraphic),
+	}, nil
 }
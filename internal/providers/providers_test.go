@@ -0,0 +1,157 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+	"promptguard/internal/config"
+)
+
+// TestMaxTokensReachesRequest sends a completion with max_tokens set in the
+// provider config, as YAML and JSON configs decode it, and checks the value
+// in the request the OpenAI API receives
+func TestMaxTokensReachesRequest(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings string
+		want     int
+	}{
+		{"YAML whole number", "max_tokens: 256", 256},
+		{"JSON number", `{"max_tokens": 256.0}`, 256},
+		{"unset", "temperature: 0.2", defaultMaxTokens},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var received struct {
+				MaxTokens int `json:"max_tokens"`
+			}
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if err := json.NewDecoder(r.Body).Decode(&received); err != nil {
+					t.Errorf("decoding request: %v", err)
+				}
+				w.Header().Set("Content-Type", "application/json")
+				w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"hi"},"finish_reason":"stop"}],"usage":{"prompt_tokens":1,"completion_tokens":1,"total_tokens":2}}`))
+			}))
+			defer server.Close()
+
+			var settings map[string]interface{}
+			if err := yaml.Unmarshal([]byte(tt.settings), &settings); err != nil {
+				t.Fatal(err)
+			}
+			settings["api_key"] = "test-key"
+			settings["base_url"] = server.URL
+
+			client, err := NewOpenAIClient("gpt-4o-mini", settings)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := client.Complete(context.Background(), []Message{{Role: "user", Content: "hello"}}); err != nil {
+				t.Fatal(err)
+			}
+
+			if received.MaxTokens != tt.want {
+				t.Errorf("max_tokens = %d, want %d", received.MaxTokens, tt.want)
+			}
+		})
+	}
+}
+
+// TestOllamaNeedsNoAPIKey builds an Ollama provider with no key set
+// anywhere, since local Ollama servers do not authenticate
+func TestOllamaNeedsNoAPIKey(t *testing.T) {
+	t.Setenv("OLLAMA_API_KEY", "")
+
+	client, err := NewClient(&config.Provider{ID: "ollama:llama3"})
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+
+	ollama, ok := client.(*OllamaClient)
+	if !ok {
+		t.Fatalf("NewClient() = %T, want *OllamaClient", client)
+	}
+	if len(ollama.endpoints) != 1 || ollama.endpoints[0] != "http://localhost:11434" {
+		t.Errorf("endpoints = %v, want the local Ollama server", ollama.endpoints)
+	}
+	if ollama.GetModel() != "llama3" {
+		t.Errorf("model = %s, want llama3", ollama.GetModel())
+	}
+}
+
+// TestModerateUsesProviderKey checks that the moderations endpoint is called
+// with the key named by the provider's api_key_env rather than OPENAI_API_KEY
+func TestModerateUsesProviderKey(t *testing.T) {
+	t.Setenv("OPENAI_API_KEY", "")
+	t.Setenv("TEAM_OPENAI_KEY", "team-key")
+
+	var authorization string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization = r.Header.Get("Authorization")
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"results":[{"category_scores":{"hate":0.1,"violence":0.7}}]}`))
+	}))
+	defer server.Close()
+
+	scores, err := Moderate(context.Background(), "hello", map[string]interface{}{
+		"api_key_env": "TEAM_OPENAI_KEY",
+		"base_url":    server.URL,
+	})
+	if err != nil {
+		t.Fatalf("Moderate() error = %v", err)
+	}
+	if authorization != "Bearer team-key" {
+		t.Errorf("Authorization = %q, want the key from api_key_env", authorization)
+	}
+	if scores["violence"] < 0.69 || scores["violence"] > 0.71 {
+		t.Errorf("violence score = %v, want 0.7", scores["violence"])
+	}
+
+	if _, err := Moderate(context.Background(), "hello", nil); err == nil {
+		t.Error("Moderate() with no key set anywhere succeeded, want an error")
+	}
+}
+
+// TestZeroTemperatureIsSent checks that a temperature of 0, set or by
+// default, is in the request body rather than omitted, which the API would
+// read as its default of 1
+func TestZeroTemperatureIsSent(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings map[string]interface{}
+	}{
+		{"default", map[string]interface{}{}},
+		{"explicit zero", map[string]interface{}{"temperature": 0}},
+		{"explicit zero float", map[string]interface{}{"temperature": 0.0}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.settings["api_key"] = "test-key"
+			client, err := NewOpenAIClient("gpt-4o-mini", tt.settings)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			body, err := json.Marshal(client.request([]Message{{Role: "user", Content: "hello"}}))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var fields map[string]interface{}
+			if err := json.Unmarshal(body, &fields); err != nil {
+				t.Fatal(err)
+			}
+			temperature, ok := fields["temperature"].(float64)
+			if !ok {
+				t.Fatalf("request body has no temperature: %s", body)
+			}
+			if temperature > 1e-6 {
+				t.Errorf("temperature = %v, want 0", temperature)
+			}
+		})
+	}
+}
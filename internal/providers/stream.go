@@ -0,0 +1,256 @@
+package providers
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// StreamFunc receives each piece of a response as it is generated
+type StreamFunc func(delta string)
+
+// Streamer is implemented by clients that can stream a response as it is
+// generated
+type Streamer interface {
+	CompleteStream(ctx context.Context, messages []Message, onDelta StreamFunc) (*Response, error)
+}
+
+// CompleteStream streams the response of clients that implement Streamer.
+// Other clients complete as usual and pass the whole text as a single delta.
+// Either way the returned response is complete, with its tokens and cost.
+func CompleteStream(ctx context.Context, client Client, messages []Message, onDelta StreamFunc) (*Response, error) {
+	if streamer, ok := client.(Streamer); ok {
+		return streamer.CompleteStream(ctx, messages, onDelta)
+	}
+	return completeWhole(ctx, client, messages, onDelta)
+}
+
+// completeWhole completes without streaming and passes the whole text to
+// onDelta
+func completeWhole(ctx context.Context, client Client, messages []Message, onDelta StreamFunc) (*Response, error) {
+	resp, err := client.Complete(ctx, messages)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Text != "" {
+		onDelta(resp.Text)
+	}
+	return resp, nil
+}
+
+// streamRequest is a chat completion request that streams its response and
+// reports usage in the final chunk
+type streamRequest struct {
+	openai.ChatCompletionRequest
+	StreamOptions struct {
+		IncludeUsage bool `json:"include_usage"`
+	} `json:"stream_options"`
+}
+
+// streamChunk holds the parts of a streamed chat completion chunk used here
+type streamChunk struct {
+	Choices []struct {
+		Delta struct {
+			Content string `json:"content"`
+		} `json:"delta"`
+		FinishReason string `json:"finish_reason"`
+	} `json:"choices"`
+	Usage *openai.Usage `json:"usage"`
+}
+
+// CompleteStream executes a chat completion, passing each content delta to
+// onDelta as it arrives. Responses with logprobs or tools are not streamed.
+func (c *OpenAIClient) CompleteStream(ctx context.Context, messages []Message, onDelta StreamFunc) (*Response, error) {
+	if logprobs, ok := c.config["logprobs"].(bool); ok && logprobs {
+		return completeWhole(ctx, c, messages, onDelta)
+	}
+	if len(toolsSetting(c.config)) > 0 {
+		return completeWhole(ctx, c, messages, onDelta)
+	}
+
+	req := c.request(messages)
+	req.Stream = true
+	return withFailover(ctx, c.endpoints, func(endpoint string) (*Response, error) {
+		return c.stream(ctx, endpoint, req, onDelta)
+	})
+}
+
+// stream sends a streaming chat completion request to a single endpoint. The
+// SDK's stream type does not report usage, so the events are read directly.
+func (c *OpenAIClient) stream(ctx context.Context, endpoint string, req openai.ChatCompletionRequest, onDelta StreamFunc) (*Response, error) {
+	streamReq := streamRequest{ChatCompletionRequest: req}
+	streamReq.StreamOptions.IncludeUsage = true
+
+	body, err := json.Marshal(streamReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint+"/chat/completions", bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Accept", "text/event-stream")
+	httpReq.Header.Set("Authorization", "Bearer "+c.apiKey)
+	if c.orgID != "" {
+		httpReq.Header.Set("OpenAI-Organization", c.orgID)
+	}
+
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("OpenAI API error: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var errResp openai.ErrorResponse
+		if json.NewDecoder(resp.Body).Decode(&errResp) == nil && errResp.Error != nil && errResp.Error.Code == contentFilterReason {
+			return c.filteredResponse(), nil
+		}
+		return nil, fmt.Errorf("OpenAI API returned status %d", resp.StatusCode)
+	}
+
+	var text strings.Builder
+	var usage *openai.Usage
+	var finishReason string
+	chunks := 0
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data:")
+		if !ok {
+			continue
+		}
+		data = strings.TrimSpace(data)
+		if data == "[DONE]" {
+			break
+		}
+
+		var chunk streamChunk
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return nil, fmt.Errorf("failed to decode OpenAI stream: %w", err)
+		}
+		if chunk.Usage != nil {
+			usage = chunk.Usage
+		}
+		for _, choice := range chunk.Choices {
+			if choice.Delta.Content != "" {
+				chunks++
+				text.WriteString(choice.Delta.Content)
+				onDelta(choice.Delta.Content)
+			}
+			if choice.FinishReason != "" {
+				finishReason = choice.FinishReason
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("OpenAI stream interrupted: %w", err)
+	}
+
+	// Compatible servers may ignore stream_options; each content chunk is
+	// then counted as one token and the prompt is estimated
+	if usage == nil {
+		usage = &openai.Usage{PromptTokens: EstimateTokens(messagesOf(req)), CompletionTokens: chunks}
+		usage.TotalTokens = usage.PromptTokens + usage.CompletionTokens
+	}
+
+	return &Response{
+		Text:         text.String(),
+		Cost:         completionCost("openai", c.model, usage.PromptTokens, usage.CompletionTokens),
+		Tokens:       usage.TotalTokens,
+		Provider:     "openai",
+		Model:        c.model,
+		FinishReason: finishReason,
+		Filtered:     finishReason == contentFilterReason,
+	}, nil
+}
+
+// messagesOf converts the messages of a chat completion request back
+func messagesOf(req openai.ChatCompletionRequest) []Message {
+	messages := make([]Message, len(req.Messages))
+	for i, message := range req.Messages {
+		messages[i] = Message{Role: message.Role, Content: message.Content}
+	}
+	return messages
+}
+
+// CompleteStream executes a chat completion, passing each content delta to
+// onDelta as Ollama generates it
+func (c *OllamaClient) CompleteStream(ctx context.Context, messages []Message, onDelta StreamFunc) (*Response, error) {
+	jsonBody, err := c.requestBody(messages, true)
+	if err != nil {
+		return nil, err
+	}
+
+	return withFailover(ctx, c.endpoints, func(endpoint string) (*Response, error) {
+		return c.stream(ctx, endpoint, jsonBody, onDelta)
+	})
+}
+
+// stream posts a streaming chat request to a single Ollama endpoint, which
+// answers with one JSON object per line
+func (c *OllamaClient) stream(ctx context.Context, endpoint string, jsonBody []byte, onDelta StreamFunc) (*Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint+"/api/chat", bytes.NewReader(jsonBody))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("Ollama API request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Ollama API returned status %d", resp.StatusCode)
+	}
+
+	var text strings.Builder
+	tokens := 0
+	decoder := json.NewDecoder(resp.Body)
+	for {
+		var chunk struct {
+			Message         Message `json:"message"`
+			Done            bool    `json:"done"`
+			PromptEvalCount int     `json:"prompt_eval_count"`
+			EvalCount       int     `json:"eval_count"`
+		}
+		if err := decoder.Decode(&chunk); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("failed to decode Ollama stream: %w", err)
+		}
+
+		if chunk.Message.Content != "" {
+			text.WriteString(chunk.Message.Content)
+			onDelta(chunk.Message.Content)
+		}
+		if chunk.Done {
+			tokens = chunk.PromptEvalCount + chunk.EvalCount
+			break
+		}
+	}
+
+	if tokens == 0 {
+		tokens = len(strings.Fields(text.String())) // Approximate
+	}
+
+	// Ollama is free/local, so cost is 0
+	return &Response{
+		Text:     text.String(),
+		Tokens:   tokens,
+		Provider: "ollama",
+		Model:    c.model,
+	}, nil
+}
@@ -0,0 +1,75 @@
+package providers
+
+import (
+	"fmt"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// ToolCall is a function call the model made instead of, or alongside, a
+// text answer
+type ToolCall struct {
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"` // JSON object, as generated by the model
+}
+
+// String formats the call as name(arguments)
+func (t ToolCall) String() string {
+	return fmt.Sprintf("%s(%s)", t.Name, t.Arguments)
+}
+
+// toolsSetting returns the functions of a provider's tools setting, a list
+// of {name, description, parameters} with parameters a JSON schema of the
+// arguments. Entries without a name are skipped.
+func toolsSetting(config map[string]interface{}) []openai.Tool {
+	entries, _ := config["tools"].([]interface{})
+
+	tools := make([]openai.Tool, 0, len(entries))
+	for _, entry := range entries {
+		tool, ok := entry.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		name, _ := tool["name"].(string)
+		if name == "" {
+			continue
+		}
+
+		function := openai.FunctionDefinition{Name: name}
+		function.Description, _ = tool["description"].(string)
+		if parameters, ok := tool["parameters"].(map[string]interface{}); ok {
+			function.Parameters = parameters
+		} else {
+			function.Parameters = map[string]interface{}{"type": "object", "properties": map[string]interface{}{}}
+		}
+		tools = append(tools, openai.Tool{Type: openai.ToolTypeFunction, Function: function})
+	}
+	return tools
+}
+
+// toolChoiceSetting returns the tool_choice setting: "auto", "none", or
+// "required" as is, or any other name as the function the model must call
+func toolChoiceSetting(config map[string]interface{}) interface{} {
+	choice, _ := config["tool_choice"].(string)
+	switch choice {
+	case "":
+		return nil
+	case "auto", "none", "required":
+		return choice
+	default:
+		return openai.ToolChoice{Type: openai.ToolTypeFunction, Function: openai.ToolFunction{Name: choice}}
+	}
+}
+
+// toolCalls converts the function calls of an OpenAI message
+func toolCalls(calls []openai.ToolCall) []ToolCall {
+	if len(calls) == 0 {
+		return nil
+	}
+
+	converted := make([]ToolCall, 0, len(calls))
+	for _, call := range calls {
+		converted = append(converted, ToolCall{Name: call.Function.Name, Arguments: call.Function.Arguments})
+	}
+	return converted
+}
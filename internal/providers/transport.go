@@ -0,0 +1,82 @@
+package providers
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+)
+
+// DefaultUserAgent identifies PromptGuard traffic when settings.userAgent is
+// unset
+const DefaultUserAgent = "promptguard/0.1.0"
+
+var (
+	headersMu     sync.RWMutex
+	globalHeaders = map[string]string{"User-Agent": DefaultUserAgent}
+)
+
+// SetHeaders sets the User-Agent and the headers sent with every provider
+// request. An empty userAgent keeps DefaultUserAgent; a User-Agent entry in
+// headers takes precedence over both.
+func SetHeaders(userAgent string, headers map[string]string) {
+	if userAgent == "" {
+		userAgent = DefaultUserAgent
+	}
+
+	resolved := map[string]string{"User-Agent": userAgent}
+	for name, value := range headers {
+		resolved[http.CanonicalHeaderKey(name)] = value
+	}
+
+	headersMu.Lock()
+	globalHeaders = resolved
+	headersMu.Unlock()
+}
+
+// headerTransport adds the global headers, then the provider's own headers,
+// to every request, so provider headers override global ones of the same name
+type headerTransport struct {
+	base    http.RoundTripper
+	headers map[string]string // Provider headers
+}
+
+func (t *headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+
+	headersMu.RLock()
+	for name, value := range globalHeaders {
+		req.Header.Set(name, value)
+	}
+	headersMu.RUnlock()
+
+	for name, value := range t.headers {
+		req.Header.Set(name, value)
+	}
+
+	return t.base.RoundTrip(req)
+}
+
+// newHTTPClient returns the HTTP client used for a provider's requests,
+// sending the global headers and those of the provider's headers setting
+func newHTTPClient(config map[string]interface{}) *http.Client {
+	return &http.Client{
+		Transport: &headerTransport{
+			base:    http.DefaultTransport,
+			headers: headersSetting(config),
+		},
+	}
+}
+
+// headersSetting returns the headers provider setting
+func headersSetting(config map[string]interface{}) map[string]string {
+	settings, ok := config["headers"].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+
+	headers := make(map[string]string, len(settings))
+	for name, value := range settings {
+		headers[name] = fmt.Sprint(value)
+	}
+	return headers
+}
@@ -0,0 +1,56 @@
+package reporter
+
+import (
+	"fmt"
+	"os"
+)
+
+// Color is an ANSI color escape code
+type Color string
+
+// Colors used for console output
+const (
+	Red    Color = "\033[31m"
+	Green  Color = "\033[32m"
+	Yellow Color = "\033[33m"
+)
+
+const ansiReset = "\033[0m"
+
+// Color modes accepted by ColorEnabled
+const (
+	ColorAuto   = "auto"
+	ColorAlways = "always"
+	ColorNever  = "never"
+)
+
+// ColorEnabled resolves a color mode for output written to out. In auto
+// mode, color is used only when out is a terminal and NO_COLOR is unset, so
+// piped output and CI logs stay plain.
+func ColorEnabled(mode string, out *os.File) (bool, error) {
+	switch mode {
+	case ColorAlways:
+		return true, nil
+	case ColorNever:
+		return false, nil
+	case ColorAuto, "":
+		if os.Getenv("NO_COLOR") != "" || os.Getenv("TERM") == "dumb" {
+			return false, nil
+		}
+		info, err := out.Stat()
+		if err != nil {
+			return false, nil
+		}
+		return info.Mode()&os.ModeCharDevice != 0, nil
+	default:
+		return false, fmt.Errorf("invalid color mode %q (expected auto, always, or never)", mode)
+	}
+}
+
+// Paint wraps s in color when enabled
+func Paint(enabled bool, color Color, s string) string {
+	if !enabled {
+		return s
+	}
+	return string(color) + s + ansiReset
+}
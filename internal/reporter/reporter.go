@@ -1,23 +1,59 @@
 package reporter
 
 import (
+	"bufio"
+	"bytes"
+	_ "embed"
 	"encoding/json"
 	"encoding/xml"
 	"fmt"
 	"html/template"
+	"io"
 	"os"
 	"path/filepath"
-	"strings"	"time"
+	"strings"
+	"time"
 
-	"promptgaurd/internal/runner"
-	"promptgaurd/internal/diff"
+	"promptguard/internal/diff"
+	"promptguard/internal/runner"
 )
 
-// Reporter interface for different output formats
+// Reporter interface for different output formats. Reporters write to any
+// io.Writer, such as stdout, a file, or a buffer.
 type Reporter interface {
-	Generate(results *runner.Results, outputFile string) error
+	Write(w io.Writer, results *runner.Results) error
 }
 
+// Generate writes a report to outputFile, creating its directory, or to
+// stdout when outputFile is empty
+func Generate(r Reporter, results *runner.Results, outputFile string) error {
+	if outputFile == "" {
+		return r.Write(os.Stdout, results)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(outputFile), 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	file, err := os.Create(outputFile)
+	if err != nil {
+		return fmt.Errorf("failed to create file: %w", err)
+	}
+
+	if err := r.Write(file, results); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// DefaultMaxResponseChars is the longest response shown in HTML, markdown,
+// and JUnit reports when settings.maxResponseChars is unset
+const DefaultMaxResponseChars = 2000
+
+// Formats lists the output formats New supports
+var Formats = []string{"console", "json", "junit", "html", "markdown", "sarif"}
+
 // New creates a new reporter for the specified format
 func New(format string) Reporter {
 	switch format {
@@ -29,6 +65,8 @@ func New(format string) Reporter {
 		return &HTMLReporter{}
 	case "markdown":
 		return &MarkdownReporter{}
+	case "sarif":
+		return &SARIFReporter{}
 	case "console":
 		return &ConsoleReporter{}
 	default:
@@ -36,42 +74,108 @@ func New(format string) Reporter {
 	}
 }
 
-// JSONReporter outputs results in JSON format
-type JSONReporter struct{}
+// MachineReadable reports whether a format is meant to be parsed by other
+// tools, so nothing else may be written to the same stream
+func MachineReadable(format string) bool {
+	switch format {
+	case "json", "junit", "sarif":
+		return true
+	default:
+		return false
+	}
+}
+
+// JSONReporter outputs results in JSON format, indented for reading unless
+// Compact is set
+type JSONReporter struct {
+	Compact bool // Single-line JSON, for log ingestion
+}
 
-func (r *JSONReporter) Generate(results *runner.Results, outputFile string) error {
-	data, err := json.MarshalIndent(results, "", "  ")
+// Write streams the results to w one test at a time, so a large suite is
+// never held in memory as a whole encoded document
+func (r *JSONReporter) Write(w io.Writer, results *runner.Results) error {
+	// Everything but the tests is small; encode it with a placeholder
+	// where the tests are streamed in
+	header := *results
+	header.TestResults = nil
+	data, err := r.marshal(&header, "")
 	if err != nil {
 		return fmt.Errorf("failed to marshal JSON: %w", err)
 	}
-
-	if outputFile == "" {
-		fmt.Println(string(data))
-		return nil
+	placeholder := `"testResults": null`
+	if r.Compact {
+		placeholder = `"testResults":null`
+	}
+	head, tail, _ := bytes.Cut(data, []byte(placeholder))
+
+	bw := bufio.NewWriter(w)
+	bw.Write(head)
+	bw.WriteString(strings.TrimSuffix(placeholder, "null"))
+	if results.TestResults == nil {
+		bw.WriteString("null")
+	} else {
+		bw.WriteString("[")
+		for i := range results.TestResults {
+			if i > 0 {
+				bw.WriteString(",")
+			}
+			if !r.Compact {
+				bw.WriteString("\n    ")
+			}
+			test, err := r.marshal(&results.TestResults[i], "    ")
+			if err != nil {
+				return fmt.Errorf("failed to marshal JSON for test %s: %w", results.TestResults[i].Name, err)
+			}
+			bw.Write(test)
+		}
+		if !r.Compact && len(results.TestResults) > 0 {
+			bw.WriteString("\n  ")
+		}
+		bw.WriteString("]")
 	}
+	bw.Write(tail)
+	bw.WriteString("\n")
+	return bw.Flush()
+}
 
-	return os.WriteFile(outputFile, data, 0644)
+// marshal encodes v compactly or indented under prefix
+func (r *JSONReporter) marshal(v interface{}, prefix string) ([]byte, error) {
+	if r.Compact {
+		return json.Marshal(v)
+	}
+	return json.MarshalIndent(v, prefix, "  ")
 }
 
 // JUnitReporter outputs results in JUnit XML format
-type JUnitReporter struct{}
+type JUnitReporter struct {
+	MaxResponseChars int // Longest response in system-out; 0 shows it in full
+}
 
 type JUnitTestSuite struct {
-	XMLName   xml.Name        `xml:"testsuite"`
-	Name      string          `xml:"name,attr"`
-	Tests     int             `xml:"tests,attr"`
-	Failures  int             `xml:"failures,attr"`
-	Errors    int             `xml:"errors,attr"`
-	Time      string          `xml:"time,attr"`
-	TestCases []JUnitTestCase `xml:"testcase"`
+	XMLName    xml.Name        `xml:"testsuite"`
+	Name       string          `xml:"name,attr"`
+	Tests      int             `xml:"tests,attr"`
+	Failures   int             `xml:"failures,attr"`
+	Errors     int             `xml:"errors,attr"`
+	Skipped    int             `xml:"skipped,attr"`
+	Time       string          `xml:"time,attr"`
+	Properties []JUnitProperty `xml:"properties>property,omitempty"`
+	TestCases  []JUnitTestCase `xml:"testcase"`
+}
+
+type JUnitProperty struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
 }
 
 type JUnitTestCase struct {
-	Name      string           `xml:"name,attr"`
-	ClassName string           `xml:"classname,attr"`
-	Time      string           `xml:"time,attr"`
-	Failure   *JUnitFailure    `xml:"failure,omitempty"`
-	SystemOut string           `xml:"system-out,omitempty"`
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *JUnitFailure `xml:"failure,omitempty"`
+	Error     *JUnitFailure `xml:"error,omitempty"`
+	Skipped   *JUnitSkipped `xml:"skipped,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
 }
 
 type JUnitFailure struct {
@@ -79,13 +183,20 @@ type JUnitFailure struct {
 	Text    string `xml:",chardata"`
 }
 
-func (r *JUnitReporter) Generate(results *runner.Results, outputFile string) error {
+type JUnitSkipped struct {
+	Message string `xml:"message,attr,omitempty"`
+}
+
+func (r *JUnitReporter) Write(w io.Writer, results *runner.Results) error {
 	testSuite := JUnitTestSuite{
-		Name:     "PromptGuard Tests",
-		Tests:    results.Total,
-		Failures: results.Failed,
-		Errors:   0,
-		Time:     fmt.Sprintf("%.3f", results.Duration.Seconds()),
+		Name:    "PromptGuard Tests",
+		Tests:   results.Total,
+		Skipped: results.Skipped,
+		Time:    fmt.Sprintf("%.3f", results.Duration.Seconds()),
+	}
+
+	if results.Metadata.RunID != "" {
+		testSuite.Properties = append(testSuite.Properties, JUnitProperty{Name: "runId", Value: results.Metadata.RunID})
 	}
 
 	for _, testResult := range results.TestResults {
@@ -93,28 +204,37 @@ func (r *JUnitReporter) Generate(results *runner.Results, outputFile string) err
 			Name:      testResult.Name,
 			ClassName: testResult.PromptFile,
 			Time:      fmt.Sprintf("%.3f", testResult.Duration.Seconds()),
-			SystemOut: fmt.Sprintf("Provider: %s\nCost: $%.4f\nResponse: %s", 
-				testResult.Provider, testResult.Cost, testResult.Response),
+			SystemOut: fmt.Sprintf("Provider: %s\nCost: $%.4f\nResponse: %s",
+				testResult.Provider, testResult.Cost, runner.Preview(testResult.Response, r.MaxResponseChars)),
 		}
 
-		if testResult.Status == "failed" {
+		// A test that could not run, such as on a provider or prompt
+		// rendering error, is an <error>; <failure> is reserved for
+		// assertion mismatches
+		if testResult.Status == "skipped" {
+			testCase.Skipped = &JUnitSkipped{Message: testResult.SkipReason}
+		} else if testResult.Status == "failed" && testResult.Error != "" {
+			testSuite.Errors++
+			testCase.Error = &JUnitFailure{
+				Message: testResult.Error,
+				Text:    testResult.Error,
+			}
+		} else if testResult.Status == "failed" {
+			testSuite.Failures++
 			failureMessages := []string{}
 			for _, assertion := range testResult.Assertions {
-				if !assertion.Passed {
+				if assertion.Failed() {
 					failureMessages = append(failureMessages, assertion.Message)
 				}
 			}
-			
-			if len(failureMessages) > 0 || testResult.Error != "" {
-				message := strings.Join(failureMessages, "; ")
-				if testResult.Error != "" {
-					message = testResult.Error
-				}
-				
-				testCase.Failure = &JUnitFailure{
-					Message: message,
-					Text:    message,
-				}
+
+			message := strings.Join(failureMessages, "; ")
+			if message == "" {
+				message = "Test failed"
+			}
+			testCase.Failure = &JUnitFailure{
+				Message: message,
+				Text:    message,
 			}
 		}
 
@@ -126,152 +246,68 @@ func (r *JUnitReporter) Generate(results *runner.Results, outputFile string) err
 		return fmt.Errorf("failed to marshal XML: %w", err)
 	}
 
-	xmlData := append([]byte(xml.Header), data...)
+	_, err = fmt.Fprintln(w, xml.Header+string(data))
+	return err
+}
 
-	if outputFile == "" {
-		fmt.Println(string(xmlData))
-		return nil
-	}
+// reportTemplate is the built-in HTML report template
+//
+//go:embed templates/report.html
+var reportTemplate string
 
-	return os.WriteFile(outputFile, xmlData, 0644)
+// HTMLReporter generates an interactive HTML report
+type HTMLReporter struct {
+	// TemplateFile replaces the built-in template with a user-supplied
+	// html/template file, executed with the *runner.Results of the run
+	TemplateFile string
+	// MaxResponseChars is the longest response shown before truncating,
+	// through the template's preview function; 0 shows responses in full
+	MaxResponseChars int
 }
 
-// HTMLReporter generates an interactive HTML report
-type HTMLReporter struct{}
-
-func (r *HTMLReporter) Generate(results *runner.Results, outputFile string) error {
-	htmlTemplate := `<!DOCTYPE html>
-<html lang="en">
-<head>
-    <meta charset="UTF-8">
-    <meta name="viewport" content="width=device-width, initial-scale=1.0">
-    <title>PromptGuard Report</title>
-    <style>
-        body { font-family: -apple-system, BlinkMacSystemFont, 'Segoe UI', sans-serif; margin: 0; padding: 20px; background: #f5f5f5; }
-        .container { max-width: 1200px; margin: 0 auto; background: white; border-radius: 8px; box-shadow: 0 2px 10px rgba(0,0,0,0.1); overflow: hidden; }
-        .header { background: linear-gradient(135deg, #667eea 0%, #764ba2 100%); color: white; padding: 30px; text-align: center; }
-        .header h1 { margin: 0; font-size: 2.5em; }
-        .header .subtitle { opacity: 0.9; margin-top: 10px; }
-        .summary { display: grid; grid-template-columns: repeat(auto-fit, minmax(200px, 1fr)); gap: 20px; padding: 30px; background: #f8f9fa; }
-        .metric { text-align: center; }
-        .metric-value { font-size: 2em; font-weight: bold; margin-bottom: 5px; }
-        .metric-label { color: #666; text-transform: uppercase; font-size: 0.9em; letter-spacing: 1px; }
-        .passed { color: #28a745; }
-        .failed { color: #dc3545; }
-        .cost { color: #ffc107; }
-        .tests { padding: 30px; }
-        .test-item { border: 1px solid #e9ecef; border-radius: 6px; margin-bottom: 20px; overflow: hidden; }
-        .test-header { padding: 15px 20px; background: #f8f9fa; border-bottom: 1px solid #e9ecef; cursor: pointer; }
-        .test-header:hover { background: #e9ecef; }
-        .test-content { padding: 20px; display: none; }
-        .test-content.show { display: block; }
-        .status-badge { padding: 4px 12px; border-radius: 20px; font-size: 0.8em; font-weight: bold; text-transform: uppercase; }
-        .badge-passed { background: #d4edda; color: #155724; }
-        .badge-failed { background: #f8d7da; color: #721c24; }
-        .assertion { margin: 10px 0; padding: 10px; border-left: 4px solid #ccc; background: #f8f9fa; }
-        .assertion.passed { border-left-color: #28a745; }
-        .assertion.failed { border-left-color: #dc3545; }
-        .response { background: #f1f3f4; padding: 15px; border-radius: 4px; margin: 10px 0; white-space: pre-wrap; font-family: monospace; }
-    </style>
-</head>
-<body>
-    <div class="container">
-        <div class="header">
-            <h1>PromptGuard Report</h1>
-            <div class="subtitle">{{.Metadata.Timestamp}}</div>
-            {{if .Metadata.CommitSHA}}<div class="subtitle">Commit: {{.Metadata.CommitSHA}}</div>{{end}}
-        </div>
-        
-        <div class="summary">
-            <div class="metric">
-                <div class="metric-value passed">{{.Passed}}</div>
-                <div class="metric-label">Passed</div>
-            </div>
-            <div class="metric">
-                <div class="metric-value failed">{{.Failed}}</div>
-                <div class="metric-label">Failed</div>
-            </div>
-            <div class="metric">
-                <div class="metric-value">{{.Total}}</div>
-                <div class="metric-label">Total</div>
-            </div>
-            <div class="metric">
-                <div class="metric-value cost">${{printf "%.4f" .TotalCost}}</div>
-                <div class="metric-label">Cost</div>
-            </div>
-        </div>
-
-        <div class="tests">
-            <h2>Test Results</h2>
-            {{range $index, $test := .TestResults}}
-            <div class="test-item">
-                <div class="test-header" onclick="toggleTest({{$index}})">
-                    <span style="font-weight: bold;">{{$test.Name}}</span>
-                    <span class="status-badge badge-{{$test.Status}}">{{$test.Status}}</span>
-                    <span style="float: right;">{{$test.Provider}} • ${{printf "%.4f" $test.Cost}}</span>
-                </div>
-                <div id="test-{{$index}}" class="test-content">
-                    {{if $test.Error}}
-                    <div class="assertion failed">
-                        <strong>Error:</strong> {{$test.Error}}
-                    </div>
-                    {{end}}
-                    
-                    {{range $test.Assertions}}
-                    <div class="assertion {{if .Passed}}passed{{else}}failed{{end}}">
-                        <strong>{{.Type}}:</strong> {{.Message}}
-                        {{if .Score}}<br><em>Score: {{printf "%.2f" .Score}}</em>{{end}}
-                    </div>
-                    {{end}}
-                    
-                    <div class="response">{{$test.Response}}</div>
-                </div>
-            </div>
-            {{end}}
-        </div>
-    </div>
-
-    <script>
-        function toggleTest(index) {
-            const content = document.getElementById('test-' + index);
-            content.classList.toggle('show');
-        }
-    </script>
-</body>
-</html>`
-
-	tmpl, err := template.New("html").Parse(htmlTemplate)
-	if err != nil {
-		return fmt.Errorf("failed to parse HTML template: %w", err)
+// template parses the HTML report template
+func (r *HTMLReporter) template() (*template.Template, error) {
+	text := reportTemplate
+	if r.TemplateFile != "" {
+		data, err := os.ReadFile(r.TemplateFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read HTML template: %w", err)
+		}
+		text = string(data)
 	}
 
-	if outputFile == "" {
-		return tmpl.Execute(os.Stdout, results)
+	funcs := template.FuncMap{
+		"preview": func(text string) string { return runner.Preview(text, r.MaxResponseChars) },
+		"percent": func(rate float64) string { return fmt.Sprintf("%.1f%%", rate*100) },
 	}
 
-	// Ensure directory exists
-	if err := os.MkdirAll(filepath.Dir(outputFile), 0755); err != nil {
-		return fmt.Errorf("failed to create directory: %w", err)
+	tmpl, err := template.New("html").Funcs(funcs).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse HTML template: %w", err)
 	}
+	return tmpl, nil
+}
 
-	file, err := os.Create(outputFile)
+func (r *HTMLReporter) Write(w io.Writer, results *runner.Results) error {
+	tmpl, err := r.template()
 	if err != nil {
-		return fmt.Errorf("failed to create file: %w", err)
+		return err
 	}
-	defer file.Close()
 
-	return tmpl.Execute(file, results)
+	return tmpl.Execute(w, results)
 }
 
 // MarkdownReporter generates a markdown report
-type MarkdownReporter struct{}
+type MarkdownReporter struct {
+	MaxResponseChars int // Longest response shown; 0 shows it in full
+}
 
-func (r *MarkdownReporter) Generate(results *runner.Results, outputFile string) error {
+func (r *MarkdownReporter) Write(w io.Writer, results *runner.Results) error {
 	var sb strings.Builder
 
 	// If there are failures, generate detailed diff analysis
 	if results.HasFailures() {
-		differ := &diff.MarkdownDiffer{}
+		differ := &diff.MarkdownDiffer{MaxResponseChars: r.MaxResponseChars}
 		diffContent := differ.GenerateFailureDiff(results)
 		sb.WriteString(diffContent)
 		sb.WriteString("\n---\n\n")
@@ -280,88 +316,171 @@ func (r *MarkdownReporter) Generate(results *runner.Results, outputFile string)
 	// Standard report content
 	sb.WriteString(fmt.Sprintf("# PromptGuard Report\n\n"))
 	sb.WriteString(fmt.Sprintf("**Generated:** %s\n", results.Metadata.Timestamp))
-	
+
+	if results.Metadata.RunID != "" {
+		sb.WriteString(fmt.Sprintf("**Run:** %s\n", results.Metadata.RunID))
+	}
+
 	if results.Metadata.CommitSHA != "" {
 		sb.WriteString(fmt.Sprintf("**Commit:** %s\n", results.Metadata.CommitSHA))
 	}
-	
+
 	sb.WriteString("\n## Summary\n\n")
 	sb.WriteString("| Metric | Value |\n")
 	sb.WriteString("|--------|-------|\n")
 	sb.WriteString(fmt.Sprintf("| Tests | %d |\n", results.Total))
 	sb.WriteString(fmt.Sprintf("| Passed | %d |\n", results.Passed))
 	sb.WriteString(fmt.Sprintf("| Failed | %d |\n", results.Failed))
+	if results.Warnings > 0 {
+		sb.WriteString(fmt.Sprintf("| Warnings | %d |\n", results.Warnings))
+	}
 	sb.WriteString(fmt.Sprintf("| Cost | $%.4f |\n", results.TotalCost))
+	if results.EvalCost > 0 {
+		sb.WriteString(fmt.Sprintf("| Eval cost | $%.4f |\n", results.EvalCost))
+	}
 	sb.WriteString(fmt.Sprintf("| Duration | %v |\n", results.Duration))
 
+	if primary, canary := results.CanaryComparison(); canary != nil {
+		sb.WriteString("\n## Canary vs Primary\n\n")
+		sb.WriteString("| Metric | Primary | Canary |\n")
+		sb.WriteString("|--------|---------|--------|\n")
+		sb.WriteString(fmt.Sprintf("| Tests | %d | %d |\n", primary.Total, canary.Total))
+		sb.WriteString(fmt.Sprintf("| Pass rate | %.1f%% | %.1f%% |\n", primary.PassRate*100, canary.PassRate*100))
+		sb.WriteString(fmt.Sprintf("| Avg cost | $%.4f | $%.4f |\n", primary.AverageCost, canary.AverageCost))
+		sb.WriteString(fmt.Sprintf("| Avg duration | %v | %v |\n", primary.AverageDuration, canary.AverageDuration))
+	}
+
+	if breakdown := results.ByProvider(); len(breakdown) > 1 {
+		sb.WriteString("\n## By Provider\n\n")
+		sb.WriteString("| Provider | Tests | Passed | Failed | Pass rate | Cost |\n")
+		sb.WriteString("|----------|-------|--------|--------|-----------|------|\n")
+		for _, stats := range breakdown {
+			sb.WriteString(fmt.Sprintf("| %s | %d | %d | %d | %.1f%% | $%.4f |\n",
+				stats.Provider, stats.Total, stats.Passed, stats.Failed, stats.PassRate*100, stats.Cost))
+		}
+	}
+
 	sb.WriteString("\n## Test Results\n\n")
-	
+
 	for _, test := range results.TestResults {
 		status := "✅"
-		if test.Status == "failed" {
+		switch test.Status {
+		case "failed":
 			status = "❌"
+		case "skipped":
+			status = "⏭️"
 		}
-		
+
 		sb.WriteString(fmt.Sprintf("### %s %s\n\n", status, test.Name))
 		sb.WriteString(fmt.Sprintf("- **Provider:** %s\n", test.Provider))
 		sb.WriteString(fmt.Sprintf("- **Cost:** $%.4f\n", test.Cost))
 		sb.WriteString(fmt.Sprintf("- **Duration:** %v\n", test.Duration))
-		
+		if test.Score > 0 {
+			sb.WriteString(fmt.Sprintf("- **Score:** %.2f\n", test.Score))
+		}
+		for _, call := range test.ToolCalls {
+			sb.WriteString(fmt.Sprintf("- **Tool call:** `%s`\n", call))
+		}
+
 		if test.Error != "" {
 			sb.WriteString(fmt.Sprintf("- **Error:** %s\n", test.Error))
 		}
-		
+		if test.SkipReason != "" {
+			sb.WriteString(fmt.Sprintf("- **Skipped:** %s\n", test.SkipReason))
+		}
+
 		sb.WriteString("\n**Assertions:**\n\n")
 		for _, assertion := range test.Assertions {
 			assertionStatus := "✅"
-			if !assertion.Passed {
+			if assertion.Warning {
+				assertionStatus = "⚠️"
+			} else if !assertion.Passed {
 				assertionStatus = "❌"
 			}
 			sb.WriteString(fmt.Sprintf("- %s **%s:** %s\n", assertionStatus, assertion.Type, assertion.Message))
 		}
-		
-		sb.WriteString("\n")
-	}
-
-	content := sb.String()
 
-	if outputFile == "" {
-		fmt.Print(content)
-		return nil
+		sb.WriteString("\n")
 	}
 
-	return os.WriteFile(outputFile, []byte(content), 0644)
+	_, err := io.WriteString(w, sb.String())
+	return err
 }
 
 // ConsoleReporter outputs results to the console
-type ConsoleReporter struct{}
+type ConsoleReporter struct {
+	Color   bool // Highlight passes, failures, and cost with ANSI colors
+	Verbose bool // List every test with its assertions, scores, tokens, and cost
+}
+
+func (r *ConsoleReporter) Write(w io.Writer, results *runner.Results) error {
+	fmt.Fprintf(w, "\n=== PromptGuard Test Results ===\n")
+	fmt.Fprintf(w, "Generated: %s\n", results.Metadata.Timestamp)
+
+	if results.Metadata.RunID != "" {
+		fmt.Fprintf(w, "Run: %s\n", results.Metadata.RunID)
+	}
 
-func (r *ConsoleReporter) Generate(results *runner.Results, outputFile string) error {
-	fmt.Printf("\n=== PromptGuard Test Results ===\n")
-	fmt.Printf("Generated: %s\n", results.Metadata.Timestamp)
-	
 	if results.Metadata.CommitSHA != "" {
-		fmt.Printf("Commit: %s\n", results.Metadata.CommitSHA)
+		fmt.Fprintf(w, "Commit: %s\n", results.Metadata.CommitSHA)
 	}
-	
-	fmt.Printf("\nSummary:\n")
-	fmt.Printf("  Tests: %d\n", results.Total)
-	fmt.Printf("  Passed: %d\n", results.Passed)
-	fmt.Printf("  Failed: %d\n", results.Failed)
-	fmt.Printf("  Cost: $%.4f\n", results.TotalCost)
-	fmt.Printf("  Duration: %v\n", results.Duration)
 
+	fmt.Fprintf(w, "\nSummary:\n")
+	fmt.Fprintf(w, "  Tests: %d\n", results.Total)
+	fmt.Fprintf(w, "  Passed: %s\n", Paint(r.Color, Green, fmt.Sprint(results.Passed)))
 	if results.Failed > 0 {
-		fmt.Printf("\nFailures:\n")
+		fmt.Fprintf(w, "  Failed: %s\n", Paint(r.Color, Red, fmt.Sprint(results.Failed)))
+	} else {
+		fmt.Fprintf(w, "  Failed: %d\n", results.Failed)
+	}
+	if results.Warnings > 0 {
+		fmt.Fprintf(w, "  Warnings: %s\n", Paint(r.Color, Yellow, fmt.Sprint(results.Warnings)))
+	}
+	fmt.Fprintf(w, "  Cost: %s\n", Paint(r.Color, Yellow, fmt.Sprintf("$%.4f", results.TotalCost)))
+	if results.EvalCost > 0 {
+		fmt.Fprintf(w, "  Eval cost: $%.4f\n", results.EvalCost)
+	}
+	fmt.Fprintf(w, "  Duration: %v\n", results.Duration)
+
+	if primary, canary := results.CanaryComparison(); canary != nil {
+		fmt.Fprintf(w, "\nCanary vs Primary:\n")
+		fmt.Fprintf(w, "  Pass rate: %.1f%% (%d tests) vs %.1f%% (%d tests)\n",
+			canary.PassRate*100, canary.Total, primary.PassRate*100, primary.Total)
+		fmt.Fprintf(w, "  Avg cost: $%.4f vs $%.4f\n", canary.AverageCost, primary.AverageCost)
+		fmt.Fprintf(w, "  Avg duration: %v vs %v\n", canary.AverageDuration, primary.AverageDuration)
+	}
+
+	if top := results.TopFailureReason(); top != nil {
+		tests := "tests"
+		if top.Count == 1 {
+			tests = "test"
+		}
+		fmt.Fprintf(w, "\nTop failure reason: %d %s: %s: %s\n", top.Count, tests, top.Type, top.Message)
+	}
+
+	if len(results.Flaky) > 0 {
+		fmt.Fprintf(w, "\nFlaky tests:\n")
+		for _, test := range results.Flaky {
+			fmt.Fprintf(w, "  ⚠️  %s\n", Paint(r.Color, Yellow, fmt.Sprintf("%s (%s): %.0f%% flip rate, passed %d of %d runs",
+				test.Name, test.Provider, test.Rate*100, test.Passed, test.Runs)))
+		}
+	}
+
+	if r.Verbose {
+		r.printTests(w, results)
+	}
+
+	if results.Failed > 0 {
+		fmt.Fprintf(w, "\nFailures:\n")
 		for _, test := range results.TestResults {
 			if test.Status == "failed" {
-				fmt.Printf("  ❌ %s\n", test.Name)
+				fmt.Fprintf(w, "  ❌ %s\n", Paint(r.Color, Red, test.Name))
 				if test.Error != "" {
-					fmt.Printf("     Error: %s\n", test.Error)
+					fmt.Fprintf(w, "     %s\n", Paint(r.Color, Red, "Error: "+test.Error))
 				}
 				for _, assertion := range test.Assertions {
-					if !assertion.Passed {
-						fmt.Printf("     %s: %s\n", assertion.Type, assertion.Message)
+					if assertion.Failed() {
+						fmt.Fprintf(w, "     %s\n", Paint(r.Color, Red, assertion.Type+": "+assertion.Message))
 					}
 				}
 			}
@@ -370,3 +489,51 @@ func (r *ConsoleReporter) Generate(results *runner.Results, outputFile string) e
 
 	return nil
 }
+
+// printTests lists every test with the outcome, score, and message of each
+// of its assertions
+func (r *ConsoleReporter) printTests(w io.Writer, results *runner.Results) {
+	fmt.Fprintf(w, "\nTests:\n")
+	for _, test := range results.TestResults {
+		icon, color := "✅", Green
+		switch test.Status {
+		case "failed":
+			icon, color = "❌", Red
+		case "skipped":
+			icon, color = "⏭️ ", Yellow
+		}
+
+		fmt.Fprintf(w, "  %s %s (%s): %s, %d tokens, %v\n", icon, Paint(r.Color, color, test.Name), test.Provider,
+			Paint(r.Color, Yellow, fmt.Sprintf("$%.4f", test.Cost)), test.Tokens, test.Duration.Round(time.Millisecond))
+		if test.Error != "" {
+			fmt.Fprintf(w, "     %s\n", Paint(r.Color, Red, "Error: "+test.Error))
+		}
+		if test.SkipReason != "" {
+			fmt.Fprintf(w, "     Skipped: %s\n", test.SkipReason)
+		}
+		if test.Score > 0 {
+			fmt.Fprintf(w, "     Score: %.2f\n", test.Score)
+		}
+		for _, call := range test.ToolCalls {
+			fmt.Fprintf(w, "     Tool call: %s\n", call)
+		}
+
+		for _, assertion := range test.Assertions {
+			mark, color := "✓", Green
+			if assertion.Warning {
+				mark, color = "!", Yellow
+			} else if !assertion.Passed {
+				mark, color = "✗", Red
+			}
+
+			line := mark + " " + assertion.Type
+			if assertion.Score != 0 {
+				line += fmt.Sprintf(" (score %.2f)", assertion.Score)
+			}
+			if assertion.Message != "" {
+				line += ": " + assertion.Message
+			}
+			fmt.Fprintf(w, "     %s\n", Paint(r.Color, color, line))
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package reporter
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+
+	"promptguard/internal/runner"
+)
+
+// sarifSchema is the JSON schema of the SARIF version emitted
+const sarifSchema = "https://json.schemastore.org/sarif-2.1.0.json"
+
+// errorRuleID is the rule of tests that failed before their assertions ran
+const errorRuleID = "execution-error"
+
+// SARIFReporter outputs failed assertions as SARIF 2.1.0 for code scanning
+// tools such as GitHub's. Each failed assertion becomes an error-level result,
+// or a warning for optional assertions, located in its prompt file with the
+// assertion type as the rule.
+type SARIFReporter struct {
+	// ToolVersion is reported as the driver version; the results' version
+	// is used when empty
+	ToolVersion string
+}
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name    string      `json:"name"`
+	Version string      `json:"version,omitempty"`
+	Rules   []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           sarifRegion           `json:"region"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine int `json:"startLine"`
+}
+
+func (r *SARIFReporter) Write(w io.Writer, results *runner.Results) error {
+	version := r.ToolVersion
+	if version == "" {
+		version = results.Metadata.Version
+	}
+
+	run := sarifRun{
+		Tool: sarifTool{Driver: sarifDriver{
+			Name:    "PromptGuard",
+			Version: version,
+			Rules:   []sarifRule{},
+		}},
+		Results: []sarifResult{},
+	}
+
+	rules := make(map[string]bool)
+	addResult := func(test runner.TestResult, ruleID, level, text string) {
+		rules[ruleID] = true
+		run.Results = append(run.Results, sarifResult{
+			RuleID:  ruleID,
+			Level:   level,
+			Message: sarifMessage{Text: fmt.Sprintf("%s (%s): %s", test.Name, test.Provider, text)},
+			Locations: []sarifLocation{{PhysicalLocation: sarifPhysicalLocation{
+				ArtifactLocation: sarifArtifactLocation{URI: filepath.ToSlash(test.PromptFile)},
+				Region:           sarifRegion{StartLine: 1},
+			}}},
+		})
+	}
+
+	// Failed optional assertions are reported as warnings, whether or not
+	// their test failed
+	for _, test := range results.TestResults {
+		if test.Status == "failed" && test.Error != "" {
+			addResult(test, errorRuleID, "error", test.Error)
+			continue
+		}
+		for _, assertion := range test.Assertions {
+			switch {
+			case assertion.Warning:
+				addResult(test, assertion.Type, "warning", assertion.Message)
+			case assertion.Failed() && test.Status == "failed":
+				addResult(test, assertion.Type, "error", assertion.Message)
+			}
+		}
+	}
+
+	ruleIDs := make([]string, 0, len(rules))
+	for id := range rules {
+		ruleIDs = append(ruleIDs, id)
+	}
+	sort.Strings(ruleIDs)
+	for _, id := range ruleIDs {
+		description := fmt.Sprintf("PromptGuard %s assertion failed", id)
+		if id == errorRuleID {
+			description = "PromptGuard test failed to run"
+		}
+		run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, sarifRule{
+			ID:               id,
+			ShortDescription: sarifMessage{Text: description},
+		})
+	}
+
+	data, err := json.MarshalIndent(sarifLog{
+		Schema:  sarifSchema,
+		Version: "2.1.0",
+		Runs:    []sarifRun{run},
+	}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal SARIF: %w", err)
+	}
+
+	_, err = fmt.Fprintln(w, string(data))
+	return err
+}
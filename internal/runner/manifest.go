@@ -0,0 +1,111 @@
+package runner
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+
+	"promptguard/internal/config"
+	"promptguard/internal/prompts"
+)
+
+// Manifest records everything needed to reproduce a run. Two runs with
+// identical manifests but different response hashes point to
+// nondeterminism in the provider rather than a change in the inputs.
+type Manifest struct {
+	RunID      string             `json:"runId"`
+	Version    string             `json:"version"`
+	GoVersion  string             `json:"goVersion"`
+	Platform   string             `json:"platform"`
+	Timestamp  string             `json:"timestamp"`
+	CommitSHA  string             `json:"commitSha,omitempty"`
+	ConfigHash string             `json:"configHash"`
+	Prompts    map[string]string  `json:"prompts"` // Prompt file to SHA-256 of its contents
+	Providers  []ManifestProvider `json:"providers"`
+	Responses  []ManifestResponse `json:"responses"`
+}
+
+// ManifestProvider records a provider with the settings sent to it
+type ManifestProvider struct {
+	ID     string                 `json:"id"`
+	Seed   interface{}            `json:"seed,omitempty"`
+	Config map[string]interface{} `json:"config,omitempty"`
+}
+
+// ManifestResponse records the hash of a single test's response
+type ManifestResponse struct {
+	Name         string `json:"name"`
+	PromptFile   string `json:"promptFile"`
+	Provider     string `json:"provider"`
+	ResponseHash string `json:"responseHash,omitempty"`
+}
+
+// NewManifest builds the manifest of a finished run from the prompts it loaded
+func NewManifest(cfg *config.Config, promptFiles map[string]*prompts.Prompt, results *Results) (*Manifest, error) {
+	configData, err := json.Marshal(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash config: %w", err)
+	}
+
+	manifest := &Manifest{
+		RunID:      results.Metadata.RunID,
+		Version:    results.Metadata.Version,
+		GoVersion:  runtime.Version(),
+		Platform:   runtime.GOOS + "/" + runtime.GOARCH,
+		Timestamp:  results.Metadata.Timestamp,
+		CommitSHA:  results.Metadata.CommitSHA,
+		ConfigHash: hashBytes(configData),
+		Prompts:    make(map[string]string, len(promptFiles)),
+		Providers:  make([]ManifestProvider, 0, len(cfg.Providers)),
+		Responses:  make([]ManifestResponse, 0, len(results.TestResults)),
+	}
+
+	for file, prompt := range promptFiles {
+		manifest.Prompts[file] = prompt.Checksum()
+	}
+
+	for _, provider := range cfg.Providers {
+		manifest.Providers = append(manifest.Providers, ManifestProvider{
+			ID:     provider.ID,
+			Seed:   provider.Config["seed"],
+			Config: provider.RedactedConfig(),
+		})
+	}
+
+	for _, result := range results.TestResults {
+		response := ManifestResponse{
+			Name:       result.Name,
+			PromptFile: result.PromptFile,
+			Provider:   result.Provider,
+		}
+		if result.Error == "" {
+			response.ResponseHash = hashBytes([]byte(result.Response))
+		}
+		manifest.Responses = append(manifest.Responses, response)
+	}
+
+	return manifest, nil
+}
+
+// Write saves the manifest as JSON, creating parent directories as needed
+func (m *Manifest) Write(path string) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal manifest: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create manifest directory: %w", err)
+	}
+
+	return os.WriteFile(path, data, 0644)
+}
+
+func hashBytes(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
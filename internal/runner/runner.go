@@ -2,33 +2,72 @@ package runner
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
-	"sync"	"time"
+	"hash/fnv"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
 
-	"promptgaurd/internal/config"
-	"promptgaurd/internal/prompts"
-	"promptgaurd/internal/providers"
-	"promptgaurd/internal/assertions"
-	"promptgaurd/internal/metrics"
+	"promptguard/internal/assertions"
+	"promptguard/internal/cache"
+	"promptguard/internal/config"
+	"promptguard/internal/prompts"
+	"promptguard/internal/providers"
+	"promptguard/internal/warn"
 )
 
 // Runner orchestrates prompt testing
 type Runner struct {
 	config  *config.Config
 	options Options
-	metrics *metrics.Store
+	renders *renderCache
+	// embedder embeds texts for assertions, once per distinct text in a run
+	embedder providers.Embedder
+	// moderation holds the settings of the first OpenAI provider in the
+	// config, for assertions that call the moderations endpoint
+	moderation map[string]interface{}
+}
+
+// ResultStore records finished runs, e.g. in the metrics database
+type ResultStore interface {
+	Store(results *Results) error
+}
+
+// renderCache holds rendered prompts within a run, keyed by prompt file and
+// variables, so that provider matrices don't render the same prompt twice
+type renderCache struct {
+	mu      sync.Mutex
+	entries map[string]renderedPrompt
+}
+
+type renderedPrompt struct {
+	messages []providers.Message
+	err      error
 }
 
 // Options configures the test runner
 type Options struct {
-	Parallel        int
-	UpdateBaseline  bool
-	Filters         []string
-	Verbose         bool
-	CIMode          bool
-	BaselinePath    string
-	CommitSHA       string
-	PRNumber        string
+	Parallel     int
+	Filters      []string
+	Verbose      bool
+	CIMode       bool
+	BaselinePath string
+	CommitSHA    string
+	PRNumber     string
+	RunID        string                   // Identifies the run in metrics and artifacts; generated if empty
+	ManifestPath string                   // Where to write the run manifest; empty disables it
+	PromptFiles  []string                 // Only run tests for these prompt files; empty runs all
+	Store        ResultStore              // Records each finished run; nil skips recording
+	CostBudget   float64                  // Overrides settings.costBudget when non-zero; negative disables it
+	FailFast     bool                     // Skip the tests not yet started once a test fails
+	Cache        *cache.Cache             // Reuses responses of earlier runs; nil always calls the provider
+	OnDelta      func(test, delta string) // Streams each response as it is generated; nil waits for whole responses
 }
 
 // Results contains test execution results
@@ -37,38 +76,92 @@ type Results struct {
 	Passed      int           `json:"passed"`
 	Failed      int           `json:"failed"`
 	Skipped     int           `json:"skipped"`
+	Warnings    int           `json:"warnings,omitempty"` // Failed optional assertions, which do not fail their tests
 	TotalCost   float64       `json:"totalCost"`
+	EvalCost    float64       `json:"evalCost,omitempty"`   // Assertion calls such as embeddings, included in TotalCost
+	CostBudget  float64       `json:"costBudget,omitempty"` // Budget the run was held to; 0 if none
+	OverBudget  bool          `json:"overBudget,omitempty"` // The cost budget was exceeded
 	Duration    time.Duration `json:"duration"`
 	TestResults []TestResult  `json:"testResults"`
+	Flaky       []FlakyTest   `json:"flaky,omitempty"` // Tests whose outcome flipped across recent runs
 	Metadata    Metadata      `json:"metadata"`
 }
 
 // TestResult represents a single test result
 type TestResult struct {
-	Name         string                 `json:"name"`
-	PromptFile   string                 `json:"promptFile"`
-	Provider     string                 `json:"provider"`
-	Variables    map[string]interface{} `json:"variables"`
-	Response     string                 `json:"response"`
-	Assertions   []AssertionResult      `json:"assertions"`
-	Cost         float64                `json:"cost"`
-	Duration     time.Duration          `json:"duration"`
-	Status       string                 `json:"status"` // passed, failed, skipped
-	Error        string                 `json:"error,omitempty"`
-}
-
-// AssertionResult represents a single assertion result
-type AssertionResult struct {
-	Type     string      `json:"type"`
-	Expected interface{} `json:"expected"`
-	Actual   interface{} `json:"actual"`
-	Passed   bool        `json:"passed"`
-	Score    float64     `json:"score,omitempty"`
-	Message  string      `json:"message,omitempty"`
+	Name       string                 `json:"name"`
+	PromptFile string                 `json:"promptFile"`
+	Provider   string                 `json:"provider"`
+	Variables  map[string]interface{} `json:"variables"`
+	Response   string                 `json:"response"`
+	Assertions []AssertionResult      `json:"assertions"`
+	Cost       float64                `json:"cost"`
+	EvalCost   float64                `json:"evalCost,omitempty"` // Calls made by assertions
+	Tokens     int                    `json:"tokens,omitempty"`   // Total across repetitions
+	Warnings   int                    `json:"warnings,omitempty"` // Failed optional assertions
+	Score      float64                `json:"score,omitempty"`    // Weighted share of assertions passed, with passThreshold
+	Duration   time.Duration          `json:"duration"`
+	Status     string                 `json:"status"` // passed, failed, skipped
+	Error      string                 `json:"error,omitempty"`
+	SkipReason string                 `json:"skipReason,omitempty"` // Why a skipped test did not run
+	Canary     bool                   `json:"canary,omitempty"`     // Routed to the canary provider
+	Endpoint   string                 `json:"endpoint,omitempty"`   // Base URL that served the response
+	Filtered   bool                   `json:"filtered,omitempty"`   // Blocked by the provider's content filter
+	Cached     bool                   `json:"cached,omitempty"`     // Response reused from the cache
+	// Conversation holds every message of a multi-turn test, each response
+	// included; Response is the last one
+	Conversation []providers.Message `json:"conversation,omitempty"`
+	// ToolCalls holds the functions the model called in its response
+	ToolCalls []providers.ToolCall `json:"toolCalls,omitempty"`
+}
+
+// AssertionResult is the result of a single assertion, defined with the
+// evaluators that produce it
+type AssertionResult = assertions.AssertionResult
+
+// FailureReason is a failure shared by one or more tests
+type FailureReason struct {
+	Type    string `json:"type"`    // Assertion type, or "error" for tests that did not run
+	Message string `json:"message"` // Message of the first test that failed this way
+	Count   int    `json:"count"`   // Number of tests that failed this way
+}
+
+// FlakyTest is a test whose pass/fail outcome changed between recent runs of
+// the same commit
+type FlakyTest struct {
+	Name       string  `json:"name"`
+	PromptFile string  `json:"promptFile"`
+	Provider   string  `json:"provider"`
+	Runs       int     `json:"runs"`   // Runs in which the test passed or failed
+	Passed     int     `json:"passed"` // Runs in which the test passed
+	Flips      int     `json:"flips"`  // Times the outcome changed from one run to the next
+	Rate       float64 `json:"rate"`   // Flips per pair of consecutive runs, from 0 to 1
+}
+
+// CanaryStats summarizes the tests that ran on one side of a canary rollout
+type CanaryStats struct {
+	Total           int           `json:"total"`
+	Passed          int           `json:"passed"`
+	Failed          int           `json:"failed"`
+	PassRate        float64       `json:"passRate"`
+	AverageCost     float64       `json:"averageCost"`
+	AverageDuration time.Duration `json:"averageDuration"`
+}
+
+// ProviderStats summarizes the tests that ran on one provider
+type ProviderStats struct {
+	Provider string  `json:"provider"`
+	Total    int     `json:"total"`
+	Passed   int     `json:"passed"`
+	Failed   int     `json:"failed"`
+	Skipped  int     `json:"skipped"`
+	PassRate float64 `json:"passRate"` // Passed share of the tests that ran, from 0 to 1
+	Cost     float64 `json:"cost"`
 }
 
 // Metadata contains test run metadata
 type Metadata struct {
+	RunID     string `json:"runId,omitempty"`
 	Timestamp string `json:"timestamp"`
 	CommitSHA string `json:"commitSha,omitempty"`
 	PRNumber  string `json:"prNumber,omitempty"`
@@ -78,10 +171,12 @@ type Metadata struct {
 
 // New creates a new test runner
 func New(cfg *config.Config, options Options) *Runner {
+	providers.SetPricing(cfg.PricingOverrides())
+	providers.SetHeaders(cfg.Settings.UserAgent, cfg.Settings.Headers)
+
 	return &Runner{
 		config:  cfg,
 		options: options,
-		metrics: metrics.NewStore(),
 	}
 }
 
@@ -89,9 +184,15 @@ func New(cfg *config.Config, options Options) *Runner {
 func (r *Runner) Run() (*Results, error) {
 	startTime := time.Now()
 
+	runID := r.options.RunID
+	if runID == "" {
+		runID = newRunID(startTime)
+	}
+
 	results := &Results{
 		TestResults: make([]TestResult, 0),
 		Metadata: Metadata{
+			RunID:     runID,
 			Timestamp: startTime.Format(time.RFC3339),
 			CommitSHA: r.options.CommitSHA,
 			PRNumber:  r.options.PRNumber,
@@ -99,11 +200,14 @@ func (r *Runner) Run() (*Results, error) {
 		},
 	}
 
-	// Load prompts
+	// Load prompts once per run; test cases share the parsed prompts
 	promptFiles, err := r.loadPrompts()
 	if err != nil {
 		return nil, fmt.Errorf("failed to load prompts: %w", err)
 	}
+	r.renders = &renderCache{entries: make(map[string]renderedPrompt)}
+	r.embedder = providers.NewCachingEmbedder(providers.NewOpenAIEmbedder(r.config.Settings.EmbeddingModel))
+	r.moderation = moderationSettings(r.config.Providers)
 
 	// Generate test cases
 	testCases := r.generateTestCases(promptFiles)
@@ -112,12 +216,28 @@ func (r *Runner) Run() (*Results, error) {
 	if len(r.options.Filters) > 0 {
 		testCases = r.filterTestCases(testCases)
 	}
+	if len(r.options.PromptFiles) > 0 {
+		testCases = r.filterPromptFiles(testCases)
+	}
 
 	results.Total = len(testCases)
 
+	budget := r.options.CostBudget
+	if budget == 0 {
+		budget = r.config.Settings.CostBudget
+	}
+	spending := &costTracker{budget: budget}
+	if budget > 0 {
+		results.CostBudget = budget
+	}
+
+	// Cancelling ctx stops new tests from starting; running tests finish
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
 	// Run tests with parallelization
 	testResults := make(chan TestResult, len(testCases))
-	
+
 	// Create worker pool
 	var wg sync.WaitGroup
 	semaphore := make(chan struct{}, r.options.Parallel)
@@ -126,10 +246,25 @@ func (r *Runner) Run() (*Results, error) {
 		wg.Add(1)
 		go func(tc TestCase) {
 			defer wg.Done()
-			semaphore <- struct{}{} // Acquire
+			semaphore <- struct{}{}        // Acquire
 			defer func() { <-semaphore }() // Release
 
+			// Tests already running when the budget is exceeded finish;
+			// the rest are skipped
+			if spending.exceeded() {
+				testResults <- skippedResult(tc, fmt.Sprintf("cost budget of $%.4f exceeded", budget))
+				return
+			}
+			if ctx.Err() != nil {
+				testResults <- skippedResult(tc, "fail-fast: an earlier test failed")
+				return
+			}
+
 			result := r.runSingleTest(tc)
+			spending.add(result.Cost + result.EvalCost)
+			if r.options.FailFast && result.Status == "failed" {
+				cancel()
+			}
 			testResults <- result
 		}(testCase)
 	}
@@ -143,7 +278,6 @@ func (r *Runner) Run() (*Results, error) {
 	// Collect results
 	for result := range testResults {
 		results.TestResults = append(results.TestResults, result)
-		results.TotalCost += result.Cost
 
 		switch result.Status {
 		case "passed":
@@ -155,11 +289,27 @@ func (r *Runner) Run() (*Results, error) {
 		}
 	}
 
+	results.tally()
+	results.OverBudget = spending.exceeded()
+
 	results.Duration = time.Since(startTime)
 
 	// Store metrics
-	if err := r.metrics.Store(results); err != nil {
-		fmt.Printf("Warning: failed to store metrics: %v\n", err)
+	if r.options.Store != nil {
+		if err := r.options.Store.Store(results); err != nil {
+			warn.Printf("failed to store metrics: %v", err)
+		}
+	}
+
+	// Write the reproducibility manifest
+	if r.options.ManifestPath != "" {
+		manifest, err := NewManifest(r.config, promptFiles, results)
+		if err == nil {
+			err = manifest.Write(r.options.ManifestPath)
+		}
+		if err != nil {
+			warn.Printf("failed to write manifest: %v", err)
+		}
 	}
 
 	return results, nil
@@ -172,19 +322,61 @@ type TestCase struct {
 	Provider   string
 	Variables  map[string]interface{}
 	Test       config.Test
+	Prompt     *prompts.Prompt `json:"-"`
+	Canary     bool
+}
+
+// TestCases returns the test cases a run would execute, sorted by prompt
+// file, name, and provider
+func (r *Runner) TestCases() ([]TestCase, error) {
+	promptFiles, err := r.loadPrompts()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load prompts: %w", err)
+	}
+
+	testCases := r.generateTestCases(promptFiles)
+	if len(r.options.Filters) > 0 {
+		testCases = r.filterTestCases(testCases)
+	}
+	if len(r.options.PromptFiles) > 0 {
+		testCases = r.filterPromptFiles(testCases)
+	}
+
+	sort.SliceStable(testCases, func(i, j int) bool {
+		a, b := testCases[i], testCases[j]
+		if a.PromptFile != b.PromptFile {
+			return a.PromptFile < b.PromptFile
+		}
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		return a.Provider < b.Provider
+	})
+
+	return testCases, nil
 }
 
 func (r *Runner) loadPrompts() (map[string]*prompts.Prompt, error) {
 	promptFiles := make(map[string]*prompts.Prompt)
 
 	for _, file := range r.config.Prompts {
-		prompt, err := prompts.LoadFromFile(file)
+		prompt, err := prompts.Load(file)
 		if err != nil {
 			return nil, fmt.Errorf("failed to load prompt %s: %w", file, err)
 		}
+		prompt.SetStrict(!r.config.Settings.AllowMissingVariables)
 		promptFiles[file] = prompt
 	}
 
+	for _, name := range r.config.InlinePromptNames() {
+		prompt, err := prompts.Parse(name, r.config.InlinePrompts[name])
+		if err != nil {
+			return nil, fmt.Errorf("failed to load inline prompt %s: %w", name, err)
+		}
+		prompt.SetStrict(!r.config.Settings.AllowMissingVariables)
+		promptFiles[name] = prompt
+	}
+
 	return promptFiles, nil
 }
 
@@ -193,35 +385,172 @@ func (r *Runner) generateTestCases(promptFiles map[string]*prompts.Prompt) []Tes
 
 	for promptFile, prompt := range promptFiles {
 		for i, test := range r.config.Tests {
-			// Determine provider
+			// Determine provider: the test's, then the prompt's frontmatter,
+			// then the first configured provider
 			provider := test.Provider
+			if provider == "" {
+				provider = prompt.Provider
+			}
 			if provider == "" && len(r.config.Providers) > 0 {
 				provider = r.config.Providers[0].ID
 			}
 
-			testName := test.Name
-			if testName == "" {
-				testName = fmt.Sprintf("%s_test_%d", promptFile, i)
+			baseName := test.Name
+			if baseName == "" {
+				baseName = fmt.Sprintf("%s_test_%d", promptFile, i)
 			}
 
-			testCases = append(testCases, TestCase{
-				Name:       testName,
-				PromptFile: promptFile,
-				Provider:   provider,
-				Variables:  test.Variables,
-				Test:       test,
-			})
+			for _, combination := range matrixCombinations(test) {
+				testName := baseName + combination.suffix
+				testProvider := provider
+
+				// Route a share of the default provider's runs to the
+				// canary. Tests and prompts that pin a provider are left alone.
+				canary := false
+				if c := r.config.Canary; c != nil && test.Provider == "" && prompt.Provider == "" && canaryBucket(promptFile, testName) < c.Weight {
+					testProvider = c.Provider
+					canary = true
+				}
+
+				testCases = append(testCases, TestCase{
+					Name:       testName,
+					PromptFile: promptFile,
+					Provider:   testProvider,
+					Variables:  combination.variables,
+					Test:       test,
+					Prompt:     prompt,
+					Canary:     canary,
+				})
+			}
 		}
 	}
 
 	return testCases
 }
 
+// matrixCombination is the variables of one combination of a test's matrix,
+// with the name suffix that identifies it, e.g. "[lang=fr]"
+type matrixCombination struct {
+	suffix    string
+	variables map[string]interface{}
+}
+
+// matrixCombinations expands a test's matrix into the Cartesian product of
+// its values, in the order the values are listed and with matrix variables
+// sorted by name. A test without a matrix has one combination of its own
+// variables.
+func matrixCombinations(test config.Test) []matrixCombination {
+	if len(test.Matrix) == 0 {
+		return []matrixCombination{{variables: test.Variables}}
+	}
+
+	names := make([]string, 0, len(test.Matrix))
+	for name := range test.Matrix {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	combinations := []matrixCombination{{variables: test.Variables}}
+	for _, name := range names {
+		var expanded []matrixCombination
+		for _, combination := range combinations {
+			for _, value := range test.Matrix[name] {
+				variables := make(map[string]interface{}, len(combination.variables)+1)
+				for k, v := range combination.variables {
+					variables[k] = v
+				}
+				variables[name] = value
+
+				label := fmt.Sprintf("%s=%v", name, value)
+				if combination.suffix != "" {
+					label = combination.suffix + "," + label
+				}
+				expanded = append(expanded, matrixCombination{suffix: label, variables: variables})
+			}
+		}
+		combinations = expanded
+	}
+
+	for i := range combinations {
+		combinations[i].suffix = "[" + combinations[i].suffix + "]"
+	}
+	return combinations
+}
+
+// CostEstimate is the estimated cost of a run before it starts
+type CostEstimate struct {
+	Calls    int     // Provider calls the run will make, including repetitions
+	Cost     float64 // Upper bound in USD for the calls with known pricing
+	Unpriced int     // Calls to models without known pricing, not included in Cost
+}
+
+// EstimateCost estimates the cost of running the selected test cases from
+// the rendered prompt sizes and each provider's max_tokens
+func (r *Runner) EstimateCost() (*CostEstimate, error) {
+	testCases, err := r.TestCases()
+	if err != nil {
+		return nil, err
+	}
+
+	estimate := &CostEstimate{}
+	for _, testCase := range testCases {
+		calls := testCase.Test.Repeat
+		if calls < 1 {
+			calls = 1
+		}
+		calls += len(testCase.Test.Conversation)
+		estimate.Calls += calls
+
+		// Tests that cannot render or resolve a provider fail without a call
+		messages, err := testCase.Prompt.Render(testCase.Variables)
+		if err != nil {
+			continue
+		}
+		providerConfig, err := r.providerConfig(testCase)
+		if err != nil {
+			continue
+		}
+
+		cost, ok := providers.EstimateCost(providerConfig, messages)
+		if !ok {
+			estimate.Unpriced += calls
+			continue
+		}
+		estimate.Cost += cost * float64(calls)
+	}
+
+	return estimate, nil
+}
+
+// canaryBucket maps a test case to a stable value in [0, 1), so the same
+// tests are routed to the canary on every run
+func canaryBucket(promptFile, testName string) float64 {
+	h := fnv.New64a()
+	h.Write([]byte(promptFile + "\x00" + testName))
+	return float64(h.Sum64()>>11) / (1 << 53)
+}
+
 func (r *Runner) filterTestCases(testCases []TestCase) []TestCase {
 	// TODO: Implement test filtering based on r.options.Filters
 	return testCases
 }
 
+// filterPromptFiles keeps the test cases of the prompt files in r.options.PromptFiles
+func (r *Runner) filterPromptFiles(testCases []TestCase) []TestCase {
+	wanted := make(map[string]bool, len(r.options.PromptFiles))
+	for _, file := range r.options.PromptFiles {
+		wanted[file] = true
+	}
+
+	var filtered []TestCase
+	for _, testCase := range testCases {
+		if wanted[testCase.PromptFile] {
+			filtered = append(filtered, testCase)
+		}
+	}
+	return filtered
+}
+
 func (r *Runner) runSingleTest(testCase TestCase) TestResult {
 	startTime := time.Now()
 
@@ -229,22 +558,15 @@ func (r *Runner) runSingleTest(testCase TestCase) TestResult {
 		Name:       testCase.Name,
 		PromptFile: testCase.PromptFile,
 		Provider:   testCase.Provider,
+		Canary:     testCase.Canary,
 		Variables:  testCase.Variables,
 		Duration:   0,
 		Status:     "failed",
 		Assertions: make([]AssertionResult, 0),
 	}
 
-	// Load prompt
-	prompt, err := prompts.LoadFromFile(testCase.PromptFile)
-	if err != nil {
-		result.Error = fmt.Sprintf("Failed to load prompt: %v", err)
-		result.Duration = time.Since(startTime)
-		return result
-	}
-
 	// Render prompt with variables
-	renderedPrompt, err := prompt.Render(testCase.Variables)
+	messages, err := r.render(testCase)
 	if err != nil {
 		result.Error = fmt.Sprintf("Failed to render prompt: %v", err)
 		result.Duration = time.Since(startTime)
@@ -252,13 +574,21 @@ func (r *Runner) runSingleTest(testCase TestCase) TestResult {
 	}
 
 	// Get provider
-	providerConfig, err := r.config.GetProvider(testCase.Provider)
+	providerConfig, err := r.providerConfig(testCase)
 	if err != nil {
 		result.Error = fmt.Sprintf("Provider not found: %v", err)
 		result.Duration = time.Since(startTime)
 		return result
 	}
 
+	// Request logprobs when the test asserts on confidence
+	if needsLogprobs(testCase.Test.AllAssertions()) {
+		providerConfig = withLogprobs(providerConfig)
+	}
+	if len(testCase.Test.Tools) > 0 {
+		providerConfig = withTools(providerConfig, testCase.Test.Tools)
+	}
+
 	// Create provider client
 	client, err := providers.NewClient(providerConfig)
 	if err != nil {
@@ -267,29 +597,143 @@ func (r *Runner) runSingleTest(testCase TestCase) TestResult {
 		return result
 	}
 
-	// Execute prompt
+	// Execute prompt, repeating it when the test asks for several samples.
+	// Assertions judge the first response; latency assertions see every call.
 	ctx := context.Background()
-	response, err := client.Complete(ctx, renderedPrompt)
-	if err != nil {
-		result.Error = fmt.Sprintf("Failed to execute prompt: %v", err)
+	repeat := testCase.Test.Repeat
+	if repeat < 1 {
+		repeat = 1
+	}
+
+	var onDelta providers.StreamFunc
+	if r.options.OnDelta != nil {
+		onDelta = func(delta string) { r.options.OnDelta(testCase.Name, delta) }
+	}
+
+	var response *providers.Response
+	latencies := make([]time.Duration, 0, repeat)
+	for i := 0; i < repeat; i++ {
+		sample, cached, err := r.complete(ctx, client, providerConfig, messages, i, onDelta)
+		if err != nil {
+			result.Error = fmt.Sprintf("Failed to execute prompt: %v", err)
+			if repeat > 1 {
+				result.Error = fmt.Sprintf("Failed to execute prompt (repetition %d of %d): %v", i+1, repeat, err)
+			}
+			result.Duration = time.Since(startTime)
+			return result
+		}
+		latencies = append(latencies, sample.Latencies...)
+		result.Tokens += sample.Tokens
+
+		// A cached response costs nothing this run; assertions still see
+		// the cost of the original call
+		if !cached {
+			result.Cost += sample.Cost
+		}
+
+		if response == nil {
+			response = sample
+			result.Cached = cached
+		}
+	}
+	response.Latencies = latencies
+
+	// Each follow-up turn is sent with the conversation so far, the previous
+	// response included
+	turnMessages := make([][]providers.Message, len(testCase.Test.Conversation))
+	turnResponses := make([]*providers.Response, len(testCase.Test.Conversation))
+	for i, turn := range testCase.Test.Conversation {
+		if response.Filtered {
+			result.Error = fmt.Sprintf("Response before turn %d blocked by the %s content filter", i+1, testCase.Provider)
+			result.Duration = time.Since(startTime)
+			return result
+		}
+
+		text, err := testCase.Prompt.RenderText(turn.User, testCase.Variables)
+		if err != nil {
+			result.Error = fmt.Sprintf("Failed to render turn %d: %v", i+1, err)
+			result.Duration = time.Since(startTime)
+			return result
+		}
+		messages = append(append([]providers.Message{}, messages...),
+			providers.Message{Role: "assistant", Content: response.Text},
+			providers.Message{Role: "user", Content: text})
+
+		reply, cached, err := r.complete(ctx, client, providerConfig, messages, 0, onDelta)
+		if err != nil {
+			result.Error = fmt.Sprintf("Failed to execute turn %d: %v", i+1, err)
+			result.Duration = time.Since(startTime)
+			return result
+		}
+		result.Tokens += reply.Tokens
+		if !cached {
+			result.Cost += reply.Cost
+		}
+
+		turnMessages[i] = messages
+		turnResponses[i] = reply
+		response = reply
+	}
+	if len(testCase.Test.Conversation) > 0 {
+		result.Conversation = append(append([]providers.Message{}, messages...), providers.Message{Role: "assistant", Content: response.Text})
+	}
+
+	result.Response = response.Text
+	result.ToolCalls = response.ToolCalls
+	result.Endpoint = response.Endpoint
+	result.Filtered = response.Filtered
+
+	// A filtered response fails the test on its own, rather than through
+	// assertions tripping over the empty text, unless the test expects it
+	if response.Filtered && !expectsFiltered(testCase.Test.Assert) {
+		result.Error = fmt.Sprintf("Response blocked by the %s content filter", testCase.Provider)
 		result.Duration = time.Since(startTime)
 		return result
 	}
 
-	result.Response = response.Text
-	result.Cost = response.Cost
+	// Run assertions: each turn's against its response, then the test's
+	// against the last response
+	var judged []config.Assertion
+	var pending []AssertionResult
+	for i, turn := range testCase.Test.Conversation {
+		for _, assertion := range turn.Assert {
+			assertionResult := r.runAssertion(assertion, turnMessages[i], turnResponses[i])
+			assertionResult.Turn = i + 1
+			assertionResult.Message = fmt.Sprintf("turn %d: %s", i+1, assertionResult.Message)
+			judged = append(judged, assertion)
+			pending = append(pending, assertionResult)
+		}
+	}
+	for _, assertion := range testCase.Test.Assert {
+		judged = append(judged, assertion)
+		pending = append(pending, r.runAssertion(assertion, messages, response))
+	}
 
-	// Run assertions
 	allPassed := true
-	for _, assertion := range testCase.Test.Assert {
-		assertionResult := r.runAssertion(assertion, response)
+	for i, assertionResult := range pending {
+		assertion := judged[i]
+		result.EvalCost += assertionResult.Cost
+
+		if !assertionResult.Passed && !assertion.IsRequired() {
+			assertionResult.Warning = true
+		}
+		if assertionResult.Warning {
+			result.Warnings++
+		}
 		result.Assertions = append(result.Assertions, assertionResult)
-		
-		if !assertionResult.Passed {
+
+		if assertionResult.Failed() {
 			allPassed = false
 		}
 	}
 
+	// With a pass threshold, the weighted score decides instead of every
+	// assertion having to pass
+	if threshold := testCase.Test.PassThreshold; threshold > 0 {
+		result.Score = weightedScore(judged, result.Assertions)
+		allPassed = result.Score >= threshold
+	}
+
 	if allPassed {
 		result.Status = "passed"
 	}
@@ -298,22 +742,445 @@ func (r *Runner) runSingleTest(testCase TestCase) TestResult {
 	return result
 }
 
-func (r *Runner) runAssertion(assertion config.Assertion, response *providers.Response) AssertionResult {
+// weightedScore is the weighted share of a test's required assertions that
+// passed, from 0 to 1. Optional assertions do not count; a test without
+// required assertions scores 1. Assertions that could not judge the response
+// and only warned do not count either.
+func weightedScore(asserts []config.Assertion, results []AssertionResult) float64 {
+	var total, passed float64
+	for i, assertion := range asserts {
+		if !assertion.IsRequired() || results[i].Warning {
+			continue
+		}
+		weight := assertion.EffectiveWeight()
+		total += weight
+		if results[i].Passed {
+			passed += weight
+		}
+	}
+
+	if total == 0 {
+		return 1
+	}
+	return passed / total
+}
+
+// complete sends messages to the provider, or returns the response cached
+// for the same provider settings, messages, and sample by an earlier run.
+// With onDelta set the response is streamed to it, a cached one all at once.
+// The response's Latencies hold the duration of the call, which is cached
+// with it, so a cached response reports the latency of the original call
+// rather than of the cache read; entries cached without one have none.
+func (r *Runner) complete(ctx context.Context, client providers.Client, provider *config.Provider, messages []providers.Message, sample int, onDelta providers.StreamFunc) (*providers.Response, bool, error) {
+	call := client.Complete
+	if onDelta != nil {
+		call = func(ctx context.Context, messages []providers.Message) (*providers.Response, error) {
+			return providers.CompleteStream(ctx, client, messages, onDelta)
+		}
+	}
+
+	if r.options.Cache == nil {
+		response, err := timedCall(ctx, call, messages)
+		return response, false, err
+	}
+
+	key := cache.Key(provider, messages, sample)
+	if response, ok := r.options.Cache.Get(key); ok {
+		if onDelta != nil {
+			onDelta(response.Text)
+		}
+		return response, true, nil
+	}
+
+	response, err := timedCall(ctx, call, messages)
+	if err != nil {
+		return nil, false, err
+	}
+	if err := r.options.Cache.Put(key, response); err != nil {
+		warn.Printf("failed to cache response: %v", err)
+	}
+	return response, false, nil
+}
+
+// timedCall calls the provider and records the duration of the call as the
+// response's latency
+func timedCall(ctx context.Context, call func(context.Context, []providers.Message) (*providers.Response, error), messages []providers.Message) (*providers.Response, error) {
+	start := time.Now()
+	response, err := call(ctx, messages)
+	if err != nil {
+		return nil, err
+	}
+	response.Latencies = []time.Duration{time.Since(start)}
+	return response, nil
+}
+
+// skippedResult is the result of a test case that was not run
+func skippedResult(testCase TestCase, reason string) TestResult {
+	return TestResult{
+		Name:       testCase.Name,
+		PromptFile: testCase.PromptFile,
+		Provider:   testCase.Provider,
+		Canary:     testCase.Canary,
+		Variables:  testCase.Variables,
+		Status:     "skipped",
+		SkipReason: reason,
+		Assertions: make([]AssertionResult, 0),
+	}
+}
+
+// costTracker sums the cost of finished tests against the run's cost budget
+type costTracker struct {
+	mu     sync.Mutex
+	budget float64 // 0 or less means no budget
+	spent  float64
+}
+
+func (t *costTracker) add(cost float64) {
+	t.mu.Lock()
+	t.spent += cost
+	t.mu.Unlock()
+}
+
+// exceeded reports whether the tests so far cost more than the budget
+func (t *costTracker) exceeded() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.budget > 0 && t.spent > t.budget
+}
+
+// runAssertion evaluates an assertion against the response, or against the
+// whole conversation for evaluators that judge every turn
+func (r *Runner) runAssertion(assertion config.Assertion, messages []providers.Message, response *providers.Response) AssertionResult {
 	evaluator := assertions.NewEvaluator(assertion.Type)
-	
-	result, err := evaluator.Evaluate(assertion, response)
+
+	var result AssertionResult
+	var err error
+	if conversational, ok := evaluator.(assertions.ConversationEvaluator); ok {
+		conversation := append(append([]providers.Message{}, messages...), providers.Message{Role: "assistant", Content: response.Text})
+		result, err = conversational.EvaluateConversation(assertion, conversation)
+	} else if embedding, ok := evaluator.(assertions.EmbeddingEvaluator); ok && r.embedder != nil {
+		result, err = embedding.EvaluateWithEmbedder(assertion, response, r.embedder)
+	} else if moderated, ok := evaluator.(assertions.ModerationEvaluator); ok {
+		result, err = moderated.EvaluateWithModeration(assertion, response, r.moderation)
+	} else {
+		result, err = evaluator.Evaluate(assertion, response)
+	}
 	if err != nil {
+		// An evaluation error fails the assertion too, so it also gets the
+		// author's message, followed by the error for debugging
+		message := fmt.Sprintf("Evaluation error: %v", err)
+		if assertion.Message != "" {
+			message = fmt.Sprintf("%s (evaluation error: %v)", assertion.Message, err)
+		}
 		return AssertionResult{
 			Type:    assertion.Type,
 			Passed:  false,
-			Message: fmt.Sprintf("Evaluation error: %v", err),
+			Message: message,
 		}
 	}
 
+	// Prefer the author's failure message over the generated one
+	if !result.Passed && assertion.Message != "" {
+		result.Message = assertion.Message
+	}
+
 	return result
 }
 
+// moderationSettings returns the settings of the first OpenAI provider, or
+// nil when there is none and the moderations endpoint uses OPENAI_API_KEY
+func moderationSettings(providerConfigs []config.Provider) map[string]interface{} {
+	for _, provider := range providerConfigs {
+		if strings.HasPrefix(provider.ID, "openai:") {
+			return provider.Config
+		}
+	}
+	return nil
+}
+
+// render renders a test case's prompt, reusing the result for test cases
+// with the same prompt file and variables
+func (r *Runner) render(testCase TestCase) ([]providers.Message, error) {
+	vars, err := json.Marshal(testCase.Variables)
+	if err != nil {
+		return testCase.Prompt.Render(testCase.Variables)
+	}
+	key := testCase.PromptFile + "\x00" + string(vars)
+
+	r.renders.mu.Lock()
+	cached, ok := r.renders.entries[key]
+	r.renders.mu.Unlock()
+	if ok {
+		return cached.messages, cached.err
+	}
+
+	messages, err := testCase.Prompt.Render(testCase.Variables)
+
+	r.renders.mu.Lock()
+	r.renders.entries[key] = renderedPrompt{messages: messages, err: err}
+	r.renders.mu.Unlock()
+
+	return messages, err
+}
+
+// expectsFiltered reports whether a test asserts that the content filter fires
+func expectsFiltered(asserts []config.Assertion) bool {
+	for _, assertion := range asserts {
+		if assertion.Type == "expect-filtered" {
+			return true
+		}
+	}
+	return false
+}
+
+// needsLogprobs reports whether any assertion requires token logprobs
+func needsLogprobs(asserts []config.Assertion) bool {
+	for _, assertion := range asserts {
+		if assertion.Type == "min-confidence" {
+			return true
+		}
+	}
+	return false
+}
+
+// providerConfig resolves the provider of a test case. A provider named only
+// in the prompt frontmatter needs no config entry, and the prompt's
+// temperature overrides the provider's.
+func (r *Runner) providerConfig(testCase TestCase) (*config.Provider, error) {
+	provider, err := r.config.GetProvider(testCase.Provider)
+	if err != nil {
+		if testCase.Prompt == nil || testCase.Provider != testCase.Prompt.Provider {
+			return nil, err
+		}
+		provider = &config.Provider{ID: testCase.Provider}
+	}
+
+	if testCase.Prompt != nil && testCase.Prompt.Temperature != nil {
+		provider = withSetting(provider, "temperature", *testCase.Prompt.Temperature)
+	}
+
+	return provider, nil
+}
+
+// withLogprobs returns a copy of the provider config with logprobs enabled
+func withLogprobs(provider *config.Provider) *config.Provider {
+	return withSetting(provider, "logprobs", true)
+}
+
+// withTools returns a copy of the provider config offering a test's tools
+// after the provider's own; a test tool replaces a provider tool of the
+// same name
+func withTools(provider *config.Provider, tools []config.Tool) *config.Provider {
+	replaced := make(map[string]bool, len(tools))
+	for _, tool := range tools {
+		replaced[tool.Name] = true
+	}
+
+	var merged []interface{}
+	existing, _ := provider.Config["tools"].([]interface{})
+	for _, entry := range existing {
+		if setting, ok := entry.(map[string]interface{}); ok && replaced[fmt.Sprint(setting["name"])] {
+			continue
+		}
+		merged = append(merged, entry)
+	}
+	for _, tool := range tools {
+		merged = append(merged, tool.Setting())
+	}
+
+	return withSetting(provider, "tools", merged)
+}
+
+// withSetting returns a copy of the provider config with one setting replaced
+func withSetting(provider *config.Provider, key string, value interface{}) *config.Provider {
+	settings := make(map[string]interface{}, len(provider.Config)+1)
+	for k, v := range provider.Config {
+		settings[k] = v
+	}
+	settings[key] = value
+
+	return &config.Provider{ID: provider.ID, Config: settings}
+}
+
+// sortTestResults orders test results by prompt file, name, and provider
+func sortTestResults(testResults []TestResult) {
+	sort.SliceStable(testResults, func(i, j int) bool {
+		a, b := testResults[i], testResults[j]
+		if a.PromptFile != b.PromptFile {
+			return a.PromptFile < b.PromptFile
+		}
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		return a.Provider < b.Provider
+	})
+}
+
+// tally sorts the test results and sums their costs and warnings. Results
+// arrive in completion order; sorting them keeps reports stable and sums the
+// floating-point cost total in the same order every run.
+func (results *Results) tally() {
+	sortTestResults(results.TestResults)
+	for _, result := range results.TestResults {
+		results.TotalCost += result.Cost + result.EvalCost
+		results.EvalCost += result.EvalCost
+		results.Warnings += result.Warnings
+	}
+}
+
+// numberRegex matches the figures that vary between otherwise identical
+// failure messages, e.g. the cost in "Cost: $0.0312 (threshold: $0.0100)"
+var numberRegex = regexp.MustCompile(`\d+(\.\d+)?`)
+
+// TopFailureReason groups failed tests by assertion type and message and
+// returns the most frequent reason, or nil if no tests failed. Numbers in
+// messages are ignored when grouping; ties go to the reason that reached
+// the count first.
+func (r *Results) TopFailureReason() *FailureReason {
+	var top *FailureReason
+	reasons := make(map[string]*FailureReason)
+
+	for _, test := range r.TestResults {
+		if test.Status != "failed" {
+			continue
+		}
+
+		var failures []FailureReason
+		if test.Error != "" {
+			failures = append(failures, FailureReason{Type: "error", Message: test.Error})
+		}
+		for _, assertion := range test.Assertions {
+			if assertion.Failed() {
+				failures = append(failures, FailureReason{Type: assertion.Type, Message: assertion.Message})
+			}
+		}
+
+		// Count each reason once per test
+		counted := make(map[string]bool)
+		for _, failure := range failures {
+			key := failure.Type + "\x00" + numberRegex.ReplaceAllString(failure.Message, "#")
+			if counted[key] {
+				continue
+			}
+			counted[key] = true
+
+			reason, ok := reasons[key]
+			if !ok {
+				reason = &FailureReason{Type: failure.Type, Message: failure.Message}
+				reasons[key] = reason
+			}
+			reason.Count++
+
+			if top == nil || reason.Count > top.Count {
+				top = reason
+			}
+		}
+	}
+
+	return top
+}
+
+// CanaryComparison summarizes the canary and primary test runs separately.
+// It returns nil for both if no test ran on the canary.
+func (r *Results) CanaryComparison() (primary, canary *CanaryStats) {
+	primary, canary = &CanaryStats{}, &CanaryStats{}
+	var primaryCost, canaryCost float64
+	var primaryDuration, canaryDuration time.Duration
+
+	for _, test := range r.TestResults {
+		stats, cost, duration := primary, &primaryCost, &primaryDuration
+		if test.Canary {
+			stats, cost, duration = canary, &canaryCost, &canaryDuration
+		}
+
+		stats.Total++
+		switch test.Status {
+		case "passed":
+			stats.Passed++
+		case "failed":
+			stats.Failed++
+		}
+		*cost += test.Cost
+		*duration += test.Duration
+	}
+
+	if canary.Total == 0 {
+		return nil, nil
+	}
+
+	for _, side := range []struct {
+		stats    *CanaryStats
+		cost     float64
+		duration time.Duration
+	}{{primary, primaryCost, primaryDuration}, {canary, canaryCost, canaryDuration}} {
+		if side.stats.Total > 0 {
+			side.stats.PassRate = float64(side.stats.Passed) / float64(side.stats.Total)
+			side.stats.AverageCost = side.cost / float64(side.stats.Total)
+			side.stats.AverageDuration = side.duration / time.Duration(side.stats.Total)
+		}
+	}
+
+	return primary, canary
+}
+
+// ByProvider summarizes the tests of each provider, ordered by provider ID
+func (r *Results) ByProvider() []ProviderStats {
+	index := make(map[string]int)
+	var breakdown []ProviderStats
+	for _, test := range r.TestResults {
+		i, ok := index[test.Provider]
+		if !ok {
+			i = len(breakdown)
+			index[test.Provider] = i
+			breakdown = append(breakdown, ProviderStats{Provider: test.Provider})
+		}
+
+		stats := &breakdown[i]
+		stats.Total++
+		switch test.Status {
+		case "passed":
+			stats.Passed++
+		case "failed":
+			stats.Failed++
+		case "skipped":
+			stats.Skipped++
+		}
+		stats.Cost += test.Cost
+	}
+
+	for i := range breakdown {
+		if ran := breakdown[i].Passed + breakdown[i].Failed; ran > 0 {
+			breakdown[i].PassRate = float64(breakdown[i].Passed) / float64(ran)
+		}
+	}
+
+	sort.Slice(breakdown, func(i, j int) bool { return breakdown[i].Provider < breakdown[j].Provider })
+	return breakdown
+}
+
+// Preview shortens text to at most max characters, ending it with a marker
+// of how many were cut. Characters are counted as runes so that multibyte
+// characters are never split. A max of 0 or less returns text unchanged.
+func Preview(text string, max int) string {
+	if max <= 0 || utf8.RuneCountInString(text) <= max {
+		return text
+	}
+
+	runes := []rune(text)
+	return fmt.Sprintf("%s… (%d more chars)", string(runes[:max]), len(runes)-max)
+}
+
 // HasFailures returns true if any tests failed
 func (r *Results) HasFailures() bool {
 	return r.Failed > 0
 }
+
+// newRunID returns a run ID that sorts by start time, with a random suffix
+// so that runs started in the same second stay distinct
+func newRunID(startTime time.Time) string {
+	suffix := make([]byte, 3)
+	if _, err := rand.Read(suffix); err != nil {
+		return startTime.Format("20060102-150405.000000")
+	}
+	return startTime.Format("20060102-150405") + "-" + hex.EncodeToString(suffix)
+}
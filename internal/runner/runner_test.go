@@ -0,0 +1,229 @@
+package runner
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"promptguard/internal/cache"
+	"promptguard/internal/config"
+	"promptguard/internal/prompts"
+	"promptguard/internal/providers"
+)
+
+func TestTallyCostIndependentOfOrder(t *testing.T) {
+	// Costs whose floating-point sum depends on the order they are added in
+	costs := []float64{0.1, 1e-17, 0.2, 3e16, 0.3, -3e16, 0.0007, 1e-9, 0.123456789}
+
+	var tests []TestResult
+	for i, cost := range costs {
+		tests = append(tests, TestResult{
+			Name:     fmt.Sprintf("test-%d", i),
+			Provider: "mock:echo",
+			Cost:     cost,
+			EvalCost: cost / 3,
+		})
+	}
+
+	var want *Results
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 50; i++ {
+		shuffled := append([]TestResult{}, tests...)
+		rng.Shuffle(len(shuffled), func(a, b int) { shuffled[a], shuffled[b] = shuffled[b], shuffled[a] })
+
+		results := &Results{TestResults: shuffled}
+		results.tally()
+
+		if want == nil {
+			want = results
+			continue
+		}
+		if results.TotalCost != want.TotalCost || results.EvalCost != want.EvalCost {
+			t.Fatalf("shuffle %d: total %v, eval %v; want %v, %v", i, results.TotalCost, results.EvalCost, want.TotalCost, want.EvalCost)
+		}
+	}
+}
+
+func TestAssertionMessageOnFailureAndError(t *testing.T) {
+	response := &providers.Response{Text: "You are an idiot.", Cost: 0.01}
+
+	tests := []struct {
+		name      string
+		assertion config.Assertion
+		want      string
+	}{
+		{"failure", config.Assertion{Type: "cost", Threshold: 0.001, Message: "Too expensive"}, "Too expensive"},
+		{"evaluation error", config.Assertion{Type: "toxicity", Value: "not a list", Message: "Must stay polite"}, "Must stay polite (evaluation error: toxicity assertion value must be a keyword list or a map)"},
+		{"error without message", config.Assertion{Type: "toxicity", Value: "not a list"}, "Evaluation error: toxicity assertion value must be a keyword list or a map"},
+		{"pass", config.Assertion{Type: "cost", Threshold: 1, Message: "Too expensive"}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := (&Runner{}).runAssertion(tt.assertion, nil, response)
+			if tt.want == "" {
+				if !result.Passed || result.Message == tt.assertion.Message {
+					t.Errorf("passed = %v, message = %q; want a pass with the generated message", result.Passed, result.Message)
+				}
+				return
+			}
+			if result.Passed || result.Message != tt.want {
+				t.Errorf("passed = %v, message = %q; want a failure with %q", result.Passed, result.Message, tt.want)
+			}
+		})
+	}
+}
+
+// benchmarkPrompt is a prompt file of the size and shape of a typical suite
+const benchmarkPrompt = `---
+title: "Onboarding"
+---
+
+You are a friendly onboarding assistant for {{ .product }}.
+
+Welcome {{ .name | default "there" }} to the {{ .plan | upper }} plan.
+{{ if .trial_days }}Their trial lasts {{ .trial_days }} days.{{ end }}
+List the three most useful features of {{ .product }} for a {{ .role }}.
+`
+
+// benchmarkTestCases loads benchmarkPrompt once, as Run does, and returns a
+// runner and one test case per variable set
+func benchmarkTestCases(b *testing.B) (*Runner, []TestCase, string) {
+	file := filepath.Join(b.TempDir(), "onboard.prompt")
+	if err := os.WriteFile(file, []byte(benchmarkPrompt), 0644); err != nil {
+		b.Fatal(err)
+	}
+
+	r := New(&config.Config{Prompts: []string{file}}, Options{})
+	promptFiles, err := r.loadPrompts()
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	var testCases []TestCase
+	for i := 0; i < 100; i++ {
+		testCases = append(testCases, TestCase{
+			Name:       fmt.Sprintf("test-%d", i),
+			PromptFile: file,
+			Prompt:     promptFiles[file],
+			Variables: map[string]interface{}{
+				"product":    "PromptGuard",
+				"name":       fmt.Sprintf("user-%d", i),
+				"plan":       "pro",
+				"trial_days": i % 30,
+				"role":       "developer",
+			},
+		})
+	}
+	return r, testCases, file
+}
+
+// BenchmarkRenderLoadedPrompt renders a run's test cases from the prompt
+// loaded once per run, as runSingleTest does
+func BenchmarkRenderLoadedPrompt(b *testing.B) {
+	r, testCases, _ := benchmarkTestCases(b)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		r.renders = &renderCache{entries: make(map[string]renderedPrompt)}
+		for _, testCase := range testCases {
+			if _, err := r.render(testCase); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+// BenchmarkRenderReloadedPrompt renders the same test cases re-reading and
+// re-parsing the prompt file for each, for comparison
+func BenchmarkRenderReloadedPrompt(b *testing.B) {
+	_, testCases, file := benchmarkTestCases(b)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, testCase := range testCases {
+			prompt, err := prompts.LoadFromFile(file)
+			if err != nil {
+				b.Fatal(err)
+			}
+			if _, err := prompt.Render(testCase.Variables); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+const latencyConfig = `
+inlinePrompts:
+  greet: "Say hi."
+providers:
+  - id: mock:echo
+    config:
+      delay: 40ms
+tests:
+  - name: slow
+    repeat: 2
+    assert:
+      - type: latency-p95
+        value: 20ms
+`
+
+// TestCachedResponsesKeepTheirLatency runs a slow test twice with the cache
+// on: the cached run must report the original latency, not the cache read's,
+// and fail the same way. Entries without a latency warn instead of passing.
+func TestCachedResponsesKeepTheirLatency(t *testing.T) {
+	cfg, err := config.Parse([]byte(latencyConfig))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	responses := cache.New(dir, time.Hour)
+
+	run := func() TestResult {
+		t.Helper()
+		results, err := New(cfg, Options{Parallel: 1, Cache: responses}).Run()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return results.TestResults[0]
+	}
+
+	if result := run(); result.Status != "failed" || result.Cached {
+		t.Fatalf("first run: status %s, cached %v; want a failed, uncached run", result.Status, result.Cached)
+	}
+	if result := run(); result.Status != "failed" || !result.Cached {
+		t.Fatalf("cached run: status %s, cached %v; want the cached run to fail too (%s)", result.Status, result.Cached, result.Assertions[0].Message)
+	}
+
+	// Drop the latencies, as in entries cached before they were recorded
+	entries, err := filepath.Glob(filepath.Join(dir, "*"))
+	if err != nil || len(entries) == 0 {
+		t.Fatalf("no cache entries in %s: %v", dir, err)
+	}
+	for _, entry := range entries {
+		data, err := os.ReadFile(entry)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var fields map[string]interface{}
+		if err := json.Unmarshal(data, &fields); err != nil {
+			t.Fatal(err)
+		}
+		delete(fields["response"].(map[string]interface{}), "latencies")
+		if data, err = json.Marshal(fields); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(entry, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	result := run()
+	if result.Status != "passed" || result.Warnings != 1 || !result.Assertions[0].Warning {
+		t.Errorf("run without cached latencies: status %s, %d warnings; want passed with a warning (%s)", result.Status, result.Warnings, result.Assertions[0].Message)
+	}
+}
@@ -0,0 +1,242 @@
+package server
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"promptguard/internal/config"
+	"promptguard/internal/runner"
+)
+
+// Run status values
+const (
+	StatusQueued    = "queued"
+	StatusRunning   = "running"
+	StatusCompleted = "completed"
+	StatusFailed    = "failed"
+)
+
+// maxConfigSize limits the size of a submitted configuration
+const maxConfigSize = 1 << 20
+
+// maxFinishedRuns caps the finished runs kept for /results; the oldest are
+// forgotten first
+const maxFinishedRuns = 1000
+
+// Run represents a queued or finished test run
+type Run struct {
+	ID        string          `json:"id"`
+	Status    string          `json:"status"`
+	Submitted string          `json:"submitted"`
+	Error     string          `json:"error,omitempty"`
+	Results   *runner.Results `json:"results,omitempty"`
+
+	config *config.Config
+	done   chan struct{}
+}
+
+// Server exposes the test runner over HTTP
+type Server struct {
+	token    string
+	parallel int
+	store    runner.ResultStore
+	serve    config.ServeOptions
+	mux      *http.ServeMux
+	queue    chan *Run
+
+	mu       sync.RWMutex
+	runs     map[string]*Run
+	finished []string // IDs of finished runs, oldest first
+}
+
+// NewServer creates a new API server. Requests must carry the token as a
+// bearer token. Posted configs are restricted by serve, see
+// config.ParseServed. Runs are executed one at a time in submission order
+// and recorded in store, if not nil.
+func NewServer(token string, parallel, queueSize int, store runner.ResultStore, serve config.ServeOptions) *Server {
+	server := &Server{
+		token:    token,
+		parallel: parallel,
+		store:    store,
+		serve:    serve,
+		mux:      http.NewServeMux(),
+		queue:    make(chan *Run, queueSize),
+		runs:     make(map[string]*Run),
+	}
+
+	server.setupRoutes()
+	go server.worker()
+	return server
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !s.authorized(r) {
+		writeError(w, http.StatusUnauthorized, "missing or invalid API token")
+		return
+	}
+	s.mux.ServeHTTP(w, r)
+}
+
+func (s *Server) setupRoutes() {
+	s.mux.HandleFunc("/run", s.handleRun)
+	s.mux.HandleFunc("/results/", s.handleResults)
+}
+
+func (s *Server) authorized(r *http.Request) bool {
+	provided := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return subtle.ConstantTimeCompare([]byte(provided), []byte(s.token)) == 1
+}
+
+// handleRun accepts a YAML or JSON configuration and queues a run. With
+// ?wait=true the request blocks until the run finishes and returns its results.
+func (s *Server) handleRun(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "use POST to submit a run")
+		return
+	}
+
+	data, err := io.ReadAll(io.LimitReader(r.Body, maxConfigSize))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "failed to read request body")
+		return
+	}
+
+	cfg, err := config.ParseServed(data, s.serve)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	run := &Run{
+		ID:        newRunID(),
+		Status:    StatusQueued,
+		Submitted: time.Now().Format(time.RFC3339),
+		config:    cfg,
+		done:      make(chan struct{}),
+	}
+
+	// Record the run before queueing it, so that the worker cannot retire
+	// it before it is known
+	s.mu.Lock()
+	s.runs[run.ID] = run
+	s.mu.Unlock()
+
+	select {
+	case s.queue <- run:
+	default:
+		s.mu.Lock()
+		delete(s.runs, run.ID)
+		s.mu.Unlock()
+		writeError(w, http.StatusServiceUnavailable, "run queue is full")
+		return
+	}
+
+	if r.URL.Query().Get("wait") == "true" {
+		select {
+		case <-run.done:
+		case <-r.Context().Done():
+			return
+		}
+		s.writeRun(w, http.StatusOK, run)
+		return
+	}
+
+	s.writeRun(w, http.StatusAccepted, run)
+}
+
+func (s *Server) handleResults(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "use GET to fetch results")
+		return
+	}
+
+	id := strings.TrimPrefix(r.URL.Path, "/results/")
+
+	s.mu.RLock()
+	run, ok := s.runs[id]
+	s.mu.RUnlock()
+
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("run not found: %s", id))
+		return
+	}
+
+	s.writeRun(w, http.StatusOK, run)
+}
+
+// worker executes queued runs sequentially
+func (s *Server) worker() {
+	for run := range s.queue {
+		s.setStatus(run, StatusRunning, nil, "")
+
+		testRunner := runner.New(run.config, runner.Options{Parallel: s.parallel, Store: s.store})
+		results, err := testRunner.Run()
+		if err != nil {
+			s.setStatus(run, StatusFailed, nil, err.Error())
+		} else {
+			s.setStatus(run, StatusCompleted, results, "")
+		}
+
+		close(run.done)
+		s.retire(run)
+	}
+}
+
+// retire records run as finished, forgetting the oldest finished runs once
+// there are more than maxFinishedRuns
+func (s *Server) retire(run *Run) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.finished = append(s.finished, run.ID)
+	for len(s.finished) > maxFinishedRuns {
+		delete(s.runs, s.finished[0])
+		s.finished = s.finished[1:]
+	}
+}
+
+func (s *Server) setStatus(run *Run, status string, results *runner.Results, errMsg string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	run.Status = status
+	run.Results = results
+	run.Error = errMsg
+}
+
+func (s *Server) writeRun(w http.ResponseWriter, status int, run *Run) {
+	s.mu.RLock()
+	data, err := json.Marshal(run)
+	s.mu.RUnlock()
+
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to encode run")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(data)
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
+
+func newRunID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%d", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,180 @@
+package slack
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"promptguard/internal/runner"
+)
+
+// maxFailures is the number of failing tests listed in a message
+const maxFailures = 5
+
+// postTimeout bounds the webhook request so that a Slack outage cannot hold
+// up the CI job
+const postTimeout = 10 * time.Second
+
+// Message is an incoming-webhook payload. Text is the notification fallback
+// shown where blocks are not rendered.
+type Message struct {
+	Text   string  `json:"text"`
+	Blocks []Block `json:"blocks"`
+}
+
+// Block is a Block Kit layout block
+type Block struct {
+	Type     string `json:"type"`
+	Text     *Text  `json:"text,omitempty"`
+	Fields   []Text `json:"fields,omitempty"`
+	Elements []Text `json:"elements,omitempty"`
+}
+
+// Text is a Block Kit text object
+type Text struct {
+	Type string `json:"type"` // plain_text or mrkdwn
+	Text string `json:"text"`
+}
+
+// Post sends a summary of the results to a Slack incoming webhook
+func Post(webhookURL string, results *runner.Results) error {
+	body, err := json.Marshal(NewMessage(results))
+	if err != nil {
+		return fmt.Errorf("failed to marshal Slack message: %w", err)
+	}
+
+	client := &http.Client{Timeout: postTimeout}
+	resp, err := client.Post(webhookURL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("Slack webhook request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("Slack webhook returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(detail)))
+	}
+
+	return nil
+}
+
+// NewMessage builds the Block Kit summary of a run: pass/fail counts, cost,
+// commit, and the first failing tests, linked to their prompt files when
+// running in GitHub Actions
+func NewMessage(results *runner.Results) Message {
+	status := "✅ PromptGuard tests passed"
+	if results.HasFailures() {
+		status = "❌ PromptGuard tests failed"
+	}
+
+	commit := "-"
+	if sha := results.Metadata.CommitSHA; sha != "" {
+		commit = "`" + shortSHA(sha) + "`"
+		if url := commitURL(sha); url != "" {
+			commit = link(url, shortSHA(sha))
+		}
+	}
+
+	message := Message{
+		Text: fmt.Sprintf("%s: %d passed, %d failed", status, results.Passed, results.Failed),
+		Blocks: []Block{
+			{Type: "header", Text: &Text{Type: "plain_text", Text: status}},
+			{Type: "section", Fields: []Text{
+				{Type: "mrkdwn", Text: fmt.Sprintf("*Passed:* %d", results.Passed)},
+				{Type: "mrkdwn", Text: fmt.Sprintf("*Failed:* %d", results.Failed)},
+				{Type: "mrkdwn", Text: fmt.Sprintf("*Cost:* $%.4f", results.TotalCost)},
+				{Type: "mrkdwn", Text: "*Commit:* " + commit},
+			}},
+		},
+	}
+
+	var failures []string
+	failed := 0
+	for _, test := range results.TestResults {
+		if test.Status != "failed" {
+			continue
+		}
+		failed++
+		if len(failures) == maxFailures {
+			continue
+		}
+
+		name := escape(test.Name)
+		if url := fileURL(results.Metadata.CommitSHA, test.PromptFile); url != "" {
+			name = link(url, test.Name)
+		}
+		failures = append(failures, fmt.Sprintf("• %s (%s)", name, escape(test.Provider)))
+	}
+	if failed > len(failures) {
+		failures = append(failures, fmt.Sprintf("…and %d more", failed-len(failures)))
+	}
+	if len(failures) > 0 {
+		message.Blocks = append(message.Blocks, Block{
+			Type: "section",
+			Text: &Text{Type: "mrkdwn", Text: "*Failing tests*\n" + strings.Join(failures, "\n")},
+		})
+	}
+
+	context := fmt.Sprintf("Run %s", escape(results.Metadata.RunID))
+	if url := runURL(); url != "" {
+		context += " · " + link(url, "View workflow run")
+	}
+	message.Blocks = append(message.Blocks, Block{
+		Type:     "context",
+		Elements: []Text{{Type: "mrkdwn", Text: context}},
+	})
+
+	return message
+}
+
+// repoURL returns the GitHub repository URL when running in GitHub Actions
+func repoURL() string {
+	server, repo := os.Getenv("GITHUB_SERVER_URL"), os.Getenv("GITHUB_REPOSITORY")
+	if server == "" || repo == "" {
+		return ""
+	}
+	return server + "/" + repo
+}
+
+func commitURL(sha string) string {
+	if repo := repoURL(); repo != "" {
+		return repo + "/commit/" + sha
+	}
+	return ""
+}
+
+func fileURL(sha, path string) string {
+	if repo := repoURL(); repo != "" && sha != "" && path != "" {
+		return repo + "/blob/" + sha + "/" + strings.TrimPrefix(filepath.ToSlash(path), "./")
+	}
+	return ""
+}
+
+func runURL() string {
+	if repo, id := repoURL(), os.Getenv("GITHUB_RUN_ID"); repo != "" && id != "" {
+		return repo + "/actions/runs/" + id
+	}
+	return ""
+}
+
+func link(url, label string) string {
+	return "<" + url + "|" + escape(label) + ">"
+}
+
+// escape escapes the characters Slack treats as control sequences in mrkdwn
+func escape(s string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(s)
+}
+
+func shortSHA(sha string) string {
+	if len(sha) > 7 {
+		return sha[:7]
+	}
+	return sha
+}
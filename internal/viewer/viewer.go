@@ -1,31 +1,104 @@
 package viewer
 
 import (
+	_ "embed"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"html/template"
-	"net/http"	"os"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
 
-	"promptgaurd/internal/runner"
+	"promptguard/internal/diff"
+	"promptguard/internal/metrics"
+	"promptguard/internal/runner"
 )
 
 // Server provides the web interface for viewing test results
 type Server struct {
-	resultsFile string
-	mux         *http.ServeMux
+	// MaxResponseChars is the response length after which the page shows a
+	// preview with a toggle for the full text; 0 shows responses in full
+	MaxResponseChars int
+
+	resultsFile  string
+	baselineFile string
+	metrics      *metrics.Store
+	mux          *http.ServeMux
+}
+
+// indexTemplate is the viewer page, which loads its data from the API
+//
+//go:embed templates/index.html
+var indexTemplate string
+
+// maxHistoryLimit caps the number of runs returned by /api/history
+const maxHistoryLimit = 1000
+
+// HistoryPoint summarizes one stored run for the history charts
+type HistoryPoint struct {
+	RunID     string  `json:"runId,omitempty"`
+	Timestamp string  `json:"timestamp"`
+	CommitSHA string  `json:"commitSha,omitempty"`
+	Cost      float64 `json:"cost"`
+	Passed    int     `json:"passed"`
+	Failed    int     `json:"failed"`
+}
+
+// DiffResponse is the baseline comparison returned by /api/diff
+type DiffResponse struct {
+	Baseline string     `json:"baseline"`
+	Summary  DiffTotals `json:"summary"`
+	Tests    []TestDiff `json:"tests"`
+	Markdown string     `json:"markdown"`
+}
+
+// DiffTotals compares the run totals of the baseline and current results
+type DiffTotals struct {
+	BaselinePassed int     `json:"baselinePassed"`
+	CurrentPassed  int     `json:"currentPassed"`
+	PassedDelta    int     `json:"passedDelta"`
+	BaselineFailed int     `json:"baselineFailed"`
+	CurrentFailed  int     `json:"currentFailed"`
+	FailedDelta    int     `json:"failedDelta"`
+	BaselineCost   float64 `json:"baselineCost"`
+	CurrentCost    float64 `json:"currentCost"`
+	CostDelta      float64 `json:"costDelta"`
+}
+
+// TestDiff describes how a single test changed from the baseline
+type TestDiff struct {
+	Name           string  `json:"name"`
+	PromptFile     string  `json:"promptFile"`
+	Provider       string  `json:"provider"`
+	BaselineStatus string  `json:"baselineStatus"` // "missing" if the test is new
+	CurrentStatus  string  `json:"currentStatus"`  // "missing" if the test was removed
+	Transition     string  `json:"transition"`     // regressed, fixed, unchanged, added, or removed
+	CostDelta      float64 `json:"costDelta"`
 }
 
-// NewServer creates a new viewer server
-func NewServer(resultsFile string) *Server {
+// NewServer creates a new viewer server. baselineFile is compared with the
+// results when /api/diff is called without a baseline parameter. History is
+// read from the metrics database at metricsPath (see metrics.NewStore).
+func NewServer(resultsFile, baselineFile, metricsPath string) *Server {
 	server := &Server{
-		resultsFile: resultsFile,
-		mux:         http.NewServeMux(),
+		resultsFile:  resultsFile,
+		baselineFile: baselineFile,
+		metrics:      metrics.NewStore(metricsPath),
+		mux:          http.NewServeMux(),
 	}
 
 	server.setupRoutes()
 	return server
 }
 
+// Close releases the metrics database
+func (s *Server) Close() error {
+	return s.metrics.Close()
+}
+
 func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	s.mux.ServeHTTP(w, r)
 }
@@ -34,213 +107,18 @@ func (s *Server) setupRoutes() {
 	s.mux.HandleFunc("/", s.handleIndex)
 	s.mux.HandleFunc("/api/results", s.handleAPIResults)
 	s.mux.HandleFunc("/api/diff", s.handleAPIDiff)
+	s.mux.HandleFunc("/api/history", s.handleAPIHistory)
 }
 
 func (s *Server) handleIndex(w http.ResponseWriter, r *http.Request) {
-	tmpl := `<!DOCTYPE html>
-<html lang="en">
-<head>
-    <meta charset="UTF-8">
-    <meta name="viewport" content="width=device-width, initial-scale=1.0">
-    <title>PromptGuard Viewer</title>
-    <style>
-        body { font-family: -apple-system, BlinkMacSystemFont, 'Segoe UI', sans-serif; margin: 0; padding: 0; background: #f5f7fa; }
-        .header { background: linear-gradient(135deg, #667eea 0%, #764ba2 100%); color: white; padding: 20px 0; text-align: center; }
-        .container { max-width: 1400px; margin: 0 auto; padding: 20px; }
-        .controls { background: white; padding: 20px; border-radius: 8px; margin-bottom: 20px; box-shadow: 0 2px 4px rgba(0,0,0,0.1); }
-        .results-grid { display: grid; grid-template-columns: 1fr 1fr; gap: 20px; }
-        .results-panel { background: white; border-radius: 8px; padding: 20px; box-shadow: 0 2px 4px rgba(0,0,0,0.1); }
-        .test-item { border: 1px solid #e1e5e9; border-radius: 6px; margin-bottom: 15px; overflow: hidden; }
-        .test-header { padding: 15px; background: #f8f9fa; border-bottom: 1px solid #e1e5e9; cursor: pointer; }
-        .test-content { padding: 15px; display: none; }
-        .test-content.show { display: block; }
-        .status-badge { padding: 3px 8px; border-radius: 12px; font-size: 0.7em; font-weight: bold; text-transform: uppercase; }
-        .badge-passed { background: #d4edda; color: #155724; }
-        .badge-failed { background: #f8d7da; color: #721c24; }
-        .diff-viewer { background: #f8f9fa; border-radius: 4px; padding: 15px; margin: 10px 0; }
-        .response-text { font-family: monospace; white-space: pre-wrap; background: #f1f3f4; padding: 10px; border-radius: 4px; }
-        .metrics-chart { height: 300px; margin: 20px 0; }
-        button { background: #667eea; color: white; border: none; padding: 8px 16px; border-radius: 4px; cursor: pointer; }
-        button:hover { background: #5a67d8; }
-        .tab-buttons { display: flex; gap: 10px; margin-bottom: 20px; }
-        .tab-buttons button { background: #e2e8f0; color: #4a5568; }
-        .tab-buttons button.active { background: #667eea; color: white; }
-    </style>
-</head>
-<body>
-    <div class="header">
-        <h1>PromptGuard Interactive Viewer</h1>
-        <p>Explore test results, compare baselines, and analyze prompt performance</p>
-    </div>
-
-    <div class="container">
-        <div class="controls">
-            <div class="tab-buttons">
-                <button id="results-tab" class="active" onclick="showTab('results')">Test Results</button>
-                <button id="diff-tab" onclick="showTab('diff')">Baseline Comparison</button>
-                <button id="metrics-tab" onclick="showTab('metrics')">Historical Metrics</button>
-            </div>
-            
-            <div id="results-controls">
-                <button onclick="loadResults()">Refresh Results</button>
-                <button onclick="exportResults()">Export Report</button>
-            </div>
-            
-            <div id="diff-controls" style="display: none;">
-                <button onclick="loadBaseline()">Load Baseline</button>
-                <button onclick="compareResults()">Compare with Current</button>
-            </div>
-        </div>
-
-        <div id="results-view">
-            <div class="results-grid">
-                <div class="results-panel">
-                    <h3>Current Results</h3>
-                    <div id="current-results">Loading...</div>
-                </div>
-                <div class="results-panel">
-                    <h3>Test Details</h3>
-                    <div id="test-details">Select a test to view details</div>
-                </div>
-            </div>
-        </div>
-
-        <div id="diff-view" style="display: none;">
-            <div class="results-panel">
-                <h3>Baseline vs Current Comparison</h3>
-                <div id="diff-content">No comparison data available</div>
-            </div>
-        </div>
-
-        <div id="metrics-view" style="display: none;">
-            <div class="results-panel">
-                <h3>Historical Performance</h3>
-                <div class="metrics-chart" id="cost-chart"></div>
-                <div class="metrics-chart" id="success-chart"></div>
-            </div>
-        </div>
-    </div>
-
-    <script>
-        let currentResults = null;
-
-        async function loadResults() {
-            try {
-                const response = await fetch('/api/results');
-                currentResults = await response.json();
-                displayResults(currentResults);
-            } catch (error) {
-                console.error('Failed to load results:', error);
-                document.getElementById('current-results').innerHTML = 'Error loading results';
-            }
-        }
-
-        function displayResults(results) {
-            const container = document.getElementById('current-results');
-            
-            let html = '<div class="summary">';
-            html += '<h4>Summary</h4>';
-            html += '<p><strong>Total:</strong> ' + results.total + '</p>';
-            html += '<p><strong>Passed:</strong> ' + results.passed + '</p>';
-            html += '<p><strong>Failed:</strong> ' + results.failed + '</p>';
-            html += '<p><strong>Cost:</strong> $' + results.totalCost.toFixed(4) + '</p>';
-            html += '</div>';
-
-            html += '<div class="test-list">';
-            results.testResults.forEach((test, index) => {
-                const statusClass = test.status === 'passed' ? 'badge-passed' : 'badge-failed';
-                html += '<div class="test-item">';
-                html += '<div class="test-header" onclick="toggleTest(' + index + '); showTestDetails(' + index + ')">';
-                html += '<span><strong>' + test.name + '</strong></span>';
-                html += '<span class="status-badge ' + statusClass + '">' + test.status + '</span>';
-                html += '</div>';
-                html += '<div id="test-' + index + '" class="test-content">';
-                html += '<p><strong>Provider:</strong> ' + test.provider + '</p>';
-                html += '<p><strong>Cost:</strong> $' + test.cost.toFixed(4) + '</p>';
-                html += '<div class="response-text">' + test.response + '</div>';
-                html += '</div>';
-                html += '</div>';
-            });
-            html += '</div>';
-
-            container.innerHTML = html;
-        }
-
-        function showTestDetails(index) {
-            if (!currentResults) return;
-            
-            const test = currentResults.testResults[index];
-            const container = document.getElementById('test-details');
-            
-            let html = '<h4>' + test.name + '</h4>';
-            html += '<p><strong>File:</strong> ' + test.promptFile + '</p>';
-            html += '<p><strong>Provider:</strong> ' + test.provider + '</p>';
-            html += '<p><strong>Duration:</strong> ' + test.duration + '</p>';
-            
-            if (test.error) {
-                html += '<div style="color: red;"><strong>Error:</strong> ' + test.error + '</div>';
-            }
-            
-            html += '<h5>Assertions</h5>';
-            test.assertions.forEach(assertion => {
-                const status = assertion.passed ? '✅' : '❌';
-                html += '<div>' + status + ' <strong>' + assertion.type + ':</strong> ' + assertion.message + '</div>';
-            });
-            
-            html += '<h5>Response</h5>';
-            html += '<div class="response-text">' + test.response + '</div>';
-            
-            container.innerHTML = html;
-        }
-
-        function toggleTest(index) {
-            const content = document.getElementById('test-' + index);
-            content.classList.toggle('show');
-        }
-
-        function showTab(tabName) {
-            // Hide all views
-            document.getElementById('results-view').style.display = 'none';
-            document.getElementById('diff-view').style.display = 'none';
-            document.getElementById('metrics-view').style.display = 'none';
-            document.getElementById('results-controls').style.display = 'none';
-            document.getElementById('diff-controls').style.display = 'none';
-            
-            // Remove active class from all tabs
-            document.querySelectorAll('.tab-buttons button').forEach(btn => btn.classList.remove('active'));
-            
-            // Show selected view and controls
-            document.getElementById(tabName + '-view').style.display = 'block';
-            document.getElementById(tabName + '-controls').style.display = 'block';
-            document.getElementById(tabName + '-tab').classList.add('active');
-        }
-
-        function exportResults() {
-            if (!currentResults) return;
-            
-            const dataStr = JSON.stringify(currentResults, null, 2);
-            const dataBlob = new Blob([dataStr], {type: 'application/json'});
-            const url = URL.createObjectURL(dataBlob);
-            const link = document.createElement('a');
-            link.href = url;
-            link.download = 'promptguard-results.json';
-            link.click();
-        }
-
-        // Load results on page load
-        loadResults();
-    </script>
-</body>
-</html>`
-
-	t, err := template.New("index").Parse(tmpl)
+	t, err := template.New("index").Parse(indexTemplate)
 	if err != nil {
 		http.Error(w, "Template error", http.StatusInternalServerError)
 		return
 	}
 
 	w.Header().Set("Content-Type", "text/html")
-	t.Execute(w, nil)
+	t.Execute(w, s)
 }
 
 func (s *Server) handleAPIResults(w http.ResponseWriter, r *http.Request) {
@@ -260,8 +138,178 @@ func (s *Server) handleAPIResults(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(results)
 }
 
+// handleAPIDiff compares the current results with a baseline, taken from the
+// "baseline" query parameter or the configured baseline file. The parameter
+// names a run ID or a file inside the results directory.
 func (s *Server) handleAPIDiff(w http.ResponseWriter, r *http.Request) {
-	// TODO: Implement baseline comparison
+	baselineFile := r.URL.Query().Get("baseline")
+	baselinePath := s.baselineFile
+	if baselineFile == "" {
+		baselineFile = s.baselineFile
+	} else {
+		path, err := s.resultsPath(baselineFile)
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		baselinePath = path
+	}
+	if baselineFile == "" {
+		writeJSONError(w, http.StatusBadRequest, "no baseline file configured; pass ?baseline=<path or run ID>")
+		return
+	}
+
+	current, err := loadResults(s.resultsFile)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, fmt.Sprintf("failed to load current results: %v", err))
+		return
+	}
+
+	baseline, err := s.loadBaseline(baselinePath, baselineFile)
+	if os.IsNotExist(err) || errors.Is(err, metrics.ErrRunNotFound) {
+		writeJSONError(w, http.StatusNotFound, fmt.Sprintf("baseline not found: %s", baselineFile))
+		return
+	}
+	if err != nil {
+		writeJSONError(w, http.StatusUnprocessableEntity, fmt.Sprintf("invalid baseline %s: %v", baselineFile, err))
+		return
+	}
+
+	differ := &diff.MarkdownDiffer{}
+	response := DiffResponse{
+		Baseline: baselineFile,
+		Summary: DiffTotals{
+			BaselinePassed: baseline.Passed,
+			CurrentPassed:  current.Passed,
+			PassedDelta:    current.Passed - baseline.Passed,
+			BaselineFailed: baseline.Failed,
+			CurrentFailed:  current.Failed,
+			FailedDelta:    current.Failed - baseline.Failed,
+			BaselineCost:   baseline.TotalCost,
+			CurrentCost:    current.TotalCost,
+			CostDelta:      current.TotalCost - baseline.TotalCost,
+		},
+		Tests:    make([]TestDiff, 0, len(current.TestResults)),
+		Markdown: differ.GenerateBaselineComparison(current, baseline),
+	}
+
+	for _, change := range diff.CompareTests(baseline, current) {
+		test := TestDiff{
+			Name:           change.Name,
+			PromptFile:     change.PromptFile,
+			Provider:       change.Provider,
+			BaselineStatus: "missing",
+			CurrentStatus:  "missing",
+		}
+
+		switch {
+		case change.Before == nil:
+			test.CurrentStatus = change.After.Status
+			test.Transition = "added"
+		case change.After == nil:
+			test.BaselineStatus = change.Before.Status
+			test.Transition = "removed"
+		default:
+			test.BaselineStatus = change.Before.Status
+			test.CurrentStatus = change.After.Status
+			test.CostDelta = change.After.Cost - change.Before.Cost
+			switch {
+			case test.BaselineStatus == "passed" && test.CurrentStatus != "passed":
+				test.Transition = "regressed"
+			case test.BaselineStatus != "passed" && test.CurrentStatus == "passed":
+				test.Transition = "fixed"
+			default:
+				test.Transition = "unchanged"
+			}
+		}
+
+		response.Tests = append(response.Tests, test)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// handleAPIHistory returns the cost, pass, and fail counts of the most recent
+// stored runs, oldest first. The number of runs is set with ?limit= (default 50).
+func (s *Server) handleAPIHistory(w http.ResponseWriter, r *http.Request) {
+	limit := 50
+	if value := r.URL.Query().Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 || parsed > maxHistoryLimit {
+			writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("limit must be a number between 1 and %d", maxHistoryLimit))
+			return
+		}
+		limit = parsed
+	}
+
+	history, err := s.metrics.GetHistory(limit)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, fmt.Sprintf("failed to load history: %v", err))
+		return
+	}
+
+	// GetHistory returns the newest run first; charts read left to right
+	points := make([]HistoryPoint, 0, len(history))
+	for i := len(history) - 1; i >= 0; i-- {
+		run := history[i]
+		points = append(points, HistoryPoint{
+			RunID:     run.Metadata.RunID,
+			Timestamp: run.Metadata.Timestamp,
+			CommitSHA: run.Metadata.CommitSHA,
+			Cost:      run.TotalCost,
+			Passed:    run.Passed,
+			Failed:    run.Failed,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(points)
+}
+
+// resultsPath resolves a baseline requested by a client, which may only name
+// a file inside the results directory, to its path. Absolute paths and paths
+// containing ".." are rejected so that clients cannot read other files.
+func (s *Server) resultsPath(ref string) (string, error) {
+	if filepath.IsAbs(ref) || strings.HasPrefix(ref, "/") || strings.HasPrefix(ref, `\`) {
+		return "", fmt.Errorf("baseline must be a run ID or a path inside the results directory: %s", ref)
+	}
+	for _, element := range strings.FieldsFunc(ref, func(r rune) bool { return r == '/' || r == '\\' }) {
+		if element == ".." {
+			return "", fmt.Errorf("baseline must be a run ID or a path inside the results directory: %s", ref)
+		}
+	}
+	return filepath.Join(filepath.Dir(s.resultsFile), ref), nil
+}
+
+// loadBaseline reads the baseline results file at path or, if no such file
+// exists, the stored results of the run with ID runID
+func (s *Server) loadBaseline(path, runID string) (*runner.Results, error) {
+	results, err := loadResults(path)
+	if os.IsNotExist(err) {
+		return s.metrics.GetRun(runID)
+	}
+	return results, err
+}
+
+// loadResults reads a results file. A missing file returns an error
+// satisfying os.IsNotExist.
+func loadResults(filename string) (*runner.Results, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	var results runner.Results
+	if err := json.Unmarshal(data, &results); err != nil {
+		return nil, fmt.Errorf("failed to parse results: %w", err)
+	}
+
+	return &results, nil
+}
+
+func writeJSONError(w http.ResponseWriter, status int, message string) {
 	w.Header().Set("Content-Type", "application/json")
-	w.Write([]byte(`{"message": "Diff functionality not yet implemented"}`))
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
 }
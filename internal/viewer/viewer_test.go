@@ -0,0 +1,171 @@
+package viewer
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"promptguard/internal/runner"
+)
+
+// xssResults is a results file whose model-controlled values carry markup
+var xssResults = runner.Results{
+	Total:  1,
+	Failed: 1,
+	TestResults: []runner.TestResult{{
+		Name:       `<img src=x onerror=alert(1)>`,
+		PromptFile: "prompts/<svg onload=alert(2)>.prompt",
+		Provider:   "mock:echo",
+		Response:   `<script>alert(3)</script>`,
+		Status:     "failed",
+		Error:      `<iframe src="javascript:alert(4)">`,
+		Assertions: []runner.AssertionResult{{
+			Type:    "contains-json",
+			Message: `<b onmouseover=alert(5)>bold</b>`,
+		}},
+	}},
+}
+
+// pageHarness stubs the DOM for the viewer's script, points fetch at the
+// test server, and prints the HTML rendered for the results list and the
+// details of the first test
+const pageHarness = `
+const realFetch = globalThis.fetch;
+globalThis.fetch = url => realFetch(process.env.VIEWER_URL + url);
+const elements = {};
+globalThis.document = {
+    getElementById: id => elements[id] || (elements[id] = { innerHTML: '', textContent: '', style: {}, classList: { toggle() {}, add() {} } }),
+    querySelectorAll: () => [],
+};
+%SCRIPT%
+(async () => {
+    await loadResults();
+    showTestDetails(0);
+    process.stdout.write(elements['current-results'].innerHTML + '\n' + elements['test-details'].innerHTML);
+})();
+`
+
+func TestViewerEscapesResults(t *testing.T) {
+	node, err := exec.LookPath("node")
+	if err != nil {
+		t.Skip("node is required to run the viewer's script")
+	}
+
+	resultsFile := filepath.Join(t.TempDir(), "results.json")
+	data, err := json.Marshal(xssResults)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(resultsFile, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	viewer := NewServer(resultsFile, "", ":memory:")
+	defer viewer.Close()
+	server := httptest.NewServer(viewer)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	page, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := strings.Index(string(page), "<script>")
+	end := strings.LastIndex(string(page), "</script>")
+	if start < 0 || end < start {
+		t.Fatal("viewer page has no script")
+	}
+	script := string(page[start+len("<script>") : end])
+
+	cmd := exec.Command(node, "-")
+	cmd.Stdin = strings.NewReader(strings.Replace(pageHarness, "%SCRIPT%", script, 1))
+	cmd.Env = append(os.Environ(), "VIEWER_URL="+server.URL)
+	out, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("running the viewer script: %v", err)
+	}
+	rendered := string(out)
+
+	for _, markup := range []string{"<img", "<svg", "<script", "<iframe", "<b "} {
+		if strings.Contains(rendered, markup) {
+			t.Errorf("rendered HTML contains unescaped %q:\n%s", markup, rendered)
+		}
+	}
+	for _, escaped := range []string{
+		"&lt;img src=x onerror=alert(1)&gt;",
+		"&lt;svg onload=alert(2)&gt;",
+		"&lt;script&gt;alert(3)&lt;/script&gt;",
+		"&lt;iframe src=&quot;javascript:alert(4)&quot;&gt;",
+		"&lt;b onmouseover=alert(5)&gt;",
+	} {
+		if !strings.Contains(rendered, escaped) {
+			t.Errorf("rendered HTML is missing %q:\n%s", escaped, rendered)
+		}
+	}
+}
+
+func TestDiffBaselineStaysInResultsDirectory(t *testing.T) {
+	dir := t.TempDir()
+	resultsDir := filepath.Join(dir, "artifacts")
+	if err := os.Mkdir(resultsDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(runner.Results{Total: 1, Passed: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{
+		filepath.Join(resultsDir, "results.json"),
+		filepath.Join(resultsDir, "previous.json"),
+		filepath.Join(dir, "outside.json"),
+	} {
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	viewer := NewServer(filepath.Join(resultsDir, "results.json"), "", ":memory:")
+	defer viewer.Close()
+	server := httptest.NewServer(viewer)
+	defer server.Close()
+
+	tests := []struct {
+		baseline string
+		status   int
+	}{
+		{"previous.json", http.StatusOK},
+		{"./previous.json", http.StatusOK},
+		{"../outside.json", http.StatusBadRequest},
+		{"nested/../../outside.json", http.StatusBadRequest},
+		{`..\outside.json`, http.StatusBadRequest},
+		{filepath.Join(dir, "outside.json"), http.StatusBadRequest},
+		{"/etc/passwd", http.StatusBadRequest},
+		{"run-missing", http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.baseline, func(t *testing.T) {
+			resp, err := http.Get(server.URL + "/api/diff?baseline=" + url.QueryEscape(tt.baseline))
+			if err != nil {
+				t.Fatal(err)
+			}
+			body, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			if resp.StatusCode != tt.status {
+				t.Errorf("status = %d, want %d: %s", resp.StatusCode, tt.status, body)
+			}
+		})
+	}
+}
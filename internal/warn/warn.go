@@ -0,0 +1,34 @@
+// Package warn reports non-fatal problems. Warnings go to stderr so that
+// stdout stays clean for machine-readable output such as `pg test -o json`.
+package warn
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+var (
+	mu     sync.Mutex
+	output io.Writer = os.Stderr
+)
+
+// SetOutput redirects warnings, e.g. to capture them. It returns the
+// previous writer.
+func SetOutput(w io.Writer) io.Writer {
+	mu.Lock()
+	defer mu.Unlock()
+
+	previous := output
+	output = w
+	return previous
+}
+
+// Printf writes a warning line prefixed with "Warning: "
+func Printf(format string, args ...interface{}) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	fmt.Fprintf(output, "Warning: "+format+"\n", args...)
+}
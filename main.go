@@ -3,7 +3,7 @@ package main
 import (
 	"os"
 
-	"promptgaurd/cmd"
+	"promptguard/cmd"
 )
 
 func main() {